@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/alukart32/go-fast-key/internal/database"
 	"github.com/alukart32/go-fast-key/internal/network"
 	"github.com/alukart32/go-fast-key/internal/pkg/datasize"
 	"go.uber.org/zap"
@@ -17,7 +18,12 @@ import (
 func main() {
 	address := flag.String("address", "localhost:8080", "Address of the spider")
 	idleTimeout := flag.Duration("idle_timeout", time.Minute, "Idle timeout for connection")
-	maxMessageSizeStr := flag.String("max_message_size", "4KB", "Max message size for connection")
+	maxMessageSizeStr := flag.String("max_message_size", "4KB", "Max request size for connection")
+	useTLS := flag.Bool("tls", false, "Connect over TLS")
+	tlsCAFile := flag.String("tls_ca", "", "CA certificate file to verify the server, the system CAs if empty")
+	tlsCertFile := flag.String("tls_cert", "", "Client certificate file for mutual TLS")
+	tlsKeyFile := flag.String("tls_key", "", "Client key file for mutual TLS")
+	tlsServerName := flag.String("tls_server_name", "", "Server name to verify, the address host if empty")
 	flag.Parse()
 
 	logger, _ := zap.NewDevelopment()
@@ -30,6 +36,14 @@ func main() {
 	options = append(options, network.WithClientIdleTimeout(*idleTimeout))
 	options = append(options, network.WithClientBufferSize(uint(maxMessageSize)))
 
+	if *useTLS {
+		tlsConfig, err := network.NewClientTLSConfig(*tlsCAFile, *tlsCertFile, *tlsKeyFile, *tlsServerName)
+		if err != nil {
+			logger.Fatal("failed to create tls config", zap.Error(err))
+		}
+		options = append(options, network.WithClientTLSConfig(tlsConfig))
+	}
+
 	reader := bufio.NewReader(os.Stdin)
 	client, err := network.NewTCPClient(*address, options...)
 	if err != nil {
@@ -45,16 +59,17 @@ func main() {
 			logger.Error("fail to read query", zap.Error(err))
 		}
 
-		response, err := client.Send([]byte(request))
-		logger.Info("response", zap.String("response", string(response)), zap.Error(err))
+		data, err := client.Send([]byte(request))
 		if errors.Is(err, syscall.EPIPE) {
 			logger.Fatal("connection was closed", zap.Error(err))
 		} else if err != nil {
 			logger.Error("fail to send query", zap.Error(err))
+		} else if response, err := database.DecodeResponse(data); err != nil {
+			logger.Error("fail to decode response", zap.Error(err))
+		} else {
+			fmt.Println(response)
 		}
 
-		fmt.Println(string(response))
-
 		client.RefreshDeadline()
 	}
 }
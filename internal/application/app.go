@@ -4,25 +4,47 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/alukart32/go-fast-key/internal/auth"
 	"github.com/alukart32/go-fast-key/internal/configuration"
 	"github.com/alukart32/go-fast-key/internal/database"
 	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/database/replication"
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
 	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
 	"go.uber.org/zap"
 )
 
+// Version is the server version reported by INFO, it is set at build time with
+// -ldflags "-X github.com/alukart32/go-fast-key/internal/application.Version=<version>".
+var Version = "dev"
+
 type App struct {
-	dbEngine database.Engine
-	server   *network.TCPServer
-	logger   *zap.Logger
+	dbEngine         database.Engine
+	wal              *wal.WAL
+	snapshots        *snapshot.Store
+	snapshotInterval time.Duration
+	master           *replication.Master
+	slave            *replication.Slave
+	servers          []*network.TCPServer
+	authenticator    *auth.Authenticator
+	metrics          *Metrics
+	info             database.ServerInfo
+	logger           *zap.Logger
 }
 
-func NewApp(cfg *configuration.Config) (*App, error) {
+// NewApp creates the application of the config, the resources opened before a failed step are closed.
+func NewApp(cfg *configuration.Config) (_ *App, err error) {
 	if cfg == nil {
 		return nil, errors.New("new application: config is invalid")
 	}
+	startedAt := time.Now()
 
 	logger, _ := zap.NewDevelopment()
 	// logger, err := CreateLogger(cfg.Logging)
@@ -30,52 +52,252 @@ func NewApp(cfg *configuration.Config) (*App, error) {
 	// 	return nil, fmt.Errorf("create logger: %w", err)
 	// }
 
+	var closers []func() error
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, closer := range slices.Backward(closers) {
+			_ = closer()
+		}
+	}()
+
 	engine, err := CreateEngine(cfg.Engine, logger)
 	if err != nil {
 		return nil, fmt.Errorf("create database engine: %w", err)
 	}
 
-	server, err := CreateNetwork(cfg.Network, logger)
+	metrics, err := CreateMetrics(cfg.Metrics, logger)
+	if err != nil {
+		return nil, fmt.Errorf("create metrics: %w", err)
+	}
+	if metrics != nil {
+		closers = append(closers, metrics.Close)
+	}
+
+	var walOptions []wal.Option
+	if metrics != nil {
+		walOptions = append(walOptions, wal.WithFlushObserver(metrics.ObserveWALFlush))
+	}
+	log, err := CreateWAL(cfg.WAL, logger, walOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("create wal: %w", err)
+	}
+	if log != nil {
+		closers = append(closers, log.Close)
+	}
+
+	snapshots, err := CreateSnapshotStore(cfg.Snapshot, logger)
+	if err != nil {
+		return nil, fmt.Errorf("create snapshot store: %w", err)
+	}
+
+	var (
+		master *replication.Master
+		slave  *replication.Slave
+		tlsCfg *configuration.TLS
+	)
+	if cfg.Network != nil {
+		tlsCfg = cfg.Network.TLS
+	}
+	if cfg.Replication != nil {
+		switch cfg.Replication.ReplicaType {
+		case masterReplicaType:
+			master, err = CreateMaster(cfg.Replication, tlsCfg, log, snapshots, logger)
+		case slaveReplicaType:
+			slave, err = CreateSlave(cfg.Replication, tlsCfg, log, logger)
+		default:
+			err = fmt.Errorf("invalid replica type: %v", cfg.Replication.ReplicaType)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("create replication: %w", err)
+		}
+		if master != nil {
+			closers = append(closers, master.Close)
+		}
+	}
+
+	authenticator, err := CreateAuthenticator(cfg.Auth, logger)
+	if err != nil {
+		return nil, fmt.Errorf("create authenticator: %w", err)
+	}
+
+	servers, err := CreateNetwork(cfg.Network, logger)
 	if err != nil {
 		return nil, fmt.Errorf("create network: %w", err)
 	}
 
 	app := App{
 		dbEngine: engine,
-		server:   server,
+		wal:      log,
+		master:   master,
+		slave:    slave,
+		servers:  servers,
+		metrics:  metrics,
 		logger:   logger,
+
+		authenticator: authenticator,
+	}
+	if snapshots != nil {
+		app.snapshots = snapshots
+		app.snapshotInterval = cfg.Snapshot.Interval
+	}
+	app.info = database.ServerInfo{
+		Version:   Version,
+		StartedAt: startedAt,
+		Settings:  serverSettings(cfg, &app),
 	}
 
 	return &app, nil
 }
 
+// Run serves the clients until ctx is done, then shuts the application down gracefully:
+// the servers stop accepting and drain their connections, the WAL is flushed and closed,
+// and the logs are synced last.
 func (a *App) Run(ctx context.Context) error {
+	defer func() {
+		a.logger.Info("App is down")
+		_ = a.logger.Sync()
+	}()
+
 	requestParser, err := compute.NewParser(a.logger)
 	if err != nil {
 		return fmt.Errorf("create the request parser: %v", err)
 	}
 
-	db, err := database.NewDatabase(requestParser, a.dbEngine, a.logger)
+	var options []database.Option
+	if a.wal != nil {
+		options = append(options, database.WithWAL(a.wal))
+		defer func() {
+			if err := a.wal.Close(); err != nil {
+				a.logger.Error("fail to close wal", zap.Error(err))
+			}
+		}()
+	}
+	if a.snapshots != nil {
+		options = append(options, database.WithSnapshots(a.snapshots))
+	}
+	if a.slave != nil {
+		options = append(options, database.WithReadOnly())
+	}
+	if a.authenticator != nil {
+		options = append(options, database.WithAuthenticator(a.authenticator))
+	}
+	if a.metrics != nil {
+		options = append(options, database.WithMetrics(a.metrics))
+	}
+
+	options = append(options, database.WithServerInfo(a.info))
+	listeners := make([]database.Listener, 0, len(a.servers))
+	for _, server := range a.servers {
+		listeners = append(listeners, listenerInfo{server: server})
+	}
+	options = append(options, database.WithListeners(listeners...))
+	// The typed nil replication is not passed, so INFO reports the standalone role.
+	switch {
+	case a.master != nil:
+		options = append(options, database.WithReplication(replicationInfo{stats: a.master.Stats}))
+	case a.slave != nil:
+		options = append(options, database.WithReplication(replicationInfo{stats: a.slave.Stats}))
+	}
+
+	db, err := database.NewDatabase(requestParser, a.dbEngine, a.logger, options...)
 	if err != nil {
 		return fmt.Errorf("create the database: %v", err)
 	}
 
+	if err := db.Recover(); err != nil {
+		return fmt.Errorf("recover the database: %v", err)
+	}
+
 	var wg sync.WaitGroup
-	wg.Add(1)
+	respHandler := CreateRESPHandler(requestParser, db)
+	for _, server := range a.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if server.Protocol() == network.RESPProtocol {
+				server.HandleRESP(ctx, respHandler)
+				return
+			}
+			server.HandleQueries(ctx, func(_ context.Context, sess *session.Session, request []byte) []byte {
+				return database.EncodeResponse(db.HandleRequest(sess, string(request)))
+			})
+		}()
+	}
 
+	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		a.server.HandleQueries(ctx, func(_ context.Context, request []byte) []byte {
-			response := db.HandleRequest(string(request))
-			return []byte(response)
-		})
+		runExpiration(ctx, a.dbEngine)
 	}()
 
+	if a.metrics != nil {
+		a.metrics.RegisterEngine(a.dbEngine)
+		for _, server := range a.servers {
+			a.metrics.RegisterServer(server)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.metrics.Serve(ctx)
+		}()
+	}
+
+	if a.master != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.master.Serve(ctx)
+		}()
+	}
+	if a.slave != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.slave.Run(ctx, db)
+		}()
+	}
+
+	if a.snapshots != nil && a.snapshotInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runSnapshots(ctx, a.snapshotInterval, db, a.logger)
+		}()
+	}
+
 	a.logger.Info("App is running")
 
-	wg.Wait()
-	a.logger.Sync()
+	<-ctx.Done()
+	a.logger.Info("App is shutting down")
 
-	a.logger.Info("App is down")
+	wg.Wait()
 	return err
 }
+
+// serverSettings summarizes the config of the app for INFO.
+func serverSettings(cfg *configuration.Config, app *App) []database.Setting {
+	partitions := 1
+	if cfg.Engine != nil && cfg.Engine.PartitionsNumber > 1 {
+		partitions = cfg.Engine.PartitionsNumber
+	}
+
+	return []database.Setting{
+		{Name: "engine_partitions", Value: strconv.Itoa(partitions)},
+		{Name: "listeners", Value: strconv.Itoa(len(app.servers))},
+		{Name: "tls", Value: yesNo(cfg.Network != nil && cfg.Network.TLS != nil)},
+		{Name: "auth", Value: yesNo(app.authenticator != nil)},
+		{Name: "wal", Value: yesNo(app.wal != nil)},
+		{Name: "snapshots", Value: yesNo(app.snapshots != nil)},
+		{Name: "metrics", Value: yesNo(app.metrics != nil)},
+	}
+}
+
+func yesNo(ok bool) string {
+	if ok {
+		return "yes"
+	}
+	return "no"
+}
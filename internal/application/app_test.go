@@ -0,0 +1,35 @@
+package application_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/application"
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewApp_ClosesListenersOnFailure(t *testing.T) {
+	t.Parallel()
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	metricsAddress := free.Addr().String()
+	require.NoError(t, free.Close())
+
+	app, err := application.NewApp(&configuration.Config{
+		Network: &configuration.Network{Address: busy.Addr().String()},
+		Metrics: &configuration.Metrics{Address: metricsAddress},
+	})
+	assert.Error(t, err)
+	assert.Nil(t, app)
+
+	listener, err := net.Listen("tcp", metricsAddress)
+	require.NoError(t, err, "metrics listener is not closed")
+	_ = listener.Close()
+}
@@ -0,0 +1,51 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alukart32/go-fast-key/internal/auth"
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"go.uber.org/zap"
+)
+
+// CreateAuthenticator creates the authenticator of the users, it returns nil if auth is not configured.
+func CreateAuthenticator(cfg *configuration.Auth, logger *zap.Logger) (*auth.Authenticator, error) {
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if len(cfg.Users) == 0 {
+		return nil, errors.New("auth users are empty")
+	}
+
+	var options []auth.Option
+	if cfg.MaxFailures < 0 {
+		return nil, fmt.Errorf("invalid max failures: %v", cfg.MaxFailures)
+	}
+	if cfg.MaxFailures != 0 {
+		options = append(options, auth.WithMaxFailures(cfg.MaxFailures))
+	}
+
+	if cfg.FailureWindow < 0 {
+		return nil, fmt.Errorf("invalid failure window: %v", cfg.FailureWindow)
+	}
+	if cfg.FailureWindow != 0 {
+		options = append(options, auth.WithFailureWindow(cfg.FailureWindow))
+	}
+
+	users := make([]auth.User, 0, len(cfg.Users))
+	for _, u := range cfg.Users {
+		users = append(users, auth.User{
+			Name:         u.Name,
+			PasswordHash: u.PasswordHash,
+			Token:        u.Token,
+			Rules:        u.Rules,
+		})
+	}
+
+	return auth.NewAuthenticator(users, logger, options...)
+}
@@ -0,0 +1,77 @@
+package application_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/application"
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCreateAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	users := []configuration.User{{Name: "svc-a", Token: "token"}}
+	tests := map[string]struct {
+		cfg    *configuration.Auth
+		logger *zap.Logger
+
+		wantErr    error
+		wantNilObj bool
+	}{
+		"create authenticator without logger": {
+			cfg:        &configuration.Auth{Users: users},
+			wantErr:    errors.New("logger is nil"),
+			wantNilObj: true,
+		},
+		"create authenticator without config": {
+			logger:     zap.NewNop(),
+			wantErr:    nil,
+			wantNilObj: true,
+		},
+		"create authenticator without users": {
+			logger:     zap.NewNop(),
+			cfg:        &configuration.Auth{},
+			wantErr:    errors.New("auth users are empty"),
+			wantNilObj: true,
+		},
+		"create authenticator with incorrect max failures": {
+			logger:     zap.NewNop(),
+			cfg:        &configuration.Auth{Users: users, MaxFailures: -1},
+			wantErr:    errors.New("invalid max failures: -1"),
+			wantNilObj: true,
+		},
+		"create authenticator with incorrect failure window": {
+			logger:     zap.NewNop(),
+			cfg:        &configuration.Auth{Users: users, FailureWindow: -time.Second},
+			wantErr:    errors.New("invalid failure window: -1s"),
+			wantNilObj: true,
+		},
+		"create authenticator with config fields": {
+			logger: zap.NewNop(),
+			cfg: &configuration.Auth{
+				Users:         users,
+				MaxFailures:   3,
+				FailureWindow: time.Minute,
+			},
+			wantErr: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			authenticator, err := application.CreateAuthenticator(test.cfg, test.logger)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantNilObj {
+				assert.Nil(t, authenticator)
+			} else {
+				assert.NotNil(t, authenticator)
+			}
+		})
+	}
+}
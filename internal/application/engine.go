@@ -1,21 +1,33 @@
 package application
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/alukart32/go-fast-key/internal/configuration"
 	"github.com/alukart32/go-fast-key/internal/database"
 	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/alukart32/go-fast-key/internal/pkg/datasize"
 	"go.uber.org/zap"
 )
 
+const defaultEngineCapacity = 256
+
+const (
+	// expirationSweepInterval is the interval of the expired keys removal.
+	expirationSweepInterval = 100 * time.Millisecond
+	// expirationSweepLimit is the number of keys with a deadline checked at once.
+	expirationSweepLimit = 20
+)
+
 func CreateEngine(cfg *configuration.Engine, logger *zap.Logger) (database.Engine, error) {
 	if logger == nil {
 		return nil, errors.New("logger is nil")
 	}
 	if cfg == nil {
-		return engine.NewMemEngine(256), nil
+		return engine.NewMemEngine(defaultEngineCapacity), nil
 	}
 
 	if cfg.Type != "" {
@@ -28,5 +40,53 @@ func CreateEngine(cfg *configuration.Engine, logger *zap.Logger) (database.Engin
 		}
 	}
 
-	return engine.NewMemEngine(256), nil
+	if cfg.PartitionsNumber < 0 {
+		return nil, fmt.Errorf("invalid partitions number: %v", cfg.PartitionsNumber)
+	}
+
+	var options []engine.Option
+	if cfg.MaxMemory != "" {
+		size, err := datasize.Parse(cfg.MaxMemory)
+		if err != nil {
+			return nil, fmt.Errorf("parse max memory: %v", err)
+		}
+
+		options = append(options, engine.WithMaxMemory(uint(size)))
+	}
+
+	if cfg.EvictionPolicy != "" {
+		policy := engine.EvictionPolicy(cfg.EvictionPolicy)
+		if !policy.Valid() {
+			return nil, fmt.Errorf("unsupported eviction policy: %v", cfg.EvictionPolicy)
+		}
+
+		options = append(options, engine.WithEvictionPolicy(policy))
+	}
+
+	if cfg.PartitionsNumber > 1 {
+		return engine.NewPartitionedEngine(cfg.PartitionsNumber, defaultEngineCapacity, options...), nil
+	}
+
+	return engine.NewMemEngine(defaultEngineCapacity, options...), nil
+}
+
+// runExpiration removes the expired keys that are not accessed until ctx is done.
+//
+// The sweep is repeated right away while it keeps finding many expired keys.
+func runExpiration(ctx context.Context, e database.Engine) {
+	ticker := time.NewTicker(expirationSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for e.DeleteExpired(expirationSweepLimit) > expirationSweepLimit/4 {
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}
+	}
 }
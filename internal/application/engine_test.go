@@ -39,6 +39,40 @@ func TestCreateEngine(t *testing.T) {
 			logger:  zap.NewNop(),
 			wantErr: nil,
 		},
+		"create partitioned engine": {
+			cfg: &configuration.Engine{
+				Type:             "in_memory",
+				PartitionsNumber: 8,
+			},
+			logger:  zap.NewNop(),
+			wantErr: nil,
+		},
+		"create engine with incorrect partitions number": {
+			cfg:        &configuration.Engine{PartitionsNumber: -1},
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("invalid partitions number: -1"),
+			wantNilObj: true,
+		},
+		"create engine with memory limit": {
+			cfg: &configuration.Engine{
+				MaxMemory:      "1GB",
+				EvictionPolicy: "allkeys-lru",
+			},
+			logger:  zap.NewNop(),
+			wantErr: nil,
+		},
+		"create engine with incorrect max memory": {
+			cfg:        &configuration.Engine{MaxMemory: "1TB"},
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("parse max memory: invalid size"),
+			wantNilObj: true,
+		},
+		"create engine with incorrect eviction policy": {
+			cfg:        &configuration.Engine{EvictionPolicy: "allkeys-fifo"},
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("unsupported eviction policy: allkeys-fifo"),
+			wantNilObj: true,
+		},
 		"create engine with incorrect type": {
 			cfg:        &configuration.Engine{Type: "invalid"},
 			logger:     zap.NewNop(),
@@ -0,0 +1,40 @@
+package application
+
+import (
+	"github.com/alukart32/go-fast-key/internal/database"
+	"github.com/alukart32/go-fast-key/internal/database/replication"
+	"github.com/alukart32/go-fast-key/internal/network"
+)
+
+// listenerInfo reports the state of the server to INFO.
+type listenerInfo struct {
+	server *network.TCPServer
+}
+
+func (l listenerInfo) Stats() database.ListenerStats {
+	stats := l.server.Stats()
+	return database.ListenerStats{
+		Address:             stats.Address,
+		Protocol:            string(stats.Protocol),
+		Connections:         stats.Connections,
+		RejectedConnections: stats.RejectedConnections,
+		BytesRead:           stats.BytesRead,
+		BytesWritten:        stats.BytesWritten,
+	}
+}
+
+// replicationInfo reports the state of the replication master or slave to INFO.
+type replicationInfo struct {
+	stats func() replication.Stats
+}
+
+func (r replicationInfo) Stats() database.ReplicationStats {
+	stats := r.stats()
+	return database.ReplicationStats{
+		Role:          string(stats.Role),
+		MasterAddress: stats.MasterAddress,
+		Slaves:        stats.Slaves,
+		Connected:     stats.Connected,
+		LastSync:      stats.LastSync,
+	}
+}
@@ -0,0 +1,122 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/alukart32/go-fast-key/internal/database"
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/metrics"
+	"github.com/alukart32/go-fast-key/internal/network"
+	"go.uber.org/zap"
+)
+
+const defaultMetricsPath = "/metrics"
+
+// Metrics collects the metrics of the application components and serves them over HTTP.
+type Metrics struct {
+	registry *metrics.Registry
+	server   *metrics.Server
+
+	commands   *metrics.CounterVec
+	errors     *metrics.CounterVec
+	durations  *metrics.HistogramVec
+	walFlushes *metrics.Histogram
+}
+
+// CreateMetrics creates the metrics served on the configured address, it returns nil if the metrics are not configured.
+func CreateMetrics(cfg *configuration.Metrics, logger *zap.Logger) (*Metrics, error) {
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if cfg.Address == "" {
+		return nil, errors.New("metrics address is empty")
+	}
+	path := defaultMetricsPath
+	if cfg.Path != "" {
+		if !strings.HasPrefix(cfg.Path, "/") {
+			return nil, fmt.Errorf("invalid metrics path: %v", cfg.Path)
+		}
+		path = cfg.Path
+	}
+
+	registry := metrics.NewRegistry()
+	server, err := metrics.NewServer(cfg.Address, path, registry, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metrics{
+		registry: registry,
+		server:   server,
+		commands: registry.CounterVec("fastkey_commands_total",
+			"Number of the executed commands, including the queued ones.", "command"),
+		errors: registry.CounterVec("fastkey_command_errors_total",
+			"Number of the commands that failed.", "command"),
+		durations: registry.HistogramVec("fastkey_command_duration_seconds",
+			"Latency of the commands.", "command", metrics.DefaultBuckets),
+		walFlushes: registry.Histogram("fastkey_wal_flush_duration_seconds",
+			"Latency of the write-ahead log batch flushes.", nil, metrics.DefaultBuckets),
+	}, nil
+}
+
+// ObserveCommand counts the executed command and its latency.
+func (m *Metrics) ObserveCommand(command compute.CommandID, duration time.Duration, err error) {
+	name := command.String()
+	m.commands.With(name).Inc()
+	if err != nil {
+		m.errors.With(name).Inc()
+	}
+	m.durations.With(name).Observe(duration.Seconds())
+}
+
+// ObserveWALFlush adds the latency of the write-ahead log batch flush.
+func (m *Metrics) ObserveWALFlush(duration time.Duration) {
+	m.walFlushes.Observe(duration.Seconds())
+}
+
+// RegisterServer registers the connections and traffic metrics of the server.
+func (m *Metrics) RegisterServer(server *network.TCPServer) {
+	labels := metrics.Labels{"listener": server.Address(), "protocol": string(server.Protocol())}
+
+	m.registry.GaugeFunc("fastkey_connections", "Number of the open client connections.", labels,
+		func() float64 { return float64(server.Connections()) })
+	m.registry.CounterFunc("fastkey_rejected_connections_total",
+		"Number of the connections rejected because of the max connections.", labels,
+		func() float64 { return float64(server.RejectedConnections()) })
+	m.registry.CounterFunc("fastkey_network_read_bytes_total", "Number of the bytes read from the clients.", labels,
+		func() float64 { return float64(server.BytesRead()) })
+	m.registry.CounterFunc("fastkey_network_written_bytes_total", "Number of the bytes written to the clients.", labels,
+		func() float64 { return float64(server.BytesWritten()) })
+}
+
+// RegisterEngine registers the keys and memory metrics of the engine.
+func (m *Metrics) RegisterEngine(engine database.Engine) {
+	m.registry.GaugeFunc("fastkey_keys", "Number of the keys, including the expired ones that are not removed yet.", nil,
+		func() float64 { return float64(engine.Stats().Keys) })
+	m.registry.GaugeFunc("fastkey_used_memory_bytes", "Estimated memory held by the keys and values.", nil,
+		func() float64 { return float64(engine.Stats().UsedMemory) })
+}
+
+// Serve serves the scrapes until ctx is done.
+func (m *Metrics) Serve(ctx context.Context) {
+	m.server.Serve(ctx)
+}
+
+// Close stops listening of the metrics that are not served yet.
+func (m *Metrics) Close() error {
+	return m.server.Close()
+}
+
+// Address returns the address the metrics are served on.
+func (m *Metrics) Address() string {
+	return m.server.Address()
+}
@@ -0,0 +1,111 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/application"
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCreateMetrics(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		cfg    *configuration.Metrics
+		logger *zap.Logger
+
+		wantErr    error
+		wantNilObj bool
+	}{
+		"create metrics without logger": {
+			wantErr:    errors.New("logger is nil"),
+			wantNilObj: true,
+		},
+		"create metrics without config": {
+			logger:     zap.NewNop(),
+			wantNilObj: true,
+		},
+		"create metrics without address": {
+			logger:     zap.NewNop(),
+			cfg:        &configuration.Metrics{Path: "/metrics"},
+			wantErr:    errors.New("metrics address is empty"),
+			wantNilObj: true,
+		},
+		"create metrics with incorrect path": {
+			logger:     zap.NewNop(),
+			cfg:        &configuration.Metrics{Address: "localhost:0", Path: "metrics"},
+			wantErr:    errors.New("invalid metrics path: metrics"),
+			wantNilObj: true,
+		},
+		"create metrics with config fields": {
+			logger: zap.NewNop(),
+			cfg:    &configuration.Metrics{Address: "localhost:0", Path: "/stats"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			metrics, err := application.CreateMetrics(test.cfg, test.logger)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantNilObj {
+				assert.Nil(t, metrics)
+			} else {
+				assert.NotNil(t, metrics)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics, err := application.CreateMetrics(&configuration.Metrics{Address: "localhost:0"}, zap.NewNop())
+	require.NoError(t, err)
+
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.Set("key", "value"))
+	metrics.RegisterEngine(eng)
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop())
+	require.NoError(t, err)
+	defer server.Close()
+	metrics.RegisterServer(server)
+
+	metrics.ObserveCommand(compute.SetCommand, time.Millisecond, nil)
+	metrics.ObserveCommand(compute.GetCommand, time.Millisecond, engine.ErrNotFound)
+	metrics.ObserveWALFlush(time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go metrics.Serve(ctx)
+
+	response, err := http.Get("http://" + metrics.Address() + "/metrics")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	body := string(data)
+
+	assert.Contains(t, body, `fastkey_commands_total{command="GET"} 1`)
+	assert.Contains(t, body, `fastkey_commands_total{command="SET"} 1`)
+	assert.Contains(t, body, `fastkey_command_errors_total{command="GET"} 1`)
+	assert.NotContains(t, body, `fastkey_command_errors_total{command="SET"}`)
+	assert.Contains(t, body, `fastkey_command_duration_seconds_count{command="SET"} 1`)
+	assert.Contains(t, body, `fastkey_wal_flush_duration_seconds_count 1`)
+	assert.Contains(t, body, `fastkey_keys 1`)
+	assert.Contains(t, body, `fastkey_connections{listener="`+server.Address()+`",protocol="fastkey"} 0`)
+	assert.Contains(t, body, `fastkey_rejected_connections_total{listener="`+server.Address()+`",protocol="fastkey"} 0`)
+}
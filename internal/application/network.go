@@ -3,28 +3,41 @@ package application
 import (
 	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strconv"
 
 	"github.com/alukart32/go-fast-key/internal/configuration"
 	"github.com/alukart32/go-fast-key/internal/network"
 	"github.com/alukart32/go-fast-key/internal/pkg/datasize"
+	"github.com/alukart32/go-fast-key/internal/session"
 	"go.uber.org/zap"
 )
 
 const defaultServerAddress = ":3223"
 
-func CreateNetwork(cfg *configuration.Network, logger *zap.Logger) (*network.TCPServer, error) {
+// CreateNetwork creates the servers of the main address and the additional listeners.
+//
+// The listeners share the connection settings, but each one speaks its own protocol.
+func CreateNetwork(cfg *configuration.Network, logger *zap.Logger) ([]*network.TCPServer, error) {
 	if logger == nil {
 		return nil, errors.New("logger is nil")
 	}
 
-	address := defaultServerAddress
-	var options []network.TCPServerOption
+	listeners := []configuration.Listener{{Address: defaultServerAddress}}
+	// The listeners share the sessions, so their IDs and list cover all the clients.
+	options := []network.TCPServerOption{network.WithServerSessions(session.NewManager())}
 
 	if cfg != nil {
 		if cfg.Address != "" {
-			address = cfg.Address
+			listeners[0].Address = cfg.Address
 		}
+		listeners[0].Protocol = cfg.Protocol
+		listeners = append(listeners, cfg.Listeners...)
 
+		if cfg.MaxConnections < 0 {
+			return nil, fmt.Errorf("invalid max connections: %v", cfg.MaxConnections)
+		}
 		if cfg.MaxConnections != 0 {
 			options = append(options, network.WithServerMaxConnectionsNumber(uint(cfg.MaxConnections)))
 		}
@@ -41,7 +54,46 @@ func CreateNetwork(cfg *configuration.Network, logger *zap.Logger) (*network.TCP
 		if cfg.IdleTimeout != 0 {
 			options = append(options, network.WithServerIdleTimeout(cfg.IdleTimeout))
 		}
+
+		if cfg.ShutdownTimeout != 0 {
+			options = append(options, network.WithServerShutdownTimeout(cfg.ShutdownTimeout))
+		}
+
+		if cfg.SocketPermissions != "" {
+			perm, err := strconv.ParseUint(cfg.SocketPermissions, 8, 32)
+			if err != nil || perm > 0o777 {
+				return nil, fmt.Errorf("invalid socket permissions: %v", cfg.SocketPermissions)
+			}
+
+			options = append(options, network.WithServerSocketPermissions(os.FileMode(perm)))
+		}
+
+		if cfg.TLS != nil {
+			tlsConfig, err := network.NewServerTLSConfig(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.CAFile, cfg.TLS.RequireClientCert)
+			if err != nil {
+				return nil, fmt.Errorf("create tls config: %v", err)
+			}
+
+			options = append(options, network.WithServerTLSConfig(tlsConfig))
+		}
+	}
+
+	servers := make([]*network.TCPServer, 0, len(listeners))
+	for _, l := range listeners {
+		listenerOptions := slices.Clip(options)
+		if l.Protocol != "" {
+			listenerOptions = append(listenerOptions, network.WithServerProtocol(network.Protocol(l.Protocol)))
+		}
+
+		server, err := network.NewTCPServer(l.Address, logger, listenerOptions...)
+		if err != nil {
+			for _, s := range servers {
+				_ = s.Close()
+			}
+			return nil, err
+		}
+		servers = append(servers, server)
 	}
 
-	return network.NewTCPServer(address, logger, options...)
+	return servers, nil
 }
@@ -2,6 +2,7 @@ package application_test
 
 import (
 	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -44,9 +45,75 @@ func TestCreateNetwork(t *testing.T) {
 				MaxConnections: 100,
 				MaxMessageSize: "2KB",
 				IdleTimeout:    time.Second,
+
+				ShutdownTimeout: time.Second,
+			},
+			wantErr: nil,
+		},
+		"create network with resp listener": {
+			logger: zap.NewNop(),
+			cfg: &configuration.Network{
+				Address: "localhost:0",
+				Listeners: []configuration.Listener{
+					{Address: "localhost:0", Protocol: "resp"},
+				},
+			},
+			wantErr: nil,
+		},
+		"create network with unix socket listener": {
+			logger: zap.NewNop(),
+			cfg: &configuration.Network{
+				Address: "localhost:0",
+				Listeners: []configuration.Listener{
+					{Address: "unix://" + filepath.Join(t.TempDir(), "fastkey.sock"), Protocol: "resp"},
+				},
+				SocketPermissions: "0660",
 			},
 			wantErr: nil,
 		},
+		"create network with negative max connections": {
+			logger: zap.NewNop(),
+			cfg: &configuration.Network{
+				MaxConnections: -1,
+			},
+			wantErr:    errors.New("invalid max connections: -1"),
+			wantNilObj: true,
+		},
+		"create network with incorrect socket permissions": {
+			logger: zap.NewNop(),
+			cfg: &configuration.Network{
+				SocketPermissions: "0999",
+			},
+			wantErr:    errors.New("invalid socket permissions: 0999"),
+			wantNilObj: true,
+		},
+		"create network with incorrect protocol": {
+			logger: zap.NewNop(),
+			cfg: &configuration.Network{
+				Address:  "localhost:0",
+				Protocol: "http",
+			},
+			wantErr:    errors.New("unsupported protocol: http"),
+			wantNilObj: true,
+		},
+		"create network with incorrect tls": {
+			logger: zap.NewNop(),
+			cfg: &configuration.Network{
+				Address: "localhost:0",
+				TLS:     &configuration.TLS{CertFile: "server.crt", KeyFile: "server.key"},
+			},
+			wantErr:    errors.New("create tls config: load certificate: open server.crt: no such file or directory"),
+			wantNilObj: true,
+		},
+		"create network with tls without certificate": {
+			logger: zap.NewNop(),
+			cfg: &configuration.Network{
+				Address: "localhost:0",
+				TLS:     &configuration.TLS{RequireClientCert: true},
+			},
+			wantErr:    errors.New("create tls config: certificate and key files are required"),
+			wantNilObj: true,
+		},
 		"create network with incorrect size": {
 			logger: zap.NewNop(),
 			cfg: &configuration.Network{
@@ -0,0 +1,98 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/alukart32/go-fast-key/internal/database/replication"
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
+	"github.com/alukart32/go-fast-key/internal/network"
+	"go.uber.org/zap"
+)
+
+const (
+	masterReplicaType = "master"
+	slaveReplicaType  = "slave"
+)
+
+// CreateMaster creates the replication master that serves the wal segments on the master address.
+//
+// The master address accepts only TLS connections if tlsCfg is set.
+// The slaves behind the compacted log are resynchronized from the snapshots if they are set.
+func CreateMaster(cfg *configuration.Replication, tlsCfg *configuration.TLS, segments *wal.WAL, snapshots *snapshot.Store, logger *zap.Logger) (*replication.Master, error) {
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+	if cfg == nil {
+		return nil, errors.New("replication config is nil")
+	}
+	if segments == nil {
+		return nil, errors.New("replication requires wal")
+	}
+	if cfg.MasterAddress == "" {
+		return nil, errors.New("master address is empty")
+	}
+	if cfg.Secret == "" {
+		return nil, errors.New("replication secret is empty")
+	}
+
+	var options []network.TCPServerOption
+	if tlsCfg != nil {
+		tlsConfig, err := network.NewServerTLSConfig(tlsCfg.CertFile, tlsCfg.KeyFile, tlsCfg.CAFile, tlsCfg.RequireClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("create tls config: %v", err)
+		}
+
+		options = append(options, network.WithServerTLSConfig(tlsConfig))
+	}
+
+	server, err := network.NewTCPServer(cfg.MasterAddress, logger, options...)
+	if err != nil {
+		return nil, fmt.Errorf("create replication server: %w", err)
+	}
+
+	var masterOptions []replication.MasterOption
+	if snapshots != nil {
+		masterOptions = append(masterOptions, replication.WithSnapshots(snapshots))
+	}
+
+	master, err := replication.NewMaster(server, segments, cfg.Secret, logger, masterOptions...)
+	if err != nil {
+		_ = server.Close()
+		return nil, err
+	}
+	return master, nil
+}
+
+// CreateSlave creates the replication slave of the master at the master address.
+//
+// The slave connects over TLS if tlsCfg is set: the master is verified by the ca file,
+// or by the system authorities if it is missing, and the certificate is sent as the client one.
+func CreateSlave(cfg *configuration.Replication, tlsCfg *configuration.TLS, segments *wal.WAL, logger *zap.Logger) (*replication.Slave, error) {
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+	if cfg == nil {
+		return nil, errors.New("replication config is nil")
+	}
+	if segments == nil {
+		return nil, errors.New("replication requires wal")
+	}
+	if cfg.Secret == "" {
+		return nil, errors.New("replication secret is empty")
+	}
+
+	var options []replication.SlaveOption
+	if tlsCfg != nil {
+		tlsConfig, err := network.NewClientTLSConfig(tlsCfg.CAFile, tlsCfg.CertFile, tlsCfg.KeyFile, "")
+		if err != nil {
+			return nil, fmt.Errorf("create tls config: %v", err)
+		}
+
+		options = append(options, replication.WithSlaveTLSConfig(tlsConfig))
+	}
+
+	return replication.NewSlave(cfg.MasterAddress, cfg.SyncInterval, cfg.Secret, logger, options...)
+}
@@ -0,0 +1,172 @@
+package application_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/application"
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCreateMaster(t *testing.T) {
+	t.Parallel()
+
+	segments, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		cfg      *configuration.Replication
+		tlsCfg   *configuration.TLS
+		segments *wal.WAL
+		logger   *zap.Logger
+
+		wantErr    error
+		wantNilObj bool
+	}{
+		"create master without logger": {
+			cfg:        &configuration.Replication{MasterAddress: "localhost:0", Secret: "secret"},
+			segments:   segments,
+			wantErr:    errors.New("logger is nil"),
+			wantNilObj: true,
+		},
+		"create master without config": {
+			segments:   segments,
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("replication config is nil"),
+			wantNilObj: true,
+		},
+		"create master without wal": {
+			cfg:        &configuration.Replication{MasterAddress: "localhost:0", Secret: "secret"},
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("replication requires wal"),
+			wantNilObj: true,
+		},
+		"create master without master address": {
+			cfg:        &configuration.Replication{},
+			segments:   segments,
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("master address is empty"),
+			wantNilObj: true,
+		},
+		"create master without secret": {
+			cfg:        &configuration.Replication{MasterAddress: "localhost:0"},
+			segments:   segments,
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("replication secret is empty"),
+			wantNilObj: true,
+		},
+		"create master with invalid tls config": {
+			cfg:        &configuration.Replication{MasterAddress: "localhost:0", Secret: "secret"},
+			tlsCfg:     &configuration.TLS{RequireClientCert: true},
+			segments:   segments,
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("create tls config: certificate and key files are required"),
+			wantNilObj: true,
+		},
+		"create master with config fields": {
+			cfg:      &configuration.Replication{MasterAddress: "localhost:0", Secret: "secret"},
+			segments: segments,
+			logger:   zap.NewNop(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			master, err := application.CreateMaster(test.cfg, test.tlsCfg, test.segments, nil, test.logger)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantNilObj {
+				assert.Nil(t, master)
+			} else {
+				assert.NotNil(t, master)
+			}
+		})
+	}
+}
+
+func TestCreateSlave(t *testing.T) {
+	t.Parallel()
+
+	segments, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		cfg      *configuration.Replication
+		tlsCfg   *configuration.TLS
+		segments *wal.WAL
+		logger   *zap.Logger
+
+		wantErr    error
+		wantNilObj bool
+	}{
+		"create slave without logger": {
+			cfg:        &configuration.Replication{MasterAddress: "localhost:3232", Secret: "secret"},
+			segments:   segments,
+			wantErr:    errors.New("logger is nil"),
+			wantNilObj: true,
+		},
+		"create slave without wal": {
+			cfg:        &configuration.Replication{MasterAddress: "localhost:3232", Secret: "secret"},
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("replication requires wal"),
+			wantNilObj: true,
+		},
+		"create slave without master address": {
+			cfg:        &configuration.Replication{Secret: "secret"},
+			segments:   segments,
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("master address is empty"),
+			wantNilObj: true,
+		},
+		"create slave without secret": {
+			cfg:        &configuration.Replication{MasterAddress: "localhost:3232"},
+			segments:   segments,
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("replication secret is empty"),
+			wantNilObj: true,
+		},
+		"create slave with invalid tls config": {
+			cfg:        &configuration.Replication{MasterAddress: "localhost:3232", Secret: "secret"},
+			tlsCfg:     &configuration.TLS{CertFile: "client.crt"},
+			segments:   segments,
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("create tls config: certificate and key files must be set together"),
+			wantNilObj: true,
+		},
+		"create slave with tls config": {
+			cfg:      &configuration.Replication{MasterAddress: "localhost:3232", Secret: "secret"},
+			tlsCfg:   &configuration.TLS{},
+			segments: segments,
+			logger:   zap.NewNop(),
+		},
+		"create slave with config fields": {
+			cfg: &configuration.Replication{
+				MasterAddress: "localhost:3232",
+				SyncInterval:  time.Second,
+				Secret:        "secret",
+			},
+			segments: segments,
+			logger:   zap.NewNop(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			slave, err := application.CreateSlave(test.cfg, test.tlsCfg, test.segments, test.logger)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantNilObj {
+				assert.Nil(t, slave)
+			} else {
+				assert.NotNil(t, slave)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/alukart32/go-fast-key/internal/database"
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
+)
+
+// CreateRESPHandler creates the handler that translates the RESP commands into the database queries.
+func CreateRESPHandler(parser *compute.Parser, db *database.Database) network.RESPHandler {
+	return func(_ context.Context, sess *session.Session, args []string) network.RESPValue {
+		tokens := make([]string, len(args))
+		copy(tokens, args)
+		tokens[0] = strings.ToUpper(tokens[0])
+
+		query, err := parser.ParseTokens(tokens)
+		if err != nil {
+			sess.Transaction().Fail()
+			return respReply(database.ErrorResponse(err))
+		}
+
+		resp := db.HandleQuery(sess, query)
+		if query.CommandID() == compute.PingCommand && len(query.Arguments()) == 0 && resp.Status == database.ValueStatus {
+			// PONG is the status reply, as the clients expect.
+			return network.SimpleString(resp.Value)
+		}
+		return respReply(resp)
+	}
+}
+
+// respReply renders the response as the RESP reply, the error reply starts with the error code
+// and the transaction aborted by a watched key change is the null array.
+func respReply(resp database.Response) network.RESPValue {
+	switch resp.Status {
+	case database.OKStatus:
+		return network.SimpleString("OK")
+	case database.QueuedStatus:
+		return network.SimpleString("QUEUED")
+	case database.ValueStatus:
+		return network.BulkString(resp.Value)
+	case database.IntegerStatus:
+		return network.Integer(resp.Integer)
+	case database.NilStatus:
+		return network.Null()
+	case database.ArrayStatus:
+		replies := make([]network.RESPValue, 0, len(resp.Elements))
+		for _, e := range resp.Elements {
+			replies = append(replies, respReply(e))
+		}
+		return network.Array(replies...)
+	case database.ErrorStatus:
+		if errors.Is(resp.Err, database.ErrWatchedKeyChanged) {
+			return network.NullArray()
+		}
+		return network.ErrorValue(string(resp.Code) + " " + resp.Err.Error())
+	}
+	return network.ErrorValue("ERR unknown response status")
+}
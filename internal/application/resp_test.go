@@ -0,0 +1,247 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/application"
+	"github.com/alukart32/go-fast-key/internal/auth"
+	"github.com/alukart32/go-fast-key/internal/database"
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCreateRESPHandler(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop())
+	require.NoError(t, err)
+
+	handler := application.CreateRESPHandler(parser, db)
+
+	tests := []struct {
+		name string
+		args []string
+		want network.RESPValue
+	}{
+		{
+			name: "Missing key is null",
+			args: []string{"get", "key"},
+			want: network.Null(),
+		},
+		{
+			name: "SET with lower case option",
+			args: []string{"set", "key", "value with spaces", "ex", "100"},
+			want: network.SimpleString("OK"),
+		},
+		{
+			name: "GET is bulk string",
+			args: []string{"GET", "key"},
+			want: network.BulkString("value with spaces"),
+		},
+		{
+			name: "TTL is integer",
+			args: []string{"TTL", "key"},
+			want: network.Integer(100),
+		},
+		{
+			name: "MSET is simple string",
+			args: []string{"mset", "key_1", "val_1", "key_2", "val_2"},
+			want: network.SimpleString("OK"),
+		},
+		{
+			name: "MGET is array with null for missing key",
+			args: []string{"mget", "key_1", "missing", "key_2"},
+			want: network.Array(network.BulkString("val_1"), network.Null(), network.BulkString("val_2")),
+		},
+		{
+			name: "DEL with many keys is simple string",
+			args: []string{"del", "key_1", "key_2"},
+			want: network.SimpleString("OK"),
+		},
+		{
+			name: "INFO is bulk string",
+			args: []string{"info", "keyspace"},
+			want: network.BulkString("# Keyspace\nkeys:1\nexpires:1"),
+		},
+		{
+			name: "PING is simple string",
+			args: []string{"ping"},
+			want: network.SimpleString("PONG"),
+		},
+		{
+			name: "PING with message is bulk string",
+			args: []string{"PING", "hello"},
+			want: network.BulkString("hello"),
+		},
+		{
+			name: "INCRBY is integer",
+			args: []string{"incrby", "counter", "5"},
+			want: network.Integer(5),
+		},
+		{
+			name: "INCRBYFLOAT is bulk string",
+			args: []string{"incrbyfloat", "counter", "0.5"},
+			want: network.BulkString("5.5"),
+		},
+		{
+			name: "INCR of float is error",
+			args: []string{"INCR", "counter"},
+			want: network.ErrorValue("INVALID " + engine.ErrNotInteger.Error()),
+		},
+		{
+			name: "SET NX of existing key is null",
+			args: []string{"set", "key", "value", "nx"},
+			want: network.Null(),
+		},
+		{
+			name: "SET GET is bulk string of previous value",
+			args: []string{"set", "key", "new value", "xx", "get"},
+			want: network.BulkString("value with spaces"),
+		},
+		{
+			name: "CAS is integer",
+			args: []string{"cas", "key", "new value", "value"},
+			want: network.Integer(1),
+		},
+		{
+			name: "Unknown command is error",
+			args: []string{"FLUSHALL"},
+			want: network.ErrorValue("UNKNOWNCMD " + compute.ErrUnknownCommand.Error()),
+		},
+		{
+			name: "Invalid args number is error",
+			args: []string{"GET"},
+			want: network.ErrorValue("SYNTAX " + compute.ErrInvalidArgsNumber.Error()),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, handler(context.Background(), session.New(""), tt.args))
+		})
+	}
+}
+
+func TestCreateRESPHandler_Transaction(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop())
+	require.NoError(t, err)
+
+	handler := application.CreateRESPHandler(parser, db)
+	client, other := session.New(""), session.New("")
+	defer client.Close()
+	defer other.Close()
+
+	tests := []struct {
+		name    string
+		session *session.Session
+		args    []string
+		want    network.RESPValue
+	}{
+		{
+			name:    "MULTI is OK",
+			session: client,
+			args:    []string{"multi"},
+			want:    network.SimpleString("OK"),
+		},
+		{
+			name:    "Command is queued",
+			session: client,
+			args:    []string{"SET", "key", "value"},
+			want:    network.SimpleString("QUEUED"),
+		},
+		{
+			name:    "EXEC is array of replies",
+			session: client,
+			args:    []string{"EXEC"},
+			want:    network.Array(network.SimpleString("OK")),
+		},
+		{
+			name:    "WATCH is OK",
+			session: client,
+			args:    []string{"WATCH", "key"},
+			want:    network.SimpleString("OK"),
+		},
+		{
+			name:    "Watched key is changed",
+			session: other,
+			args:    []string{"DEL", "key"},
+			want:    network.SimpleString("OK"),
+		},
+		{
+			name:    "MULTI after WATCH",
+			session: client,
+			args:    []string{"MULTI"},
+			want:    network.SimpleString("OK"),
+		},
+		{
+			name:    "Aborted EXEC is null array",
+			session: client,
+			args:    []string{"EXEC"},
+			want:    network.NullArray(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, handler(context.Background(), tt.session, tt.args))
+		})
+	}
+}
+
+func TestCreateRESPHandler_Auth(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator([]auth.User{
+		{Name: "svc-a", Token: "token"},
+		{Name: "svc-b", Token: "token", Rules: []string{"+@read", "~svc-b:*"}},
+		{Name: auth.DefaultUser, Token: "default-token"},
+	}, zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop(), database.WithAuthenticator(authenticator))
+	require.NoError(t, err)
+
+	handler := application.CreateRESPHandler(parser, db)
+	client := session.New("127.0.0.1:5000")
+
+	assert.Equal(t,
+		network.ErrorValue("NOAUTH "+database.ErrNoAuth.Error()),
+		handler(context.Background(), client, []string{"GET", "key"}),
+	)
+	assert.Equal(t,
+		network.ErrorValue("NOAUTH "+database.ErrNoAuth.Error()),
+		handler(context.Background(), client, []string{"PING"}),
+	)
+	assert.Equal(t,
+		network.ErrorValue("WRONGPASS "+auth.ErrInvalidCredentials.Error()),
+		handler(context.Background(), client, []string{"AUTH", "svc-a", "guess"}),
+	)
+	assert.Equal(t, network.SimpleString("OK"), handler(context.Background(), client, []string{"auth", "svc-a", "token"}))
+	assert.Equal(t, network.Null(), handler(context.Background(), client, []string{"GET", "key"}))
+	assert.Equal(t, network.SimpleString("PONG"), handler(context.Background(), client, []string{"PING"}))
+	assert.Equal(t,
+		network.Array(
+			network.BulkString("user default ~* +@all"),
+			network.BulkString("user svc-a ~* +@all"),
+			network.BulkString("user svc-b ~svc-b:* -@all +@read"),
+		),
+		handler(context.Background(), client, []string{"ACL", "LIST"}),
+	)
+	assert.Equal(t, network.Null(), handler(context.Background(), client, []string{"ACL", "GETUSER", "svc-c"}))
+
+	restricted := session.New("127.0.0.1:5001")
+	assert.Equal(t, network.SimpleString("OK"), handler(context.Background(), restricted, []string{"AUTH", "svc-b", "token"}))
+	assert.Equal(t,
+		network.ErrorValue("NOPERM no permission: user svc-b can not run write commands"),
+		handler(context.Background(), restricted, []string{"SET", "svc-b:key", "value"}),
+	)
+
+	password := session.New("127.0.0.1:5002")
+	assert.Equal(t, network.SimpleString("OK"), handler(context.Background(), password, []string{"AUTH", "default-token"}))
+	assert.Equal(t, auth.DefaultUser, password.User())
+}
@@ -0,0 +1,48 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/alukart32/go-fast-key/internal/database"
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+	"go.uber.org/zap"
+)
+
+const defaultSnapshotDataDirectory = "./data/snapshots"
+
+// CreateSnapshotStore creates the snapshot store, it returns nil if snapshots are not configured.
+func CreateSnapshotStore(cfg *configuration.Snapshot, logger *zap.Logger) (*snapshot.Store, error) {
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+	if cfg == nil {
+		return nil, nil
+	}
+
+	dir := defaultSnapshotDataDirectory
+	if cfg.DataDirectory != "" {
+		dir = cfg.DataDirectory
+	}
+
+	return snapshot.NewStore(dir, logger)
+}
+
+// runSnapshots takes the database snapshots periodically until ctx is done.
+func runSnapshots(ctx context.Context, interval time.Duration, db *database.Database, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := db.Snapshot(); err != nil {
+				logger.Error("fail to take periodic snapshot", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package application_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/application"
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCreateSnapshotStore(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		cfg    *configuration.Snapshot
+		logger *zap.Logger
+
+		wantErr    error
+		wantNilObj bool
+	}{
+		"create snapshot store without logger": {
+			cfg:        &configuration.Snapshot{},
+			wantErr:    errors.New("logger is nil"),
+			wantNilObj: true,
+		},
+		"create snapshot store without config": {
+			logger:     zap.NewNop(),
+			wantErr:    nil,
+			wantNilObj: true,
+		},
+		"create snapshot store with config fields": {
+			logger: zap.NewNop(),
+			cfg: &configuration.Snapshot{
+				DataDirectory: t.TempDir(),
+			},
+			wantErr: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := application.CreateSnapshotStore(test.cfg, test.logger)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantNilObj {
+				assert.Nil(t, store)
+			} else {
+				assert.NotNil(t, store)
+			}
+		})
+	}
+}
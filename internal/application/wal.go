@@ -0,0 +1,53 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
+	"github.com/alukart32/go-fast-key/internal/pkg/datasize"
+	"go.uber.org/zap"
+)
+
+const defaultWALDataDirectory = "./data/wal"
+
+// CreateWAL creates the write-ahead log, it returns nil if the log is not configured.
+// The options are applied after the configured ones.
+func CreateWAL(cfg *configuration.WAL, logger *zap.Logger, extra ...wal.Option) (*wal.WAL, error) {
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+	if cfg == nil {
+		return nil, nil
+	}
+
+	dir := defaultWALDataDirectory
+	if cfg.DataDirectory != "" {
+		dir = cfg.DataDirectory
+	}
+
+	var options []wal.Option
+	if cfg.MaxSegmentSize != "" {
+		size, err := datasize.Parse(cfg.MaxSegmentSize)
+		if err != nil {
+			return nil, fmt.Errorf("parse segment size: %v", err)
+		}
+
+		options = append(options, wal.WithMaxSegmentSize(uint(size)))
+	}
+
+	if cfg.FlushPolicy != "" {
+		options = append(options, wal.WithFlushPolicy(wal.FlushPolicy(cfg.FlushPolicy)))
+	}
+
+	if cfg.FlushingBatchSize != 0 {
+		options = append(options, wal.WithFlushingBatchSize(uint(cfg.FlushingBatchSize)))
+	}
+
+	if cfg.FlushingBatchTimeout != 0 {
+		options = append(options, wal.WithFlushingBatchTimeout(cfg.FlushingBatchTimeout))
+	}
+
+	return wal.NewWAL(dir, logger, append(options, extra...)...)
+}
@@ -0,0 +1,80 @@
+package application_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/application"
+	"github.com/alukart32/go-fast-key/internal/configuration"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
+	"github.com/alukart32/go-fast-key/internal/pkg/datasize"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCreateWAL(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		cfg    *configuration.WAL
+		logger *zap.Logger
+
+		wantErr    error
+		wantNilObj bool
+	}{
+		"create wal without logger": {
+			cfg:        &configuration.WAL{},
+			wantErr:    errors.New("logger is nil"),
+			wantNilObj: true,
+		},
+		"create wal without config": {
+			logger:     zap.NewNop(),
+			wantErr:    nil,
+			wantNilObj: true,
+		},
+		"create wal with config fields": {
+			logger: zap.NewNop(),
+			cfg: &configuration.WAL{
+				DataDirectory:        t.TempDir(),
+				MaxSegmentSize:       "1MB",
+				FlushPolicy:          "batch",
+				FlushingBatchSize:    100,
+				FlushingBatchTimeout: 10 * time.Millisecond,
+			},
+			wantErr: nil,
+		},
+		"create wal with incorrect segment size": {
+			logger: zap.NewNop(),
+			cfg: &configuration.WAL{
+				DataDirectory:  t.TempDir(),
+				MaxSegmentSize: "1incorrect",
+			},
+			wantErr:    errors.New("parse segment size: " + datasize.ErrInvalidSize.Error()),
+			wantNilObj: true,
+		},
+		"create wal with incorrect flush policy": {
+			logger: zap.NewNop(),
+			cfg: &configuration.WAL{
+				DataDirectory: t.TempDir(),
+				FlushPolicy:   "sometimes",
+			},
+			wantErr:    wal.ErrInvalidFlushPolicy,
+			wantNilObj: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			w, err := application.CreateWAL(test.cfg, test.logger)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantNilObj {
+				assert.Nil(t, w)
+			} else {
+				assert.NotNil(t, w)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// permissions defines the command categories and the key patterns allowed to a user.
+type permissions struct {
+	categories map[compute.Category]struct{}
+	keys       []string
+}
+
+// allPermissions allows every command on every key.
+func allPermissions() permissions {
+	p := permissions{categories: make(map[compute.Category]struct{})}
+	for _, c := range compute.Categories() {
+		p.categories[c] = struct{}{}
+	}
+	p.keys = []string{"*"}
+	return p
+}
+
+func (p permissions) allowCategory(c compute.Category) bool {
+	if c == compute.NoCategory {
+		return true
+	}
+	_, found := p.categories[c]
+	return found
+}
+
+func (p permissions) allowKey(k string) bool {
+	for _, pattern := range p.keys {
+		if matchGlob(pattern, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRule changes the user by the rule:
+//
+//	+@<category>, -@<category> - allow or deny the command category, @all stands for all of them
+//	~<pattern>                 - allow the keys matching the glob pattern
+//	allkeys, resetkeys         - allow all the keys or none of them
+//	reset                      - deny all the commands and keys
+//	><password>                - set the password, passwordHash is its hash made by hashPasswords
+func (u *user) applyRule(rule, passwordHash string) error {
+	switch {
+	case rule == "reset":
+		u.permissions = permissions{categories: make(map[compute.Category]struct{})}
+	case rule == "allkeys":
+		u.permissions.keys = []string{"*"}
+	case rule == "resetkeys":
+		u.permissions.keys = nil
+	case strings.HasPrefix(rule, "~") && len(rule) > 1:
+		u.permissions.keys = append(u.permissions.keys, rule[1:])
+	case strings.HasPrefix(rule, ">"):
+		u.PasswordHash, u.Token = passwordHash, ""
+	case strings.HasPrefix(rule, "+@"), strings.HasPrefix(rule, "-@"):
+		categories := compute.Categories()
+		if name := rule[2:]; name != "all" {
+			c, found := compute.ParseCategory(name)
+			if !found {
+				return fmt.Errorf("%w %q", ErrInvalidRule, rule)
+			}
+			categories = []compute.Category{c}
+		}
+
+		for _, c := range categories {
+			if rule[0] == '+' {
+				u.permissions.categories[c] = struct{}{}
+			} else {
+				delete(u.permissions.categories, c)
+			}
+		}
+	default:
+		return fmt.Errorf("%w %q", ErrInvalidRule, rule)
+	}
+	return nil
+}
+
+// hashPasswords returns the hashes of the passwords set by the rules in order,
+// the hash of any other rule is empty.
+//
+// bcrypt is slow on purpose, so the passwords are hashed before the users are locked.
+func hashPasswords(rules []string) ([]string, error) {
+	hashes := make([]string, len(rules))
+	for i, rule := range rules {
+		password, found := strings.CutPrefix(rule, ">")
+		if !found {
+			continue
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidRule, ">...", err)
+		}
+		hashes[i] = string(hash)
+	}
+	return hashes, nil
+}
+
+// describe returns the rules that give the user permissions.
+func (u *user) describe() string {
+	rules := []string{"user", u.Name}
+	for _, pattern := range u.permissions.keys {
+		rules = append(rules, "~"+pattern)
+	}
+
+	if len(u.permissions.categories) == len(compute.Categories()) {
+		rules = append(rules, "+@all")
+	} else {
+		rules = append(rules, "-@all")
+		for _, c := range compute.Categories() {
+			if u.permissions.allowCategory(c) {
+				rules = append(rules, "+@"+c.String())
+			}
+		}
+	}
+	return strings.Join(rules, " ")
+}
+
+// matchGlob reports whether the key matches the glob pattern: * matches any sequence,
+// ? matches any byte, [abc], [a-z] and [^abc] match a byte of the class and \ escapes the next byte.
+//
+// Unlike path.Match, the patterns treat / as a usual byte.
+func matchGlob(pattern, key string) bool {
+	for len(pattern) != 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) != 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range len(key) + 1 {
+				if matchGlob(pattern, key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			matched, rest, ok := matchClass(pattern[1:], key[0])
+			if !ok {
+				// An unterminated class is a usual byte.
+				if key[0] != '[' {
+					return false
+				}
+				pattern, key = pattern[1:], key[1:]
+				continue
+			}
+			if !matched {
+				return false
+			}
+			pattern, key = rest, key[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+// matchClass matches the byte by the class that follows [ and returns the pattern after the class.
+func matchClass(class string, b byte) (matched bool, rest string, ok bool) {
+	negated := len(class) != 0 && class[0] == '^'
+	if negated {
+		class = class[1:]
+	}
+
+	for i := 0; i < len(class); i++ {
+		c := class[i]
+		switch {
+		case c == ']' && i != 0:
+			return matched != negated, class[i+1:], true
+		case c == '\\' && i+1 < len(class):
+			i++
+			matched = matched || class[i] == b
+		case i+2 < len(class) && class[i+1] == '-' && class[i+2] != ']':
+			lo, hi := c, class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (lo <= b && b <= hi)
+			i += 2
+		default:
+			matched = matched || c == b
+		}
+	}
+	return false, "", false
+}
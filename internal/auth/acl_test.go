@@ -0,0 +1,143 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/auth"
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAuthenticator_Authorize(t *testing.T) {
+	t.Parallel()
+
+	a, err := auth.NewAuthenticator([]auth.User{
+		{Name: "admin", Token: "admin"},
+		{Name: "svc-a", Token: "svc-a", Rules: []string{"+@read", "+@write", "~svc-a:*"}},
+		{Name: "reader", Token: "reader", Rules: []string{"+@all", "-@write", "-@admin", "~/etc/*", "~user_[0-9]?", "~lit\\*"}},
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		user     string
+		category compute.Category
+		keys     []string
+		wantErr  bool
+	}{
+		"user without rules is allowed everything": {
+			user:     "admin",
+			category: compute.AdminCategory,
+		},
+		"allowed category and key": {
+			user:     "svc-a",
+			category: compute.WriteCategory,
+			keys:     []string{"svc-a:balance"},
+		},
+		"denied key": {
+			user:     "svc-a",
+			category: compute.WriteCategory,
+			keys:     []string{"svc-b:balance"},
+			wantErr:  true,
+		},
+		"denied category": {
+			user:     "svc-a",
+			category: compute.AdminCategory,
+			wantErr:  true,
+		},
+		"command without category": {
+			user:     "reader",
+			category: compute.NoCategory,
+		},
+		"star matches slashes": {
+			user:     "reader",
+			category: compute.ReadCategory,
+			keys:     []string{"/etc/nginx/config"},
+		},
+		"class and any byte": {
+			user:     "reader",
+			category: compute.ReadCategory,
+			keys:     []string{"user_7x"},
+		},
+		"class mismatch": {
+			user:     "reader",
+			category: compute.ReadCategory,
+			keys:     []string{"user_x7"},
+			wantErr:  true,
+		},
+		"escaped star": {
+			user:     "reader",
+			category: compute.ReadCategory,
+			keys:     []string{"lit*"},
+		},
+		"escaped star is literal": {
+			user:     "reader",
+			category: compute.ReadCategory,
+			keys:     []string{"literal"},
+			wantErr:  true,
+		},
+		"one of the keys is denied": {
+			user:     "reader",
+			category: compute.ReadCategory,
+			keys:     []string{"/etc/hosts", "/var/log"},
+			wantErr:  true,
+		},
+		"removed category": {
+			user:     "reader",
+			category: compute.WriteCategory,
+			keys:     []string{"/etc/hosts"},
+			wantErr:  true,
+		},
+		"unknown user": {
+			user:     "root",
+			category: compute.ReadCategory,
+			wantErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := a.Authorize(test.user, test.category, test.keys)
+			if test.wantErr {
+				assert.ErrorIs(t, err, auth.ErrNoPermission)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_SetUser(t *testing.T) {
+	t.Parallel()
+
+	a, err := auth.NewAuthenticator([]auth.User{{Name: "admin", Token: "admin"}}, zap.NewNop())
+	require.NoError(t, err)
+
+	// The new user has no permissions and can not log in until it gets the password.
+	require.NoError(t, a.SetUser("svc-a", nil))
+	user, err := a.GetUser("svc-a")
+	require.NoError(t, err)
+	assert.Equal(t, "user svc-a -@all", user)
+	assert.ErrorIs(t, a.Authenticate("127.0.0.1:5000", "svc-a", ""), auth.ErrInvalidCredentials)
+
+	require.NoError(t, a.SetUser("svc-a", []string{">secret", "+@read", "+@write", "~svc-a:*", "~shared:*"}))
+	assert.NoError(t, a.Authenticate("127.0.0.1:5000", "svc-a", "secret"))
+	assert.NoError(t, a.Authorize("svc-a", compute.WriteCategory, []string{"shared:config"}))
+
+	// The invalid rule leaves the user as is.
+	assert.ErrorIs(t, a.SetUser("svc-a", []string{"resetkeys", "+@unknown"}), auth.ErrInvalidRule)
+	assert.NoError(t, a.Authorize("svc-a", compute.WriteCategory, []string{"shared:config"}))
+
+	require.NoError(t, a.SetUser("svc-a", []string{"-@write", "resetkeys", "~svc-a:*"}))
+	assert.ErrorIs(t, a.Authorize("svc-a", compute.WriteCategory, []string{"svc-a:balance"}), auth.ErrNoPermission)
+	assert.ErrorIs(t, a.Authorize("svc-a", compute.ReadCategory, []string{"shared:config"}), auth.ErrNoPermission)
+
+	assert.Equal(t, []string{
+		"user admin ~* +@all",
+		"user svc-a ~svc-a:* -@all +@read",
+	}, a.ListUsers())
+
+	_, err = a.GetUser("svc-b")
+	assert.ErrorIs(t, err, auth.ErrUserNotFound)
+}
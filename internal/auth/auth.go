@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxFailures   = 5
+	defaultFailureWindow = time.Minute
+)
+
+// DefaultUser is the user that AUTH with the password only authenticates.
+const DefaultUser = "default"
+
+// User defines the credentials of a user: the bcrypt or argon2id password hash, or the static token,
+// and the ACL rules of the user. The user without rules is allowed everything.
+type User struct {
+	Name         string
+	PasswordHash string
+	Token        string
+	Rules        []string
+}
+
+// user defines the user with the permissions its rules give.
+type user struct {
+	User
+	permissions permissions
+}
+
+// Authenticator checks the credentials and the permissions of the users.
+//
+// The failed attempts are logged and limited per remote host.
+type Authenticator struct {
+	mtx     sync.RWMutex
+	users   map[string]*user
+	limiter *limiter
+
+	maxFailures   int
+	failureWindow time.Duration
+
+	l *zap.Logger
+}
+
+// NewAuthenticator creates a new Authenticator of the users.
+func NewAuthenticator(users []User, logger *zap.Logger, options ...Option) (*Authenticator, error) {
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+
+	a := &Authenticator{
+		users:         make(map[string]*user, len(users)),
+		maxFailures:   defaultMaxFailures,
+		failureWindow: defaultFailureWindow,
+		l:             logger,
+	}
+	for _, u := range users {
+		if u.Name == "" {
+			return nil, errors.New("user name is empty")
+		}
+		if _, found := a.users[u.Name]; found {
+			return nil, fmt.Errorf("duplicate user: %v", u.Name)
+		}
+
+		switch {
+		case u.PasswordHash != "" && u.Token != "":
+			return nil, fmt.Errorf("user %v has both password hash and token", u.Name)
+		case u.PasswordHash != "":
+			if err := checkHash(u.PasswordHash); err != nil {
+				return nil, fmt.Errorf("user %v: %w", u.Name, err)
+			}
+		case u.Token == "":
+			return nil, fmt.Errorf("user %v has neither password hash nor token", u.Name)
+		}
+
+		created := &user{User: u, permissions: allPermissions()}
+		if u.Rules != nil {
+			created.permissions = permissions{categories: make(map[compute.Category]struct{})}
+		}
+		hashes, err := hashPasswords(u.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("user %v: %w", u.Name, err)
+		}
+		for i, rule := range u.Rules {
+			if err := created.applyRule(rule, hashes[i]); err != nil {
+				return nil, fmt.Errorf("user %v: %w", u.Name, err)
+			}
+		}
+		a.users[u.Name] = created
+	}
+
+	for _, option := range options {
+		option(a)
+	}
+	a.limiter = newLimiter(a.maxFailures, a.failureWindow)
+
+	return a, nil
+}
+
+// Authenticate checks the secret of the user: the password or the token.
+//
+// The remote host is refused for the failure window once it fails too many attempts.
+func (a *Authenticator) Authenticate(remoteAddr, user, secret string) error {
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+
+	if !a.limiter.reserve(host) {
+		a.l.Warn("authentication attempt is refused", zap.String("user", user), zap.String("address", remoteAddr))
+		return ErrTooManyAttempts
+	}
+
+	a.mtx.RLock()
+	u, found := a.users[user]
+	var credentials User
+	if found {
+		credentials = u.User
+	}
+	a.mtx.RUnlock()
+
+	// The unknown user is checked as the one without credentials,
+	// so the response time does not tell whether the user exists.
+	if !credentials.verify(secret) || !found {
+		a.l.Warn("authentication failed", zap.String("user", user), zap.String("address", remoteAddr))
+		return ErrInvalidCredentials
+	}
+
+	a.limiter.release(host)
+	return nil
+}
+
+// verify checks the secret, the user without credentials is never authenticated.
+//
+// The secret of the user without credentials is checked against a dummy hash,
+// so it takes as long as the check of a password.
+func (u User) verify(secret string) bool {
+	switch {
+	case u.PasswordHash != "":
+		return verifyPassword(u.PasswordHash, secret)
+	case u.Token != "":
+		return verifyToken(u.Token, secret)
+	default:
+		verifyPassword(dummyHash(), secret)
+		return false
+	}
+}
+
+// Authorize checks that the user is allowed to run the command of the category on the keys.
+func (a *Authenticator) Authorize(name string, category compute.Category, keys []string) error {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	u, found := a.users[name]
+	if !found {
+		return fmt.Errorf("%w: user %s does not exist", ErrNoPermission, name)
+	}
+	if !u.permissions.allowCategory(category) {
+		return fmt.Errorf("%w: user %s can not run %s commands", ErrNoPermission, name, category)
+	}
+	for _, k := range keys {
+		if !u.permissions.allowKey(k) {
+			return fmt.Errorf("%w: user %s can not access key %s", ErrNoPermission, name, k)
+		}
+	}
+	return nil
+}
+
+// SetUser applies the rules to the user, the missing user is created without permissions.
+// The user is not changed if any rule is invalid.
+func (a *Authenticator) SetUser(name string, rules []string) error {
+	if name == "" {
+		return fmt.Errorf("%w: user name is empty", ErrInvalidRule)
+	}
+
+	hashes, err := hashPasswords(rules)
+	if err != nil {
+		return err
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	changed := &user{
+		User:        User{Name: name},
+		permissions: permissions{categories: make(map[compute.Category]struct{})},
+	}
+	if u, found := a.users[name]; found {
+		changed.User = u.User
+		changed.permissions.keys = slices.Clone(u.permissions.keys)
+		for c := range u.permissions.categories {
+			changed.permissions.categories[c] = struct{}{}
+		}
+	}
+
+	for i, rule := range rules {
+		if err := changed.applyRule(rule, hashes[i]); err != nil {
+			return err
+		}
+	}
+	a.users[name] = changed
+	return nil
+}
+
+// GetUser returns the rules that describe the user permissions.
+func (a *Authenticator) GetUser(name string) (string, error) {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	u, found := a.users[name]
+	if !found {
+		return "", ErrUserNotFound
+	}
+	return u.describe(), nil
+}
+
+// ListUsers returns the rules of all the users ordered by the user name.
+func (a *Authenticator) ListUsers() []string {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	users := make([]string, 0, len(names))
+	for _, name := range names {
+		users = append(users, a.users[name].describe())
+	}
+	return users
+}
+
+// MaxFailures returns the number of the failed attempts a host is refused after.
+func (a *Authenticator) MaxFailures() int {
+	return a.maxFailures
+}
+
+// FailureWindow returns the time the failed attempts are counted within.
+func (a *Authenticator) FailureWindow() time.Duration {
+	return a.failureWindow
+}
@@ -0,0 +1,21 @@
+package auth
+
+import "time"
+
+// Option defines an optional Authenticator setting.
+type Option func(*Authenticator)
+
+// WithMaxFailures sets the number of the failed attempts a host is refused after.
+func WithMaxFailures(n int) Option {
+	return func(a *Authenticator) {
+		a.maxFailures = n
+	}
+}
+
+// WithFailureWindow sets the time the failed attempts are counted within,
+// the host is refused until the window of its first failure ends.
+func WithFailureWindow(window time.Duration) Option {
+	return func(a *Authenticator) {
+		a.failureWindow = window
+	}
+}
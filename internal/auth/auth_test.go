@@ -0,0 +1,193 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		users   []auth.User
+		logger  *zap.Logger
+		wantErr error
+	}{
+		"create without logger": {
+			wantErr: errors.New("logger is nil"),
+		},
+		"create without users": {
+			logger: zap.NewNop(),
+		},
+		"create with empty user name": {
+			users:   []auth.User{{Token: "token"}},
+			logger:  zap.NewNop(),
+			wantErr: errors.New("user name is empty"),
+		},
+		"create with duplicate user": {
+			users:   []auth.User{{Name: "svc", Token: "a"}, {Name: "svc", Token: "b"}},
+			logger:  zap.NewNop(),
+			wantErr: errors.New("duplicate user: svc"),
+		},
+		"create with user without credentials": {
+			users:   []auth.User{{Name: "svc"}},
+			logger:  zap.NewNop(),
+			wantErr: errors.New("user svc has neither password hash nor token"),
+		},
+		"create with user with both credentials": {
+			users:   []auth.User{{Name: "svc", Token: "a", PasswordHash: bcryptHash(t, "b")}},
+			logger:  zap.NewNop(),
+			wantErr: errors.New("user svc has both password hash and token"),
+		},
+		"create with plain text password": {
+			users:   []auth.User{{Name: "svc", PasswordHash: "secret"}},
+			logger:  zap.NewNop(),
+			wantErr: fmt.Errorf("user svc: %w", auth.ErrUnsupportedHash),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := auth.NewAuthenticator(test.users, test.logger)
+			if test.wantErr != nil {
+				assert.Nil(t, a)
+				assert.EqualError(t, err, test.wantErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, a)
+		})
+	}
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	a, err := auth.NewAuthenticator([]auth.User{
+		{Name: "admin", PasswordHash: bcryptHash(t, "admin-password")},
+		{Name: "svc-a", PasswordHash: argon2idHash("svc-a-password")},
+		{Name: "svc-b", Token: "svc-b-token"},
+	}, zap.NewNop(), auth.WithMaxFailures(100))
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		user    string
+		secret  string
+		wantErr error
+	}{
+		"bcrypt password": {
+			user:   "admin",
+			secret: "admin-password",
+		},
+		"argon2id password": {
+			user:   "svc-a",
+			secret: "svc-a-password",
+		},
+		"token": {
+			user:   "svc-b",
+			secret: "svc-b-token",
+		},
+		"invalid bcrypt password": {
+			user:    "admin",
+			secret:  "svc-a-password",
+			wantErr: auth.ErrInvalidCredentials,
+		},
+		"invalid argon2id password": {
+			user:    "svc-a",
+			secret:  "admin-password",
+			wantErr: auth.ErrInvalidCredentials,
+		},
+		"invalid token": {
+			user:    "svc-b",
+			secret:  "svc-b-token-2",
+			wantErr: auth.ErrInvalidCredentials,
+		},
+		"unknown user": {
+			user:    "root",
+			secret:  "svc-b-token",
+			wantErr: auth.ErrInvalidCredentials,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.wantErr, a.Authenticate("127.0.0.1:5000", test.user, test.secret))
+		})
+	}
+}
+
+func TestAuthenticator_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	a, err := auth.NewAuthenticator(
+		[]auth.User{{Name: "svc", Token: "token"}},
+		zap.NewNop(),
+		auth.WithMaxFailures(2),
+		auth.WithFailureWindow(100*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, auth.ErrInvalidCredentials, a.Authenticate("10.0.0.1:5000", "svc", "guess-1"))
+	assert.Equal(t, auth.ErrInvalidCredentials, a.Authenticate("10.0.0.1:5001", "svc", "guess-2"))
+
+	// The host is refused even with the valid token, the other hosts are not.
+	assert.Equal(t, auth.ErrTooManyAttempts, a.Authenticate("10.0.0.1:5002", "svc", "token"))
+	assert.NoError(t, a.Authenticate("10.0.0.2:5000", "svc", "token"))
+
+	time.Sleep(150 * time.Millisecond)
+	assert.NoError(t, a.Authenticate("10.0.0.1:5003", "svc", "token"))
+}
+
+func TestAuthenticator_RateLimitParallel(t *testing.T) {
+	t.Parallel()
+
+	a, err := auth.NewAuthenticator(
+		[]auth.User{{Name: "svc", Token: "token"}},
+		zap.NewNop(),
+		auth.WithMaxFailures(3),
+		auth.WithFailureWindow(time.Minute),
+	)
+	require.NoError(t, err)
+
+	errs := make(chan error, 20)
+	for i := range cap(errs) {
+		go func() {
+			errs <- a.Authenticate(fmt.Sprintf("10.0.0.1:%d", 5000+i), "svc", "guess")
+		}()
+	}
+
+	var failed int
+	for range cap(errs) {
+		if errors.Is(<-errs, auth.ErrInvalidCredentials) {
+			failed++
+		}
+	}
+	assert.Equal(t, 3, failed, "parallel attempts must not exceed the max failures")
+}
+
+func bcryptHash(t *testing.T, password string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(hash)
+}
+
+func argon2idHash(password string) string {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, 1, 64, 1, 32)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=64,t=1,p=1$%s$%s",
+		argon2.Version, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	)
+}
@@ -0,0 +1,12 @@
+package auth
+
+import "errors"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username-password pair")
+	ErrTooManyAttempts    = errors.New("too many failed authentication attempts")
+	ErrUnsupportedHash    = errors.New("unsupported password hash")
+	ErrNoPermission       = errors.New("no permission")
+	ErrInvalidRule        = errors.New("invalid acl rule")
+	ErrUserNotFound       = errors.New("user not found")
+)
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2idParams defines the parameters of the argon2id hash in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>.
+type argon2idParams struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	hash    []byte
+}
+
+// dummyHash returns the hash the secrets of the users without credentials are checked against.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+	return string(hash)
+})
+
+// checkHash checks that the password hash is a bcrypt hash or an argon2id hash.
+func checkHash(hash string) error {
+	switch {
+	case isBcrypt(hash):
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("%w: %v", ErrUnsupportedHash, err)
+		}
+		return nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		_, err := parseArgon2id(hash)
+		return err
+	default:
+		return ErrUnsupportedHash
+	}
+}
+
+// verifyPassword reports whether the password matches the checked hash.
+func verifyPassword(hash, password string) bool {
+	if isBcrypt(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	params, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	key := argon2.IDKey([]byte(password), params.salt, params.time, params.memory, params.threads, uint32(len(params.hash)))
+	return subtle.ConstantTimeCompare(key, params.hash) == 1
+}
+
+// verifyToken compares the digests of the tokens, so the comparison time does not depend on their lengths.
+func verifyToken(token, secret string) bool {
+	want, got := sha256.Sum256([]byte(token)), sha256.Sum256([]byte(secret))
+	return subtle.ConstantTimeCompare(want[:], got[:]) == 1
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func parseArgon2id(hash string) (argon2idParams, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return argon2idParams{}, fmt.Errorf("%w: invalid argon2id format", ErrUnsupportedHash)
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2idParams{}, fmt.Errorf("%w: argon2id version %q", ErrUnsupportedHash, parts[2])
+	}
+
+	var params argon2idParams
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads)
+	if err != nil || params.memory == 0 || params.time == 0 || params.threads == 0 {
+		return argon2idParams{}, fmt.Errorf("%w: argon2id parameters %q", ErrUnsupportedHash, parts[3])
+	}
+
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2idParams{}, fmt.Errorf("%w: argon2id salt", ErrUnsupportedHash)
+	}
+	if params.hash, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.hash) == 0 {
+		return argon2idParams{}, fmt.Errorf("%w: argon2id hash", ErrUnsupportedHash)
+	}
+	return params, nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// limiterMaxSize is the maximum number of the tracked addresses.
+const limiterMaxSize = 1024
+
+// failures defines the failed attempts of an address within the window.
+type failures struct {
+	count int
+	since time.Time
+}
+
+// limiter refuses the attempts of the address once it fails maxFailures times within the window.
+type limiter struct {
+	mtx         sync.Mutex
+	maxFailures int
+	window      time.Duration
+	addresses   map[string]failures
+	now         func() time.Time
+}
+
+func newLimiter(maxFailures int, window time.Duration) *limiter {
+	return &limiter{
+		maxFailures: maxFailures,
+		window:      window,
+		addresses:   make(map[string]failures),
+		now:         time.Now,
+	}
+}
+
+// reserve reports whether the address may make an attempt, the attempt is counted as failed
+// until it is released. The parallel attempts are counted before they are checked,
+// so they can not exceed maxFailures.
+func (l *limiter) reserve(address string) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	f, found := l.addresses[address]
+	if !found || l.expired(f) {
+		if !found && len(l.addresses) >= limiterMaxSize {
+			l.prune()
+		}
+		f = failures{since: l.now()}
+	}
+	if f.count >= l.maxFailures {
+		return false
+	}
+	f.count++
+	l.addresses[address] = f
+	return true
+}
+
+// release forgets the failed attempts of the address once its attempt succeeds.
+func (l *limiter) release(address string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delete(l.addresses, address)
+}
+
+func (l *limiter) expired(f failures) bool {
+	return l.now().Sub(f.since) >= l.window
+}
+
+// prune removes the expired addresses, the address of the oldest failures
+// is removed if none of them is expired.
+func (l *limiter) prune() {
+	var (
+		oldest      string
+		oldestSince time.Time
+	)
+	for address, f := range l.addresses {
+		if l.expired(f) {
+			delete(l.addresses, address)
+			continue
+		}
+		if oldestSince.IsZero() || f.since.Before(oldestSince) {
+			oldest, oldestSince = address, f.since
+		}
+	}
+	if len(l.addresses) >= limiterMaxSize {
+		delete(l.addresses, oldest)
+	}
+}
@@ -10,20 +10,83 @@ import (
 )
 
 type Config struct {
-	Engine  *Engine  `yaml:"engine"`
-	Network *Network `yaml:"network"`
-	Logging *Logging `yaml:"logging"`
+	Engine      *Engine      `yaml:"engine"`
+	Network     *Network     `yaml:"network"`
+	Logging     *Logging     `yaml:"logging"`
+	WAL         *WAL         `yaml:"wal"`
+	Snapshot    *Snapshot    `yaml:"snapshot"`
+	Replication *Replication `yaml:"replication"`
+	Auth        *Auth        `yaml:"auth"`
+	Metrics     *Metrics     `yaml:"metrics"`
 }
 
 type Engine struct {
-	Type string `yaml:"type"`
+	Type             string `yaml:"type"`
+	PartitionsNumber int    `yaml:"partitions_number"`
+	MaxMemory        string `yaml:"max_memory"`
+	EvictionPolicy   string `yaml:"eviction_policy"`
 }
 
 type Network struct {
-	Address        string        `yaml:"address"`
-	MaxConnections int           `yaml:"max_connections"`
-	MaxMessageSize string        `yaml:"max_message_size"`
-	IdleTimeout    time.Duration `yaml:"idle_timeout"`
+	Address           string        `yaml:"address"`
+	Protocol          string        `yaml:"protocol"`
+	Listeners         []Listener    `yaml:"listeners"`
+	MaxConnections    int           `yaml:"max_connections"`
+	MaxMessageSize    string        `yaml:"max_message_size"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	SocketPermissions string        `yaml:"socket_permissions"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	TLS               *TLS          `yaml:"tls"`
+}
+
+type TLS struct {
+	CertFile          string `yaml:"cert_file"`
+	KeyFile           string `yaml:"key_file"`
+	CAFile            string `yaml:"ca_file"`
+	RequireClientCert bool   `yaml:"require_client_cert"`
+}
+
+type Listener struct {
+	Address  string `yaml:"address"`
+	Protocol string `yaml:"protocol"`
+}
+
+type WAL struct {
+	DataDirectory        string        `yaml:"data_directory"`
+	MaxSegmentSize       string        `yaml:"max_segment_size"`
+	FlushPolicy          string        `yaml:"flush_policy"`
+	FlushingBatchSize    int           `yaml:"flushing_batch_size"`
+	FlushingBatchTimeout time.Duration `yaml:"flushing_batch_timeout"`
+}
+
+type Snapshot struct {
+	DataDirectory string        `yaml:"data_directory"`
+	Interval      time.Duration `yaml:"interval"`
+}
+
+type Replication struct {
+	ReplicaType   string        `yaml:"replica_type"`
+	MasterAddress string        `yaml:"master_address"`
+	SyncInterval  time.Duration `yaml:"sync_interval"`
+	Secret        string        `yaml:"secret"`
+}
+
+type Auth struct {
+	Users         []User        `yaml:"users"`
+	MaxFailures   int           `yaml:"max_failures"`
+	FailureWindow time.Duration `yaml:"failure_window"`
+}
+
+type User struct {
+	Name         string   `yaml:"name"`
+	PasswordHash string   `yaml:"password_hash"`
+	Token        string   `yaml:"token"`
+	Rules        []string `yaml:"rules"`
+}
+
+type Metrics struct {
+	Address string `yaml:"address"`
+	Path    string `yaml:"path"`
 }
 
 type Logging struct {
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/alukart32/go-fast-key/internal/auth"
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+)
+
+func (db *Database) doACLSetUser(q compute.Query) error {
+	if db.auth == nil {
+		return ErrAuthDisabled
+	}
+
+	args := q.Arguments()
+	return db.auth.SetUser(args[0], args[1:])
+}
+
+// doACLGetUser returns the rules of the user, nil if the user does not exist.
+func (db *Database) doACLGetUser(q compute.Query) (Response, error) {
+	if db.auth == nil {
+		return Response{}, ErrAuthDisabled
+	}
+
+	rules, err := db.auth.GetUser(q.Arguments()[0])
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		return Nil(), nil
+	case err != nil:
+		return Response{}, err
+	}
+	return Value(rules), nil
+}
+
+// doACLList returns the rules of the users.
+func (db *Database) doACLList() (Response, error) {
+	if db.auth == nil {
+		return Response{}, ErrAuthDisabled
+	}
+
+	users := db.auth.ListUsers()
+	elements := make([]Response, 0, len(users))
+	for _, u := range users {
+		elements = append(elements, Value(u))
+	}
+	return Array(elements...), nil
+}
@@ -0,0 +1,65 @@
+package compute
+
+import "strings"
+
+// Category defines the group of the commands the permissions are granted for.
+type Category int
+
+const (
+	// NoCategory is the category of the connection and transaction commands
+	// that are allowed to any authenticated user.
+	NoCategory Category = iota
+	ReadCategory
+	WriteCategory
+	AdminCategory
+)
+
+var categoryNames = map[Category]string{
+	ReadCategory:  "read",
+	WriteCategory: "write",
+	AdminCategory: "admin",
+}
+
+// Categories returns the categories the permissions are granted for.
+func Categories() []Category {
+	return []Category{ReadCategory, WriteCategory, AdminCategory}
+}
+
+// ParseCategory returns the category by its case-insensitive name.
+func ParseCategory(name string) (Category, bool) {
+	for c, n := range categoryNames {
+		if strings.EqualFold(n, name) {
+			return c, true
+		}
+	}
+	return NoCategory, false
+}
+
+func (c Category) String() string {
+	return categoryNames[c]
+}
+
+var commandCategoryByID = map[CommandID]Category{
+	GetCommand:   ReadCategory,
+	TTLCommand:   ReadCategory,
+	WatchCommand: ReadCategory,
+	MGetCommand:  ReadCategory,
+
+	SetCommand:     WriteCategory,
+	DelCommand:     WriteCategory,
+	ExpireCommand:  WriteCategory,
+	PersistCommand: WriteCategory,
+	MSetCommand:    WriteCategory,
+
+	IncrCommand:        WriteCategory,
+	DecrCommand:        WriteCategory,
+	IncrByCommand:      WriteCategory,
+	IncrByFloatCommand: WriteCategory,
+	CASCommand:         WriteCategory,
+
+	SnapshotCommand:   AdminCategory,
+	ACLSetUserCommand: AdminCategory,
+	ACLGetUserCommand: AdminCategory,
+	ACLListCommand:    AdminCategory,
+	InfoCommand:       AdminCategory,
+}
@@ -1,10 +1,33 @@
 package compute
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrStandByParser     = errors.New("stand-by parser")
 	ErrEmptyRequest      = errors.New("empty request")
 	ErrInvalidArgsNumber = errors.New("invalid args number")
 	ErrUnknownCommand    = errors.New("unknown command")
+	ErrInvalidOption     = errors.New("invalid option")
+
+	ErrUnterminatedQuote = errors.New("unterminated quote")
+	ErrInvalidEscape     = errors.New("invalid escape sequence")
+	ErrInvalidRawLength  = errors.New("invalid raw string length")
+	ErrMissingSeparator  = errors.New("missing whitespace after argument")
 )
+
+// SyntaxError defines the error of the request tokenizing at the byte offset of the request.
+type SyntaxError struct {
+	Offset int
+	Err    error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%v at byte %d", e.Err, e.Offset)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
@@ -20,26 +20,76 @@ func NewParser(logger *zap.Logger) (*Parser, error) {
 	return &Parser{l: logger}, nil
 }
 
-// Parse converts the request into a query.
+// Parse converts the request into a query, see Tokenize for the syntax of the arguments.
 func (p *Parser) Parse(req string) (Query, error) {
-	tokens := strings.Fields(strings.TrimSpace(req))
+	tokens, err := Tokenize(req)
+	if err != nil {
+		p.l.Debug("invalid request syntax", zap.Int("size", len(req)), zap.Error(err))
+		return Query{}, err
+	}
+	return p.ParseTokens(tokens)
+}
+
+// ParseTokens converts the command name followed by its arguments into a query.
+//
+// Subcommand and option names are case-insensitive.
+func (p *Parser) ParseTokens(tokens []string) (Query, error) {
 	if len(tokens) == 0 {
-		p.l.Debug("empty tokens", zap.String("request", req))
+		p.l.Debug("empty tokens")
 		return Query{}, ErrEmptyRequest
 	}
 
-	command := tokens[0]
+	command, args := tokens[0], tokens[1:]
+	if hasSubcommands(command) && len(args) != 0 {
+		command, args = command+" "+strings.ToUpper(args[0]), args[1:]
+	}
+
 	commandID, err := commandNameToCommandID(command)
 	if err != nil {
-		p.l.Debug("invalid command", zap.String("request", req))
+		p.l.Debug("invalid command", requestFields(tokens)...)
 		return Query{}, err
 	}
 
-	query := NewQuery(commandID, tokens[1:])
-	argsNumber := commandIDToArgsNumber(query.commandID)
-	if len(query.arguments) != argsNumber {
-		p.l.Debug("invalid arguments for query", zap.String("request", req))
+	argsNumber, ok := commandArityByID[commandID].argsNumber(len(args))
+	if !ok {
+		p.l.Debug("invalid arguments for query", requestFields(tokens)...)
 		return Query{}, ErrInvalidArgsNumber
 	}
+
+	query := NewQuery(commandID, args[:argsNumber])
+	for options := args[argsNumber:]; len(options) != 0; {
+		name := strings.ToUpper(options[0])
+		valuesNumber, found := commandOptionValuesNumber(commandID, name)
+		if !found {
+			p.l.Debug("invalid arguments for query", requestFields(tokens)...)
+			if len(commandOptionsByID[commandID]) == 0 {
+				return Query{}, ErrInvalidArgsNumber
+			}
+			return Query{}, ErrInvalidOption
+		}
+		if _, set := query.Option(name); set || len(options) <= valuesNumber {
+			p.l.Debug("invalid option for query", requestFields(tokens)...)
+			return Query{}, ErrInvalidOption
+		}
+
+		var values []string
+		if valuesNumber != 0 {
+			values = options[1 : valuesNumber+1]
+		}
+		query = query.WithOption(name, values...)
+		options = options[valuesNumber+1:]
+	}
+	if exclusiveOptionsSet(query) {
+		p.l.Debug("exclusive options for query", requestFields(tokens)...)
+		return Query{}, ErrInvalidOption
+	}
 	return query, nil
 }
+
+// requestFields returns the log fields of the request tokens.
+//
+// Only the command name and the number of arguments are logged,
+// as the arguments may hold credentials, e.g. of AUTH or ACL SETUSER.
+func requestFields(tokens []string) []zap.Field {
+	return []zap.Field{zap.String("command", tokens[0]), zap.Int("args", len(tokens)-1)}
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestParser_Parse(t *testing.T) {
@@ -46,14 +48,164 @@ func TestParser_Parse(t *testing.T) {
 		},
 		{
 			name:    "DEL command invalid args number",
-			req:     "DEL key1 key2 key3",
+			req:     "DEL",
 			wantErr: compute.ErrInvalidArgsNumber,
 		},
+		{
+			name: "Valid DEL request with many keys",
+			req:  "DEL key1 key2 key3",
+			want: compute.NewQuery(compute.DelCommand, []string{"key1", "key2", "key3"}),
+		},
+		{
+			name: "Valid MDEL request is DEL",
+			req:  "MDEL key1 key2",
+			want: compute.NewQuery(compute.DelCommand, []string{"key1", "key2"}),
+		},
+		{
+			name: "Valid MSET request",
+			req:  "MSET key1 val1 key2 val2",
+			want: compute.NewQuery(compute.MSetCommand, []string{"key1", "val1", "key2", "val2"}),
+		},
+		{
+			name:    "MSET command without value",
+			req:     "MSET key1 val1 key2",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name:    "MSET command invalid args number",
+			req:     "MSET key1",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name: "Valid MGET request",
+			req:  "MGET key1 key2",
+			want: compute.NewQuery(compute.MGetCommand, []string{"key1", "key2"}),
+		},
+		{
+			name:    "MGET command invalid args number",
+			req:     "MGET",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name:    "SNAPSHOT command invalid args number",
+			req:     "SNAPSHOT now",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name: "Valid SNAPSHOT request",
+			req:  "SNAPSHOT",
+			want: compute.NewQuery(compute.SnapshotCommand, []string{}),
+		},
+		{
+			name: "Valid MULTI request",
+			req:  "MULTI",
+			want: compute.NewQuery(compute.MultiCommand, []string{}),
+		},
+		{
+			name:    "EXEC command invalid args number",
+			req:     "EXEC now",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name:    "WATCH command invalid args number",
+			req:     "WATCH",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name: "Valid WATCH request with many keys",
+			req:  "WATCH key1 key2 key3",
+			want: compute.NewQuery(compute.WatchCommand, []string{"key1", "key2", "key3"}),
+		},
+		{
+			name: "Valid ACL SETUSER request",
+			req:  "ACL setuser svc-a +@read ~svc-a:*",
+			want: compute.NewQuery(compute.ACLSetUserCommand, []string{"svc-a", "+@read", "~svc-a:*"}),
+		},
+		{
+			name: "Valid ACL LIST request",
+			req:  "ACL LIST",
+			want: compute.NewQuery(compute.ACLListCommand, []string{}),
+		},
+		{
+			name:    "ACL command without subcommand",
+			req:     "ACL",
+			wantErr: compute.ErrUnknownCommand,
+		},
+		{
+			name: "Valid INFO request",
+			req:  "INFO",
+			want: compute.NewQuery(compute.InfoCommand, []string{}),
+		},
+		{
+			name: "Valid INFO request with section",
+			req:  "INFO memory",
+			want: compute.NewQuery(compute.InfoCommand, []string{"memory"}),
+		},
+		{
+			name:    "INFO command invalid args number",
+			req:     "INFO memory wal",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name:    "ACL command unknown subcommand",
+			req:     "ACL DELUSER svc-a",
+			wantErr: compute.ErrUnknownCommand,
+		},
 		{
 			name: "Valid SET request",
 			req:  "SET key val",
 			want: compute.NewQuery(compute.SetCommand, []string{"key", "val"}),
 		},
+		{
+			name: "Valid SET request with expiration",
+			req:  "SET key val EX 10",
+			want: compute.NewQuery(compute.SetCommand, []string{"key", "val"}).WithOption(compute.ExpireOption, "10"),
+		},
+		{
+			name: "Valid SET request with lower case option",
+			req:  "SET key val ex 10",
+			want: compute.NewQuery(compute.SetCommand, []string{"key", "val"}).WithOption(compute.ExpireOption, "10"),
+		},
+		{
+			name:    "SET command unknown option",
+			req:     "SET key val PX 10",
+			wantErr: compute.ErrInvalidOption,
+		},
+		{
+			name:    "SET command option without value",
+			req:     "SET key val EX",
+			wantErr: compute.ErrInvalidOption,
+		},
+		{
+			name:    "SET command repeated option",
+			req:     "SET key val EX 10 EX 20",
+			wantErr: compute.ErrInvalidOption,
+		},
+		{
+			name: "Valid EXPIRE request",
+			req:  "EXPIRE key 10",
+			want: compute.NewQuery(compute.ExpireCommand, []string{"key", "10"}),
+		},
+		{
+			name:    "EXPIRE command invalid args number",
+			req:     "EXPIRE key",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name: "Valid TTL request",
+			req:  "TTL key",
+			want: compute.NewQuery(compute.TTLCommand, []string{"key"}),
+		},
+		{
+			name: "Valid PERSIST request",
+			req:  "PERSIST key",
+			want: compute.NewQuery(compute.PersistCommand, []string{"key"}),
+		},
+		{
+			name:    "PERSIST command invalid args number",
+			req:     "PERSIST key1 key2",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
 		{
 			name: "Valid GET request",
 			req:  "GET key",
@@ -64,6 +216,95 @@ func TestParser_Parse(t *testing.T) {
 			req:  "DEL key",
 			want: compute.NewQuery(compute.DelCommand, []string{"key"}),
 		},
+		{
+			name: "Valid AUTH request",
+			req:  "AUTH user secret",
+			want: compute.NewQuery(compute.AuthCommand, []string{"user", "secret"}),
+		},
+		{
+			name: "Valid AUTH request with password only",
+			req:  "AUTH secret",
+			want: compute.NewQuery(compute.AuthCommand, []string{"secret"}),
+		},
+		{
+			name:    "AUTH command invalid args number",
+			req:     "AUTH",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name: "Valid PING request",
+			req:  "PING",
+			want: compute.NewQuery(compute.PingCommand, []string{}),
+		},
+		{
+			name: "Valid PING request with message",
+			req:  "PING hello",
+			want: compute.NewQuery(compute.PingCommand, []string{"hello"}),
+		},
+		{
+			name:    "PING command invalid args number",
+			req:     "PING hello world",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name: "Valid INCRBY request with negative delta",
+			req:  "INCRBY counter -5",
+			want: compute.NewQuery(compute.IncrByCommand, []string{"counter", "-5"}),
+		},
+		{
+			name:    "INCR command invalid args number",
+			req:     "INCR counter 5",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name:    "INCRBYFLOAT command invalid args number",
+			req:     "INCRBYFLOAT counter",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name: "Valid SET request with flags",
+			req:  "SET lock owner ex 10 nx GET",
+			want: compute.NewQuery(compute.SetCommand, []string{"lock", "owner"}).
+				WithOption(compute.ExpireOption, "10").
+				WithOption(compute.NotExistsOption).
+				WithOption(compute.GetOption),
+		},
+		{
+			name:    "SET request with exclusive flags",
+			req:     "SET lock owner NX XX",
+			wantErr: compute.ErrInvalidOption,
+		},
+		{
+			name:    "SET request with repeated flag",
+			req:     "SET lock owner GET GET",
+			wantErr: compute.ErrInvalidOption,
+		},
+		{
+			name: "Valid CAS request",
+			req:  "CAS lock owner_1 owner_2",
+			want: compute.NewQuery(compute.CASCommand, []string{"lock", "owner_1", "owner_2"}),
+		},
+		{
+			name:    "CAS command invalid args number",
+			req:     "CAS lock owner_1",
+			wantErr: compute.ErrInvalidArgsNumber,
+		},
+		{
+			name: "Valid SET request with quoted value",
+			req:  `SET "user name" "{\"name\": \"fast key\"}" EX 10`,
+			want: compute.NewQuery(compute.SetCommand, []string{"user name", `{"name": "fast key"}`}).
+				WithOption(compute.ExpireOption, "10"),
+		},
+		{
+			name: "Valid SET request with raw value",
+			req:  "SET key {11}hello world",
+			want: compute.NewQuery(compute.SetCommand, []string{"key", "hello world"}),
+		},
+		{
+			name:    "SET request with unterminated quote",
+			req:     `SET key "hello world`,
+			wantErr: &compute.SyntaxError{Offset: 8, Err: compute.ErrUnterminatedQuote},
+		},
 	}
 
 	parser, err := compute.NewParser(zap.NewNop())
@@ -110,3 +351,38 @@ func TestNewParser(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_ParseTokens(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	got, err := parser.ParseTokens([]string{"SET", "key", "value with spaces"})
+	require.NoError(t, err)
+	assert.Equal(t, compute.NewQuery(compute.SetCommand, []string{"key", "value with spaces"}), got)
+
+	_, err = parser.ParseTokens(nil)
+	assert.Equal(t, compute.ErrEmptyRequest, err)
+}
+
+func TestParser_LogsNoArguments(t *testing.T) {
+	requests := []string{
+		`AUTH user s3cr3t extra`,
+		`AUTH user "s3cr3t`,
+		`ACL SETUSER user ">s3cr3t`,
+		`SET key s3cr3t EX 1 XX NX`,
+	}
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	parser, err := compute.NewParser(zap.New(core))
+	require.NoError(t, err)
+
+	for _, req := range requests {
+		_, err := parser.Parse(req)
+		require.Error(t, err, req)
+	}
+
+	require.NotZero(t, logs.Len())
+	for _, entry := range logs.All() {
+		assert.NotContains(t, fmt.Sprint(entry.Message, entry.ContextMap()), "s3cr3t")
+	}
+}
@@ -8,15 +8,94 @@ const (
 	SetCommand
 	GetCommand
 	DelCommand
+	SnapshotCommand
+	ExpireCommand
+	TTLCommand
+	PersistCommand
+	MultiCommand
+	ExecCommand
+	DiscardCommand
+	WatchCommand
+	AuthCommand
+	PingCommand
+	ACLSetUserCommand
+	ACLGetUserCommand
+	ACLListCommand
+	InfoCommand
+	MSetCommand
+	MGetCommand
+	IncrCommand
+	DecrCommand
+	IncrByCommand
+	IncrByFloatCommand
+	CASCommand
 )
 
 var commandIdsByName = map[string]CommandID{
-	"SET": SetCommand,
-	"GET": GetCommand,
-	"DEL": DelCommand,
+	"SET":      SetCommand,
+	"GET":      GetCommand,
+	"DEL":      DelCommand,
+	"SNAPSHOT": SnapshotCommand,
+	"EXPIRE":   ExpireCommand,
+	"TTL":      TTLCommand,
+	"PERSIST":  PersistCommand,
+	"MULTI":    MultiCommand,
+	"EXEC":     ExecCommand,
+	"DISCARD":  DiscardCommand,
+	"WATCH":    WatchCommand,
+	"AUTH":     AuthCommand,
+	"INFO":     InfoCommand,
+	"MSET":     MSetCommand,
+	"MGET":     MGetCommand,
+	"PING":     PingCommand,
+
+	"INCR":        IncrCommand,
+	"DECR":        DecrCommand,
+	"INCRBY":      IncrByCommand,
+	"INCRBYFLOAT": IncrByFloatCommand,
+	"CAS":         CASCommand,
+
+	"ACL SETUSER": ACLSetUserCommand,
+	"ACL GETUSER": ACLGetUserCommand,
+	"ACL LIST":    ACLListCommand,
+}
+
+// commandsWithSubcommands defines the commands whose name is followed by the subcommand name.
+var commandsWithSubcommands = map[string]struct{}{
+	"ACL": {},
+}
+
+func hasSubcommands(name string) bool {
+	_, found := commandsWithSubcommands[name]
+	return found
+}
+
+var commandNamesByID = func() map[CommandID]string {
+	names := make(map[CommandID]string, len(commandIdsByName))
+	for name, id := range commandIdsByName {
+		names[id] = name
+	}
+	return names
+}()
+
+// String returns the name of the command, UNKNOWN for the unknown command.
+func (id CommandID) String() string {
+	if name, found := commandNamesByID[id]; found {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// commandAliases defines the alternative names of the commands.
+var commandAliases = map[string]string{
+	"MDEL": "DEL",
 }
 
 func commandNameToCommandID(name string) (CommandID, error) {
+	if alias, found := commandAliases[name]; found {
+		name = alias
+	}
+
 	if command, found := commandIdsByName[name]; !found {
 		return UnknownCommand, ErrUnknownCommand
 	} else {
@@ -24,20 +103,113 @@ func commandNameToCommandID(name string) (CommandID, error) {
 	}
 }
 
-var commandArgsNumberByID = map[CommandID]int{
-	SetCommand: 2,
-	GetCommand: 1,
-	DelCommand: 1,
+// anyArgsNumber is the max number of arguments of the variadic commands.
+const anyArgsNumber = -1
+
+// arity defines the number of the arguments the command takes before its options.
+type arity struct {
+	min int
+	// max is anyArgsNumber for the variadic commands.
+	max int
+	// even requires the arguments to come in pairs.
+	even bool
+}
+
+var commandArityByID = map[CommandID]arity{
+	SetCommand:      {min: 2, max: 2},
+	GetCommand:      {min: 1, max: 1},
+	DelCommand:      {min: 1, max: anyArgsNumber},
+	SnapshotCommand: {},
+	ExpireCommand:   {min: 2, max: 2},
+	TTLCommand:      {min: 1, max: 1},
+	PersistCommand:  {min: 1, max: 1},
+	MultiCommand:    {},
+	ExecCommand:     {},
+	DiscardCommand:  {},
+	WatchCommand:    {min: 1, max: anyArgsNumber},
+	AuthCommand:     {min: 1, max: 2},
+	InfoCommand:     {min: 0, max: 1},
+	MSetCommand:     {min: 2, max: anyArgsNumber, even: true},
+	MGetCommand:     {min: 1, max: anyArgsNumber},
+	PingCommand:     {min: 0, max: 1},
+
+	IncrCommand:        {min: 1, max: 1},
+	DecrCommand:        {min: 1, max: 1},
+	IncrByCommand:      {min: 2, max: 2},
+	IncrByFloatCommand: {min: 2, max: 2},
+	CASCommand:         {min: 3, max: 3},
+
+	ACLSetUserCommand: {min: 1, max: anyArgsNumber},
+	ACLGetUserCommand: {min: 1, max: 1},
+	ACLListCommand:    {},
 }
 
-func commandIDToArgsNumber(id CommandID) int {
-	return commandArgsNumberByID[id]
+// argsNumber returns the number of the given arguments the command takes,
+// the rest are its options. The arguments are invalid if ok is false.
+func (a arity) argsNumber(given int) (n int, ok bool) {
+	if given < a.min {
+		return 0, false
+	}
+
+	n = given
+	if a.max != anyArgsNumber {
+		n = min(n, a.max)
+	}
+	if a.even && n%2 != 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+const (
+	// ExpireOption sets the key time to live in seconds.
+	ExpireOption = "EX"
+	// NotExistsOption sets the key only if it does not exist.
+	NotExistsOption = "NX"
+	// ExistsOption sets the key only if it exists.
+	ExistsOption = "XX"
+	// GetOption returns the value the key had before it was set.
+	GetOption = "GET"
+)
+
+// commandOptionsByID defines the options that may follow the command
+// arguments and the number of values each option takes, the flags take none.
+var commandOptionsByID = map[CommandID]map[string]int{
+	SetCommand: {
+		ExpireOption:    1,
+		NotExistsOption: 0,
+		ExistsOption:    0,
+		GetOption:       0,
+	},
+}
+
+func commandOptionValuesNumber(id CommandID, name string) (int, bool) {
+	number, found := commandOptionsByID[id][name]
+	return number, found
+}
+
+// commandExclusiveOptionsByID defines the options of the command that can not be set together.
+var commandExclusiveOptionsByID = map[CommandID][][2]string{
+	SetCommand: {{NotExistsOption, ExistsOption}},
+}
+
+// exclusiveOptionsSet reports whether the query sets the options that exclude each other.
+func exclusiveOptionsSet(q Query) bool {
+	for _, pair := range commandExclusiveOptionsByID[q.commandID] {
+		_, first := q.Option(pair[0])
+		_, second := q.Option(pair[1])
+		if first && second {
+			return true
+		}
+	}
+	return false
 }
 
 // Query defines the command and its arguments to execute.
 type Query struct {
 	commandID CommandID
 	arguments []string
+	options   map[string][]string
 }
 
 // NewQuery creates a new Query.
@@ -48,6 +220,18 @@ func NewQuery(commandID CommandID, arguments []string) Query {
 	}
 }
 
+// WithOption returns a copy of the query with the option set to the values.
+func (c Query) WithOption(name string, values ...string) Query {
+	options := make(map[string][]string, len(c.options)+1)
+	for k, v := range c.options {
+		options[k] = v
+	}
+	options[name] = values
+
+	c.options = options
+	return c
+}
+
 // CommandID returns the ID of the command.
 func (c *Query) CommandID() CommandID {
 	return c.commandID
@@ -57,3 +241,32 @@ func (c *Query) CommandID() CommandID {
 func (c *Query) Arguments() []string {
 	return c.arguments
 }
+
+// Category returns the category of the command.
+func (c *Query) Category() Category {
+	return commandCategoryByID[c.commandID]
+}
+
+// Keys returns the arguments of the command that are keys.
+func (c *Query) Keys() []string {
+	switch c.commandID {
+	case SetCommand, GetCommand, ExpireCommand, TTLCommand, PersistCommand,
+		IncrCommand, DecrCommand, IncrByCommand, IncrByFloatCommand, CASCommand:
+		return c.arguments[:1]
+	case DelCommand, MGetCommand, WatchCommand:
+		return c.arguments
+	case MSetCommand:
+		keys := make([]string, 0, len(c.arguments)/2)
+		for i := 0; i < len(c.arguments); i += 2 {
+			keys = append(keys, c.arguments[i])
+		}
+		return keys
+	}
+	return nil
+}
+
+// Option returns the values of the option and whether the option is set.
+func (c *Query) Option(name string) ([]string, bool) {
+	values, found := c.options[name]
+	return values, found
+}
@@ -17,3 +17,90 @@ func TestNewQuery(t *testing.T) {
 	assert.EqualValues(t, expectedArguments, gotQuery.Arguments(),
 		"gotQuery.Arguments() = %v, want = %v", gotQuery.Arguments(), expectedArguments)
 }
+
+func TestQuery_WithOption(t *testing.T) {
+	query := compute.NewQuery(compute.SetCommand, []string{"key", "value"})
+	withOption := query.WithOption(compute.ExpireOption, "10")
+
+	_, found := query.Option(compute.ExpireOption)
+	assert.False(t, found, "the original query must not be changed")
+
+	values, found := withOption.Option(compute.ExpireOption)
+	assert.True(t, found)
+	assert.Equal(t, []string{"10"}, values)
+}
+
+func TestQuery_KeysAndCategory(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        compute.Query
+		wantKeys     []string
+		wantCategory compute.Category
+	}{
+		{
+			name:         "SET writes the key",
+			query:        compute.NewQuery(compute.SetCommand, []string{"key", "value"}),
+			wantKeys:     []string{"key"},
+			wantCategory: compute.WriteCategory,
+		},
+		{
+			name:         "WATCH reads all the keys",
+			query:        compute.NewQuery(compute.WatchCommand, []string{"key1", "key2"}),
+			wantKeys:     []string{"key1", "key2"},
+			wantCategory: compute.ReadCategory,
+		},
+		{
+			name:         "MSET writes every other argument",
+			query:        compute.NewQuery(compute.MSetCommand, []string{"key1", "val1", "key2", "val2"}),
+			wantKeys:     []string{"key1", "key2"},
+			wantCategory: compute.WriteCategory,
+		},
+		{
+			name:         "MGET reads all the keys",
+			query:        compute.NewQuery(compute.MGetCommand, []string{"key1", "key2"}),
+			wantKeys:     []string{"key1", "key2"},
+			wantCategory: compute.ReadCategory,
+		},
+		{
+			name:         "DEL writes all the keys",
+			query:        compute.NewQuery(compute.DelCommand, []string{"key1", "key2"}),
+			wantKeys:     []string{"key1", "key2"},
+			wantCategory: compute.WriteCategory,
+		},
+		{
+			name:         "INCRBY writes the key",
+			query:        compute.NewQuery(compute.IncrByCommand, []string{"counter", "5"}),
+			wantKeys:     []string{"counter"},
+			wantCategory: compute.WriteCategory,
+		},
+		{
+			name:         "CAS writes the key",
+			query:        compute.NewQuery(compute.CASCommand, []string{"lock", "owner_1", "owner_2"}),
+			wantKeys:     []string{"lock"},
+			wantCategory: compute.WriteCategory,
+		},
+		{
+			name:         "ACL SETUSER has no keys",
+			query:        compute.NewQuery(compute.ACLSetUserCommand, []string{"svc-a", "~svc-a:*"}),
+			wantCategory: compute.AdminCategory,
+		},
+		{
+			name:         "MULTI has no category",
+			query:        compute.NewQuery(compute.MultiCommand, nil),
+			wantCategory: compute.NoCategory,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantKeys, tt.query.Keys())
+			assert.Equal(t, tt.wantCategory, tt.query.Category())
+		})
+	}
+}
+
+func TestCommandID_String(t *testing.T) {
+	assert.Equal(t, "SET", compute.SetCommand.String())
+	assert.Equal(t, "ACL SETUSER", compute.ACLSetUserCommand.String())
+	assert.Equal(t, "DEL", compute.DelCommand.String(), "aliases do not rename the command")
+	assert.Equal(t, "UNKNOWN", compute.UnknownCommand.String())
+}
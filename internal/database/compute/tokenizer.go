@@ -0,0 +1,159 @@
+package compute
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxRawLengthDigits limits the length of the raw string, so it does not overflow.
+const maxRawLengthDigits = 10
+
+// Tokenize splits the request into the command name and its arguments.
+//
+// The tokens are separated by whitespace, a token is one of:
+//   - a bare word, taken as is;
+//   - a double-quoted string with the \n, \r, \t, \0, \\, \", \' and \xNN escapes;
+//   - a single-quoted string, taken as is except the \' and \\ escapes;
+//   - a raw string, {n} followed by exactly n bytes of any kind.
+//
+// A quoted or raw string must be followed by whitespace or the end of the request.
+func Tokenize(req string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(req); {
+		if isSpace(req[i]) {
+			i++
+			continue
+		}
+
+		var (
+			token string
+			end   int
+			err   error
+		)
+		switch req[i] {
+		case '"':
+			token, end, err = doubleQuoted(req, i)
+		case '\'':
+			token, end, err = singleQuoted(req, i)
+		default:
+			token, end, err = word(req, i)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if end < len(req) && !isSpace(req[end]) {
+			return nil, &SyntaxError{Offset: end, Err: ErrMissingSeparator}
+		}
+
+		tokens = append(tokens, token)
+		i = end
+	}
+	return tokens, nil
+}
+
+// word returns the raw string or the bare word that starts at i and the offset after it.
+func word(req string, i int) (string, int, error) {
+	if n, start, found := rawPrefix(req, i); found {
+		if n < 0 || n > len(req)-start {
+			return "", 0, &SyntaxError{Offset: i, Err: ErrInvalidRawLength}
+		}
+		return req[start : start+n], start + n, nil
+	}
+
+	end := i
+	for end < len(req) && !isSpace(req[end]) {
+		end++
+	}
+	return req[i:end], end, nil
+}
+
+// doubleQuoted returns the unescaped string that starts at the quote at i and the offset after it.
+func doubleQuoted(req string, i int) (string, int, error) {
+	var b strings.Builder
+	for j := i + 1; j < len(req); j++ {
+		switch c := req[j]; c {
+		case '"':
+			return b.String(), j + 1, nil
+		case '\\':
+			if j+1 == len(req) {
+				return "", 0, &SyntaxError{Offset: i, Err: ErrUnterminatedQuote}
+			}
+
+			switch esc := req[j+1]; esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '0':
+				b.WriteByte(0)
+			case '\\', '"', '\'':
+				b.WriteByte(esc)
+			case 'x':
+				if j+3 >= len(req) {
+					return "", 0, &SyntaxError{Offset: j, Err: ErrInvalidEscape}
+				}
+				n, err := strconv.ParseUint(req[j+2:j+4], 16, 8)
+				if err != nil {
+					return "", 0, &SyntaxError{Offset: j, Err: ErrInvalidEscape}
+				}
+				b.WriteByte(byte(n))
+				j += 2
+			default:
+				return "", 0, &SyntaxError{Offset: j, Err: ErrInvalidEscape}
+			}
+			j++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, &SyntaxError{Offset: i, Err: ErrUnterminatedQuote}
+}
+
+// singleQuoted returns the string that starts at the quote at i and the offset after it.
+func singleQuoted(req string, i int) (string, int, error) {
+	var b strings.Builder
+	for j := i + 1; j < len(req); j++ {
+		switch c := req[j]; {
+		case c == '\'':
+			return b.String(), j + 1, nil
+		case c == '\\' && j+1 < len(req) && (req[j+1] == '\'' || req[j+1] == '\\'):
+			b.WriteByte(req[j+1])
+			j++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, &SyntaxError{Offset: i, Err: ErrUnterminatedQuote}
+}
+
+// rawPrefix returns the length of the raw string that starts at the {n} prefix at i
+// and the offset after the prefix, the length is -1 if it is too long.
+func rawPrefix(req string, i int) (n, start int, found bool) {
+	if req[i] != '{' {
+		return 0, 0, false
+	}
+
+	j := i + 1
+	for j < len(req) && req[j] >= '0' && req[j] <= '9' {
+		j++
+	}
+	if j == i+1 || j == len(req) || req[j] != '}' {
+		return 0, 0, false
+	}
+	if j-i-1 > maxRawLengthDigits {
+		return -1, j + 1, true
+	}
+
+	n, _ = strconv.Atoi(req[i+1 : j])
+	return n, j + 1, true
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package compute_test
+
+import (
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		req        string
+		want       []string
+		wantErr    error
+		wantOffset int
+	}{
+		{
+			name: "Empty request",
+			req:  " \t\n",
+		},
+		{
+			name: "Bare words",
+			req:  "  SET key\tvalue\n",
+			want: []string{"SET", "key", "value"},
+		},
+		{
+			name: "Quotes inside bare word are literal",
+			req:  `SET key it's\n`,
+			want: []string{"SET", "key", `it's\n`},
+		},
+		{
+			name: "Double-quoted string with spaces",
+			req:  `SET key "hello  world"`,
+			want: []string{"SET", "key", "hello  world"},
+		},
+		{
+			name: "Double-quoted string escapes",
+			req:  `SET key "a\n\r\t\0\\\"\'\x41\xff"`,
+			want: []string{"SET", "key", "a\n\r\t\x00\\\"'A\xff"},
+		},
+		{
+			name: "Double-quoted JSON",
+			req:  `SET key "{\"name\": \"fast key\"}"`,
+			want: []string{"SET", "key", `{"name": "fast key"}`},
+		},
+		{
+			name: "Empty quoted strings",
+			req:  `SET "" ''`,
+			want: []string{"SET", "", ""},
+		},
+		{
+			name: "Single-quoted string keeps other backslashes",
+			req:  `SET key 'it\'s \\ a\n'`,
+			want: []string{"SET", "key", `it's \ a\n`},
+		},
+		{
+			name: "Raw string",
+			req:  "SET key {12}hello\x00\"world",
+			want: []string{"SET", "key", "hello\x00\"world"},
+		},
+		{
+			name: "Empty raw string",
+			req:  "SET key {0} EX 10",
+			want: []string{"SET", "key", "", "EX", "10"},
+		},
+		{
+			name: "Braces without length are bare word",
+			req:  `SET key {a}b {}`,
+			want: []string{"SET", "key", "{a}b", "{}"},
+		},
+		{
+			name:       "Unterminated double quote",
+			req:        `SET key "value`,
+			wantErr:    compute.ErrUnterminatedQuote,
+			wantOffset: 8,
+		},
+		{
+			name:       "Unterminated double quote after escape",
+			req:        `SET key "value\`,
+			wantErr:    compute.ErrUnterminatedQuote,
+			wantOffset: 8,
+		},
+		{
+			name:       "Unterminated single quote",
+			req:        `SET key 'value\'`,
+			wantErr:    compute.ErrUnterminatedQuote,
+			wantOffset: 8,
+		},
+		{
+			name:       "Unknown escape",
+			req:        `SET key "va\lue"`,
+			wantErr:    compute.ErrInvalidEscape,
+			wantOffset: 11,
+		},
+		{
+			name:       "Invalid hex escape",
+			req:        `SET key "\xZZ"`,
+			wantErr:    compute.ErrInvalidEscape,
+			wantOffset: 9,
+		},
+		{
+			name:       "Truncated hex escape",
+			req:        `SET key "\x4"`,
+			wantErr:    compute.ErrInvalidEscape,
+			wantOffset: 9,
+		},
+		{
+			name:       "Closing quote followed by word",
+			req:        `SET key "value"s`,
+			wantErr:    compute.ErrMissingSeparator,
+			wantOffset: 15,
+		},
+		{
+			name:       "Raw string longer than request",
+			req:        "SET key {10}value",
+			wantErr:    compute.ErrInvalidRawLength,
+			wantOffset: 8,
+		},
+		{
+			name:       "Raw string length overflow",
+			req:        "SET key {99999999999999999999}value",
+			wantErr:    compute.ErrInvalidRawLength,
+			wantOffset: 8,
+		},
+		{
+			name:       "Raw string followed by word",
+			req:        "SET key {3}values",
+			wantErr:    compute.ErrMissingSeparator,
+			wantOffset: 14,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := compute.Tokenize(tt.req)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				var syntaxErr *compute.SyntaxError
+				require.ErrorAs(t, err, &syntaxErr)
+				assert.Equal(t, tt.wantOffset, syntaxErr.Offset)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	t.Parallel()
+
+	err := &compute.SyntaxError{Offset: 8, Err: compute.ErrUnterminatedQuote}
+	assert.Equal(t, "unterminated quote at byte 8", err.Error())
+}
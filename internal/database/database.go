@@ -1,9 +1,21 @@
 package database
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/alukart32/go-fast-key/internal/auth"
 	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
+	"github.com/alukart32/go-fast-key/internal/pkg/concurrency"
+	"github.com/alukart32/go-fast-key/internal/session"
 	"go.uber.org/zap"
 )
 
@@ -19,18 +31,92 @@ type Engine interface {
 	Set(k, v string) error
 	Get(k string) (string, error)
 	Del(k string) error
+	MSet(entries []engine.Entry) error
+	MGet(keys []string) ([]string, error)
+	MDel(keys []string) error
+	IncrBy(k string, delta int64) (engine.Entry, error)
+	IncrByFloat(k string, delta float64) (engine.Entry, error)
+	SetWithDeadline(k, v string, deadline time.Time) error
+	CompareAndSet(k, v string, deadline time.Time, check func(current string) bool) (string, bool, error)
+	Expire(k string, deadline time.Time) (bool, error)
+	Persist(k string) (bool, error)
+	Deadline(k string) (time.Time, error)
+	DeleteExpired(limit int) int
+	Dump() []engine.Entry
+	Restore(entries []engine.Entry) error
+	Evicted() []engine.Entry
+	Stats() engine.Stats
+}
+
+// WAL describes the write-ahead log of the database mutations.
+type WAL interface {
+	Recover(lsn uint64, apply func(wal.Record) error) error
+	Append(records ...wal.Record) concurrency.Future[error]
+	LSN() uint64
+	Compact(lsn uint64) error
+	ApplySegment(firstLSN uint64, data []byte, apply func(wal.Record) error) error
+	Reset(lsn uint64) error
+	Stats() (wal.Stats, error)
+}
+
+// Authenticator describes the check of the client credentials and permissions.
+type Authenticator interface {
+	Authenticate(remoteAddr, user, secret string) error
+	Authorize(user string, category compute.Category, keys []string) error
+	SetUser(name string, rules []string) error
+	GetUser(name string) (string, error)
+	ListUsers() []string
+}
+
+// Snapshots describes the storage of the engine snapshots.
+type Snapshots interface {
+	Save(s snapshot.Snapshot) error
+	Latest() (snapshot.Snapshot, error)
+	CompactionLSN() uint64
+}
+
+// Metrics describes the observer of the executed commands.
+type Metrics interface {
+	ObserveCommand(command compute.CommandID, duration time.Duration, err error)
 }
 
 // Database defines the key-value database.
 type Database struct {
 	parser RequestParser
 	e      Engine
+	w      WAL
+	s      Snapshots
+	auth   Authenticator
+	m      Metrics
+
+	// info, listeners and replication are reported by INFO.
+	info        ServerInfo
+	listeners   []Listener
+	replication Replication
+	// processed counts the executed commands.
+	processed atomic.Uint64
+
+	// readOnly rejects the client mutations of the replica.
+	readOnly bool
+
+	// txMtx is held exclusively by the transactions, so the other queries
+	// never see their partial changes.
+	txMtx sync.RWMutex
+	// mtx keeps the order of the logged mutations consistent with the engine.
+	mtx sync.Mutex
+	// logMtx is shared by the mutations until they are logged or rolled back,
+	// so the snapshot holding it exclusively sees only the logged ones.
+	logMtx sync.RWMutex
+	// watches invalidates the transactions watching the modified keys.
+	watches *watchRegistry
+	// snapshotMtx allows only one snapshot at a time.
+	snapshotMtx sync.Mutex
 
 	l *zap.Logger
 }
 
 // NewDatabase creates a new Database.
-func NewDatabase(parser RequestParser, engine Engine, logger *zap.Logger) (*Database, error) {
+func NewDatabase(parser RequestParser, engine Engine, logger *zap.Logger, options ...Option) (*Database, error) {
 	if parser == nil {
 		return nil, fmt.Errorf("parser is nil")
 	}
@@ -41,56 +127,935 @@ func NewDatabase(parser RequestParser, engine Engine, logger *zap.Logger) (*Data
 		return nil, fmt.Errorf("logger is nil")
 	}
 
-	return &Database{
-		parser: parser,
-		e:      engine,
-		l:      logger,
-	}, nil
+	db := &Database{
+		parser:  parser,
+		e:       engine,
+		info:    ServerInfo{StartedAt: time.Now()},
+		watches: newWatchRegistry(),
+		l:       logger,
+	}
+
+	for _, option := range options {
+		option(db)
+	}
+
+	return db, nil
+}
+
+// Recover restores the engine state from the latest snapshot and
+// the write-ahead log records logged after it.
+func (db *Database) Recover() error {
+	var lsn uint64
+	if db.s != nil {
+		snap, err := db.s.Latest()
+		switch {
+		case errors.Is(err, snapshot.ErrNotFound):
+		case err != nil:
+			return fmt.Errorf("load snapshot: %w", err)
+		default:
+			if err := db.e.Restore(snap.Entries); err != nil {
+				return fmt.Errorf("restore snapshot: %w", err)
+			}
+			lsn = snap.LSN
+			db.l.Info("snapshot is restored", zap.Uint64("lsn", lsn), zap.Int("entries", len(snap.Entries)))
+		}
+	}
+
+	if db.w == nil {
+		return nil
+	}
+
+	// The replayed records are logged already, so are the evictions they make.
+	defer db.e.Evicted()
+	return db.w.Recover(lsn, db.apply)
+}
+
+// Snapshot saves the current engine state and compacts the write-ahead log covered by the snapshots.
+func (db *Database) Snapshot() error {
+	if db.s == nil {
+		return ErrSnapshotsDisabled
+	}
+
+	db.snapshotMtx.Lock()
+	defer db.snapshotMtx.Unlock()
+
+	// The pending mutations are logged or rolled back before the state is copied and the new ones
+	// wait for it, so the state holds exactly the records up to the LSN of the last logged one.
+	db.logMtx.Lock()
+	db.mtx.Lock()
+	var snap snapshot.Snapshot
+	if db.w != nil {
+		snap.LSN = db.w.LSN()
+	}
+	snap.Entries = db.e.Dump()
+	db.mtx.Unlock()
+	db.logMtx.Unlock()
+
+	if err := db.s.Save(snap); err != nil {
+		db.l.Error("fail to save snapshot", zap.Error(err))
+		return ErrSnapshotFailed
+	}
+
+	if db.w != nil {
+		if lsn := db.s.CompactionLSN(); lsn != 0 {
+			if err := db.w.Compact(lsn); err != nil {
+				db.l.Warn("fail to compact wal", zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// LSN returns the LSN of the last logged mutation.
+func (db *Database) LSN() uint64 {
+	if db.w == nil {
+		return 0
+	}
+	return db.w.LSN()
+}
+
+// ApplySegment stores the log frames replicated from the master segment and applies their new records.
+func (db *Database) ApplySegment(firstLSN uint64, data []byte) error {
+	if db.w == nil {
+		return ErrWALDisabled
+	}
+
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	return db.w.ApplySegment(firstLSN, data, func(r wal.Record) error {
+		err := db.apply(r)
+		// The replica evicts the keys on its own if its memory is lower than the master one.
+		db.evicted()
+		if err != nil {
+			return err
+		}
+		db.watches.Touch(recordKeys(r)...)
+		return nil
+	})
 }
 
-// HandleRequest processes the incoming request and returns the query result.
+// ApplySnapshot replaces the replica state with the master snapshot, the replicated log
+// continues after the snapshot LSN.
 //
-// Errors occur due to an incorrect query or inconsistent data.
-func (db *Database) HandleRequest(request string) string {
-	db.l.Debug("handle the request", zap.String("request", request))
+// The snapshot is saved before the log is reset, so a restarted replica recovers from it.
+func (db *Database) ApplySnapshot(snap snapshot.Snapshot) error {
+	if db.w == nil {
+		return ErrWALDisabled
+	}
+	if db.s == nil {
+		return ErrSnapshotsDisabled
+	}
+
+	db.snapshotMtx.Lock()
+	defer db.snapshotMtx.Unlock()
+
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	if err := db.s.Save(snap); err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	if err := db.e.Restore(snap.Entries); err != nil {
+		return fmt.Errorf("restore snapshot: %w", err)
+	}
+	db.watches.TouchAll()
+
+	return db.w.Reset(snap.LSN)
+}
 
+// HandleRequest processes the incoming request of the session and returns the query response.
+//
+// Errors occur due to an incorrect query or inconsistent data, they are the error responses.
+func (db *Database) HandleRequest(s *session.Session, request string) Response {
 	query, err := db.parser.Parse(request)
 	if err != nil {
-		return err.Error()
+		s.Transaction().Fail()
+		return ErrorResponse(err)
+	}
+
+	// The credentials are never logged.
+	if id := query.CommandID(); id != compute.AuthCommand && id != compute.ACLSetUserCommand {
+		db.l.Debug("handle the request", zap.Uint64("session", s.ID()), zap.String("request", request))
+	}
+
+	return db.HandleQuery(s, query)
+}
+
+// HandleQuery executes the parsed query of the session and returns its response.
+//
+// The queries that follow MULTI are queued until EXEC. If the authentication is enabled,
+// the queries of the session are refused until AUTH succeeds, then each query is checked
+// against the ACL rules of the user.
+//
+// EXEC executes the queued queries atomically and returns their responses in order.
+// The transaction is discarded if a query failed to be queued or a watched key is changed.
+// The failed queries do not roll back the others.
+func (db *Database) HandleQuery(s *session.Session, query compute.Query) Response {
+	start := time.Now()
+	resp, err := db.handleQuery(s, query)
+	db.observe(query.CommandID(), start, err)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+	return resp
+}
+
+func (db *Database) handleQuery(s *session.Session, query compute.Query) (Response, error) {
+	if query.CommandID() == compute.AuthCommand {
+		return ok(db.authenticate(s, query.Arguments()))
+	}
+	if db.auth != nil {
+		if !s.Authenticated() {
+			s.Transaction().Fail()
+			return Response{}, ErrNoAuth
+		}
+		if err := db.auth.Authorize(s.User(), query.Category(), query.Keys()); err != nil {
+			s.Transaction().Fail()
+			return Response{}, err
+		}
 	}
 
-	var result string
+	tx := s.Transaction()
+	switch query.CommandID() {
+	case compute.MultiCommand:
+		return ok(db.multi(tx))
+	case compute.ExecCommand:
+		return db.exec(tx)
+	case compute.DiscardCommand:
+		return ok(db.discard(tx))
+	case compute.WatchCommand:
+		return ok(db.watch(tx, query.Arguments()))
+	}
+
+	if tx.Active() {
+		if query.CommandID() == compute.SnapshotCommand {
+			tx.Fail()
+			return Response{}, ErrNotInTransaction
+		}
+		tx.Queue(query)
+		return Queued(), nil
+	}
+
+	db.txMtx.RLock()
+	defer db.txMtx.RUnlock()
+
+	return db.execute(query, db.mutate)
+}
+
+// exec executes the queries queued by the transaction atomically and returns their responses.
+func (db *Database) exec(tx *session.Transaction) (Response, error) {
+	if !tx.Active() {
+		return Response{}, ErrExecWithoutMulti
+	}
+
+	db.txMtx.Lock()
+	defer db.txMtx.Unlock()
+
+	queries, failed, invalidated := tx.Queries(), tx.Failed(), tx.Invalidated()
+	db.watches.Unwatch(tx)
+	tx.Reset()
+
+	if failed {
+		return Response{}, ErrExecAborted
+	}
+	if invalidated {
+		return Response{}, ErrWatchedKeyChanged
+	}
+
+	// The records are logged together once all the queries are applied,
+	// all the mutations are rolled back if they fail to be logged.
+	var (
+		records []wal.Record
+		changes []change
+	)
+	mutate := func(r *wal.Record, apply func() error) error {
+		if db.readOnly {
+			return ErrReadOnly
+		}
+
+		var before []keyState
+		if db.w != nil {
+			before = db.states(recordKeys(*r))
+		}
+		err := apply()
+		if evicted, evictions, found := db.evicted(); found {
+			records = append(records, evicted)
+			changes = append(changes, evictions...)
+		}
+		if err != nil {
+			return err
+		}
+		if db.w != nil {
+			changes = append(changes, db.changes(before)...)
+		}
+		records = append(records, *r)
+		db.watches.Touch(recordKeys(*r)...)
+		return nil
+	}
+
+	db.logMtx.RLock()
+	defer db.logMtx.RUnlock()
+
+	db.mtx.Lock()
+	responses := make([]Response, 0, len(queries))
+	for _, q := range queries {
+		resp, err := db.execute(q, mutate)
+		if err != nil {
+			resp = ErrorResponse(err)
+		}
+		responses = append(responses, resp)
+	}
+
+	if db.w == nil || len(records) == 0 {
+		db.mtx.Unlock()
+		return Array(responses...), nil
+	}
+	logged := db.w.Append(records...)
+	db.mtx.Unlock()
+
+	if err := logged.Get(); err != nil {
+		db.l.Error("fail to log the transaction", zap.Error(err))
+		db.rollback(changes)
+		return Response{}, ErrNotLogged
+	}
+	return Array(responses...), nil
+}
+
+func (db *Database) authenticate(s *session.Session, args []string) error {
+	if db.auth == nil {
+		return ErrAuthDisabled
+	}
+
+	// AUTH with the password only authenticates the default user.
+	name, secret := auth.DefaultUser, args[0]
+	if len(args) == 2 {
+		name, secret = args[0], args[1]
+	}
+	if err := db.auth.Authenticate(s.RemoteAddr(), name, secret); err != nil {
+		return err
+	}
+
+	s.Authenticate(name)
+	return nil
+}
+
+func (db *Database) multi(tx *session.Transaction) error {
+	if tx.Active() {
+		return ErrNestedMulti
+	}
+	tx.Begin()
+	return nil
+}
+
+func (db *Database) discard(tx *session.Transaction) error {
+	if !tx.Active() {
+		return ErrDiscardWithoutMulti
+	}
+	db.watches.Unwatch(tx)
+	tx.Reset()
+	return nil
+}
+
+func (db *Database) watch(tx *session.Transaction, keys []string) error {
+	if tx.Active() {
+		tx.Fail()
+		return ErrWatchInMulti
+	}
+	db.watches.Watch(tx, keys...)
+	return nil
+}
+
+// errNotChanged is returned by the conditional mutation that leaves the key as is,
+// so the mutation is not logged.
+var errNotChanged = errors.New("not changed")
+
+// mutateFunc applies the mutation to the engine and logs its record,
+// apply may complete the record with the result of the mutation.
+type mutateFunc func(r *wal.Record, apply func() error) error
+
+// execute executes the query, the mutations are applied by mutate.
+func (db *Database) execute(query compute.Query, mutate mutateFunc) (Response, error) {
 	switch query.CommandID() {
 	case compute.SetCommand:
-		err = db.doSet(query)
+		return db.doSet(query, mutate)
 	case compute.GetCommand:
-		result, err = db.doGet(query)
+		return db.doGet(query)
 	case compute.DelCommand:
-		err = db.doDel(query)
+		return ok(db.doDel(query, mutate))
+	case compute.MSetCommand:
+		return ok(db.doMSet(query, mutate))
+	case compute.MGetCommand:
+		return db.doMGet(query)
+	case compute.IncrCommand, compute.DecrCommand, compute.IncrByCommand:
+		return db.doIncrBy(query, mutate)
+	case compute.IncrByFloatCommand:
+		return db.doIncrByFloat(query, mutate)
+	case compute.CASCommand:
+		return db.doCAS(query, mutate)
+	case compute.SnapshotCommand:
+		return ok(db.Snapshot())
+	case compute.ExpireCommand:
+		return db.doExpire(query, mutate)
+	case compute.TTLCommand:
+		return db.doTTL(query)
+	case compute.PersistCommand:
+		return db.doPersist(query, mutate)
+	case compute.ACLSetUserCommand:
+		return ok(db.doACLSetUser(query))
+	case compute.ACLGetUserCommand:
+		return db.doACLGetUser(query)
+	case compute.ACLListCommand:
+		return db.doACLList()
+	case compute.InfoCommand:
+		return db.doInfo(query)
+	case compute.PingCommand:
+		return doPing(query), nil
+	}
+	return Response{}, compute.ErrUnknownCommand
+}
+
+// doPing returns PONG, or the argument if it is given.
+func doPing(q compute.Query) Response {
+	if args := q.Arguments(); len(args) != 0 {
+		return Value(args[0])
 	}
+	return Value("PONG")
+}
+
+// doSet sets the value of the key. With the NX or XX flag, the value is set only if the key
+// does not exist or exists, the response is nil if it is not set. With the GET flag,
+// the response is the value the key had, nil if it did not exist.
+func (db *Database) doSet(q compute.Query, mutate mutateFunc) (Response, error) {
+	args := q.Arguments()
 
+	var deadline time.Time
+	r := wal.Record{CommandID: compute.SetCommand, Arguments: args}
+	if ttl, found := q.Option(compute.ExpireOption); found {
+		seconds, err := strconv.ParseInt(ttl[0], 10, 64)
+		if err != nil || seconds <= 0 {
+			return Response{}, ErrInvalidExpireTime
+		}
+		if deadline, err = expireDeadline(time.Now(), seconds); err != nil {
+			return Response{}, err
+		}
+		r.Arguments = []string{args[0], args[1], formatDeadline(deadline)}
+	}
+
+	_, nx := q.Option(compute.NotExistsOption)
+	_, xx := q.Option(compute.ExistsOption)
+	_, get := q.Option(compute.GetOption)
+	if !nx && !xx && !get {
+		return ok(mutate(&r, func() error {
+			if deadline.IsZero() {
+				return db.e.Set(args[0], args[1])
+			}
+			return db.e.SetWithDeadline(args[0], args[1], deadline)
+		}))
+	}
+
+	check := func(string) bool { return true }
+	switch {
+	case nx:
+		check = func(current string) bool { return current == "" }
+	case xx:
+		check = func(current string) bool { return current != "" }
+	}
+
+	current, set, err := db.setIf(&r, deadline, check, mutate)
+	switch {
+	case err != nil:
+		return Response{}, err
+	case get && current == "":
+		return Nil(), nil
+	case get:
+		return Value(current), nil
+	case !set:
+		return Nil(), nil
+	}
+	return OK(), nil
+}
+
+// doCAS sets the new value of the key if its value is the expected one,
+// it returns 1 if the value is set and 0 otherwise. The new value never expires.
+func (db *Database) doCAS(q compute.Query, mutate mutateFunc) (Response, error) {
+	args := q.Arguments()
+	if len(args[1]) == 0 {
+		return Response{}, engine.ErrInvalidEntityData
+	}
+
+	r := wal.Record{CommandID: compute.SetCommand, Arguments: []string{args[0], args[2]}}
+	_, set, err := db.setIf(&r, time.Time{}, func(current string) bool { return current == args[1] }, mutate)
 	if err != nil {
-		result = err.Error()
+		return Response{}, err
 	}
-	if len(result) == 0 {
-		result = "ok"
+	return boolInteger(set), nil
+}
+
+// setIf sets the value of the SET record if check accepts the current value of the key,
+// the record is logged only if the value is set.
+//
+// It returns the current value, empty if the key does not exist.
+func (db *Database) setIf(r *wal.Record, deadline time.Time, check func(current string) bool, mutate mutateFunc) (string, bool, error) {
+	k, v := r.Arguments[0], r.Arguments[1]
+
+	var current string
+	err := mutate(r, func() error {
+		var (
+			set bool
+			err error
+		)
+		current, set, err = db.e.CompareAndSet(k, v, deadline, check)
+		if err == nil && !set {
+			return errNotChanged
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, errNotChanged):
+		return current, false, nil
+	case err != nil:
+		return "", false, err
+	}
+	return current, true, nil
+}
+
+// doGet returns the value of the key, nil if the key does not exist.
+func (db *Database) doGet(q compute.Query) (Response, error) {
+	val, err := db.e.Get(q.Arguments()[0])
+	switch {
+	case errors.Is(err, engine.ErrNotFound):
+		return Nil(), nil
+	case err != nil:
+		return Response{}, err
 	}
+	return Value(val), nil
+}
 
-	return result
+func (db *Database) doDel(q compute.Query, mutate mutateFunc) error {
+	args := q.Arguments()
+	return mutate(&wal.Record{CommandID: compute.DelCommand, Arguments: args}, func() error {
+		return db.del(args)
+	})
 }
 
-func (db *Database) doSet(q compute.Query) error {
+// doMSet sets all the key-value pairs at once, they are logged as one record.
+func (db *Database) doMSet(q compute.Query, mutate mutateFunc) error {
 	args := q.Arguments()
-	return db.e.Set(args[0], args[1])
+	return mutate(&wal.Record{CommandID: compute.MSetCommand, Arguments: args}, func() error {
+		return db.e.MSet(pairs(args))
+	})
+}
+
+// doMGet returns the values of the keys, nil for a missing key.
+func (db *Database) doMGet(q compute.Query) (Response, error) {
+	values, err := db.e.MGet(q.Arguments())
+	if err != nil {
+		return Response{}, err
+	}
+
+	elements := make([]Response, 0, len(values))
+	for _, v := range values {
+		if v == "" {
+			elements = append(elements, Nil())
+		} else {
+			elements = append(elements, Value(v))
+		}
+	}
+	return Array(elements...), nil
 }
 
-func (db *Database) doGet(q compute.Query) (string, error) {
+// doIncrBy adds the delta of INCR, DECR or INCRBY to the integer value of the key
+// and returns the new value.
+func (db *Database) doIncrBy(q compute.Query, mutate mutateFunc) (Response, error) {
 	args := q.Arguments()
-	val, err := db.e.Get(args[0])
-	return val, err
+
+	delta := int64(1)
+	switch q.CommandID() {
+	case compute.DecrCommand:
+		delta = -1
+	case compute.IncrByCommand:
+		var err error
+		if delta, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return Response{}, engine.ErrNotInteger
+		}
+	}
+
+	entry, err := db.update(args[0], mutate, func() (engine.Entry, error) {
+		return db.e.IncrBy(args[0], delta)
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	n, err := strconv.ParseInt(entry.Value, 10, 64)
+	if err != nil {
+		return Response{}, engine.ErrNotInteger
+	}
+	return Integer(n), nil
 }
 
-func (db *Database) doDel(q compute.Query) error {
+// doIncrByFloat adds the delta to the float value of the key and returns the new value.
+func (db *Database) doIncrByFloat(q compute.Query, mutate mutateFunc) (Response, error) {
 	args := q.Arguments()
-	return db.e.Del(args[0])
+
+	delta, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return Response{}, engine.ErrNotFloat
+	}
+	entry, err := db.update(args[0], mutate, func() (engine.Entry, error) {
+		return db.e.IncrByFloat(args[0], delta)
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return Value(entry.Value), nil
+}
+
+// update applies the mutation that computes the value of the key k from the current one.
+// The result is logged as SET with the deadline of the key, so the replay does not depend
+// on the value the mutation read.
+func (db *Database) update(k string, mutate mutateFunc, apply func() (engine.Entry, error)) (engine.Entry, error) {
+	var entry engine.Entry
+	r := wal.Record{CommandID: compute.SetCommand, Arguments: []string{k}}
+	err := mutate(&r, func() (err error) {
+		if entry, err = apply(); err != nil {
+			return err
+		}
+
+		r.Arguments = []string{entry.Key, entry.Value}
+		if entry.ExpiresAt != 0 {
+			r.Arguments = append(r.Arguments, formatDeadline(time.Unix(0, entry.ExpiresAt)))
+		}
+		return nil
+	})
+	return entry, err
+}
+
+func (db *Database) del(keys []string) error {
+	if len(keys) == 1 {
+		return db.e.Del(keys[0])
+	}
+	return db.e.MDel(keys)
+}
+
+func (db *Database) doExpire(q compute.Query, mutate mutateFunc) (Response, error) {
+	args := q.Arguments()
+
+	seconds, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return Response{}, ErrInvalidExpireTime
+	}
+	deadline, err := expireDeadline(time.Now(), seconds)
+	if err != nil {
+		return Response{}, err
+	}
+
+	r := wal.Record{
+		CommandID: compute.ExpireCommand,
+		Arguments: []string{args[0], formatDeadline(deadline)},
+	}
+	err = mutate(&r, func() error {
+		exists, err := db.e.Expire(args[0], deadline)
+		if err == nil && !exists {
+			return errNotChanged
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, errNotChanged):
+		return boolInteger(false), nil
+	case err != nil:
+		return Response{}, err
+	}
+	return boolInteger(true), nil
+}
+
+// expireDeadline returns the deadline the seconds after now.
+//
+// ErrInvalidExpireTime is returned if the deadline does not fit the Unix time in nanoseconds,
+// the seconds would overflow the duration and the logged deadline.
+func expireDeadline(now time.Time, seconds int64) (time.Time, error) {
+	const minSeconds = -math.MaxInt64 / int64(time.Second)
+	maxSeconds := (math.MaxInt64 - now.UnixNano()) / int64(time.Second)
+	if seconds < minSeconds || seconds > maxSeconds {
+		return time.Time{}, ErrInvalidExpireTime
+	}
+	return now.Add(time.Duration(seconds) * time.Second), nil
+}
+
+// doTTL returns the remaining time to live of the key in seconds,
+// -1 if the key never expires and -2 if the key does not exist.
+func (db *Database) doTTL(q compute.Query) (Response, error) {
+	args := q.Arguments()
+
+	deadline, err := db.e.Deadline(args[0])
+	switch {
+	case errors.Is(err, engine.ErrNotFound):
+		return Integer(-2), nil
+	case err != nil:
+		return Response{}, err
+	case deadline.IsZero():
+		return Integer(-1), nil
+	}
+
+	ttl := max(time.Until(deadline).Round(time.Second), 0)
+	return Integer(int64(ttl / time.Second)), nil
+}
+
+func (db *Database) doPersist(q compute.Query, mutate mutateFunc) (Response, error) {
+	args := q.Arguments()
+
+	err := mutate(&wal.Record{CommandID: compute.PersistCommand, Arguments: args}, func() error {
+		persisted, err := db.e.Persist(args[0])
+		if err == nil && !persisted {
+			return errNotChanged
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, errNotChanged):
+		return boolInteger(false), nil
+	case err != nil:
+		return Response{}, err
+	}
+	return boolInteger(true), nil
+}
+
+// mutate applies the mutation to the engine and waits until its record is logged.
+//
+// The mutation is rolled back if its record fails to be logged.
+func (db *Database) mutate(r *wal.Record, apply func() error) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if db.w == nil {
+		err := apply()
+		db.evicted()
+		if err != nil {
+			return err
+		}
+		db.watches.Touch(recordKeys(*r)...)
+		return nil
+	}
+
+	db.logMtx.RLock()
+	defer db.logMtx.RUnlock()
+
+	db.mtx.Lock()
+	before := db.states(recordKeys(*r))
+	// The failed mutation may have evicted the keys before it ran out of memory,
+	// the evictions are logged anyway.
+	applyErr := apply()
+
+	var records []wal.Record
+	evicted, changes, found := db.evicted()
+	if found {
+		records = append(records, evicted)
+	}
+	if applyErr == nil {
+		changes = append(changes, db.changes(before)...)
+		records = append(records, *r)
+		db.watches.Touch(recordKeys(*r)...)
+	}
+	if len(records) == 0 {
+		db.mtx.Unlock()
+		return applyErr
+	}
+	logged := db.w.Append(records...)
+	db.mtx.Unlock()
+
+	if err := logged.Get(); err != nil {
+		db.l.Error("fail to log the mutation", zap.Error(err))
+		db.rollback(changes)
+		return ErrNotLogged
+	}
+	return applyErr
+}
+
+// evicted takes the entries the engine evicted to make room for the mutation.
+//
+// It returns the record deleting them that is logged before the mutation record,
+// so the replay and the replicas evict the same keys. The changes restore
+// the evicted entries if the records fail to be logged.
+func (db *Database) evicted() (wal.Record, []change, bool) {
+	entries := db.e.Evicted()
+	if len(entries) == 0 {
+		return wal.Record{}, nil, false
+	}
+
+	r := wal.Record{CommandID: compute.DelCommand, Arguments: make([]string, 0, len(entries))}
+	changes := make([]change, 0, len(entries))
+	for _, entry := range entries {
+		r.Arguments = append(r.Arguments, entry.Key)
+		changes = append(changes, change{
+			before: keyState{key: entry.Key, entry: entry},
+			after:  keyState{key: entry.Key},
+		})
+	}
+	db.watches.Touch(r.Arguments...)
+	return r, changes, true
+}
+
+// keyState defines the state of the key, the entry is zero if the key does not exist.
+type keyState struct {
+	key   string
+	entry engine.Entry
+}
+
+// change defines the states of the key before and after the mutation.
+type change struct {
+	before, after keyState
+}
+
+// states returns the current states of the keys.
+func (db *Database) states(keys []string) []keyState {
+	states := make([]keyState, 0, len(keys))
+	for _, k := range keys {
+		states = append(states, db.state(k))
+	}
+	return states
+}
+
+func (db *Database) state(k string) keyState {
+	v, err := db.e.Get(k)
+	if err != nil {
+		return keyState{key: k}
+	}
+
+	state := keyState{key: k, entry: engine.Entry{Key: k, Value: v}}
+	if deadline, err := db.e.Deadline(k); err == nil && !deadline.IsZero() {
+		state.entry.ExpiresAt = deadline.UnixNano()
+	}
+	return state
+}
+
+// changes pairs the states of the keys before the mutation with their current states.
+func (db *Database) changes(before []keyState) []change {
+	changes := make([]change, 0, len(before))
+	for _, b := range before {
+		changes = append(changes, change{before: b, after: db.state(b.key)})
+	}
+	return changes
+}
+
+// rollback restores the states of the keys before the changes in reverse order.
+//
+// A key changed again since the mutation is left as is, so the later mutations are not lost.
+func (db *Database) rollback(changes []change) {
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		if db.state(c.before.key) != c.after {
+			continue
+		}
+		if err := db.restore(c.before); err != nil {
+			db.l.Error("fail to roll back the mutation", zap.String("key", c.before.key), zap.Error(err))
+		}
+	}
+}
+
+func (db *Database) restore(s keyState) error {
+	switch {
+	case s.entry.Key == "":
+		return db.e.Del(s.key)
+	case s.entry.ExpiresAt != 0:
+		return db.e.SetWithDeadline(s.key, s.entry.Value, time.Unix(0, s.entry.ExpiresAt))
+	}
+	return db.e.Set(s.key, s.entry.Value)
+}
+
+// apply replays the logged record on the engine.
+func (db *Database) apply(r wal.Record) error {
+	args := r.Arguments
+	switch r.CommandID {
+	case compute.SetCommand:
+		switch len(args) {
+		case 2:
+			return db.e.Set(args[0], args[1])
+		case 3:
+			deadline, err := parseDeadline(args[2])
+			if err != nil {
+				return err
+			}
+			return db.e.SetWithDeadline(args[0], args[1], deadline)
+		}
+	case compute.DelCommand:
+		if len(args) != 0 {
+			return db.del(args)
+		}
+	case compute.MSetCommand:
+		if len(args) != 0 && len(args)%2 == 0 {
+			return db.e.MSet(pairs(args))
+		}
+	case compute.ExpireCommand:
+		if len(args) == 2 {
+			deadline, err := parseDeadline(args[1])
+			if err != nil {
+				return err
+			}
+			_, err = db.e.Expire(args[0], deadline)
+			return err
+		}
+	case compute.PersistCommand:
+		if len(args) == 1 {
+			_, err := db.e.Persist(args[0])
+			return err
+		}
+	}
+	return fmt.Errorf("%w: command %d with %d args", ErrInvalidRecord, r.CommandID, len(args))
+}
+
+// recordKeys returns the keys modified by the record.
+func recordKeys(r wal.Record) []string {
+	q := compute.NewQuery(r.CommandID, r.Arguments)
+	return q.Keys()
+}
+
+// pairs returns the key-value pairs of the arguments.
+func pairs(args []string) []engine.Entry {
+	entries := make([]engine.Entry, 0, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		entries = append(entries, engine.Entry{Key: args[i], Value: args[i+1]})
+	}
+	return entries
+}
+
+// formatDeadline formats the deadline as the Unix time in nanoseconds,
+// the logged deadlines are absolute, so they are the same on replay.
+func formatDeadline(deadline time.Time) string {
+	return strconv.FormatInt(deadline.UnixNano(), 10)
+}
+
+func parseDeadline(s string) (time.Time, error) {
+	nanos, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: deadline %q", ErrInvalidRecord, s)
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// ok returns the success response of the command that has no result.
+func ok(err error) (Response, error) {
+	if err != nil {
+		return Response{}, err
+	}
+	return OK(), nil
+}
+
+func boolInteger(v bool) Response {
+	if v {
+		return Integer(1)
+	}
+	return Integer(0)
+}
+
+// observe counts the command executed since start and reports it to the metrics.
+func (db *Database) observe(command compute.CommandID, start time.Time, err error) {
+	db.processed.Add(1)
+	if db.m != nil {
+		db.m.ObserveCommand(command, time.Since(start), err)
+	}
 }
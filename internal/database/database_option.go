@@ -0,0 +1,63 @@
+package database
+
+// Option defines an optional Database setting.
+type Option func(*Database)
+
+// WithWAL sets the write-ahead log for the database mutations.
+func WithWAL(w WAL) Option {
+	return func(db *Database) {
+		db.w = w
+	}
+}
+
+// WithSnapshots sets the storage of the engine snapshots.
+func WithSnapshots(s Snapshots) Option {
+	return func(db *Database) {
+		db.s = s
+	}
+}
+
+// WithReadOnly rejects the client mutations, the state is changed by the replication only.
+func WithReadOnly() Option {
+	return func(db *Database) {
+		db.readOnly = true
+	}
+}
+
+// WithAuthenticator requires the sessions to authenticate before their queries are executed.
+func WithAuthenticator(a Authenticator) Option {
+	return func(db *Database) {
+		db.auth = a
+	}
+}
+
+// WithMetrics sets the observer of the executed commands.
+func WithMetrics(m Metrics) Option {
+	return func(db *Database) {
+		db.m = m
+	}
+}
+
+// WithServerInfo sets the server process reported by INFO, the database creation is the start time if it is not set.
+func WithServerInfo(info ServerInfo) Option {
+	return func(db *Database) {
+		if info.StartedAt.IsZero() {
+			info.StartedAt = db.info.StartedAt
+		}
+		db.info = info
+	}
+}
+
+// WithListeners sets the network listeners reported by INFO.
+func WithListeners(listeners ...Listener) Option {
+	return func(db *Database) {
+		db.listeners = listeners
+	}
+}
+
+// WithReplication sets the replication link reported by INFO.
+func WithReplication(r Replication) Option {
+	return func(db *Database) {
+		db.replication = r
+	}
+}
@@ -1,14 +1,25 @@
 package database_test
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alukart32/go-fast-key/internal/auth"
 	"github.com/alukart32/go-fast-key/internal/database"
 	"github.com/alukart32/go-fast-key/internal/database/compute"
 	"github.com/alukart32/go-fast-key/internal/database/engine"
 	database_mocks "github.com/alukart32/go-fast-key/internal/database/mocks"
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
+	"github.com/alukart32/go-fast-key/internal/pkg/concurrency"
+	"github.com/alukart32/go-fast-key/internal/session"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -19,7 +30,7 @@ func TestDatabase_HandleRequest(t *testing.T) {
 		request string
 		parser  func() database.RequestParser
 		storage func() database.Engine
-		want    string
+		want    database.Response
 	}{
 		{
 			name:    "Handle command with parser error",
@@ -32,8 +43,8 @@ func TestDatabase_HandleRequest(t *testing.T) {
 					Once()
 				return m
 			},
-			storage: func() database.Engine { return database_mocks.NewStorage(t) },
-			want:    "parser error",
+			storage: func() database.Engine { return newStorage(t) },
+			want:    database.ErrorResponse(fmt.Errorf("parser error")),
 		},
 		{
 			name:    "Valid SET query",
@@ -45,11 +56,11 @@ func TestDatabase_HandleRequest(t *testing.T) {
 				return m
 			},
 			storage: func() database.Engine {
-				m := database_mocks.NewStorage(t)
+				m := newStorage(t)
 				m.On("Set", "key", "val").Return(nil).Once()
 				return m
 			},
-			want: "",
+			want: database.OK(),
 		},
 		{
 			name:    "SET query with storage error",
@@ -61,11 +72,11 @@ func TestDatabase_HandleRequest(t *testing.T) {
 				return m
 			},
 			storage: func() database.Engine {
-				m := database_mocks.NewStorage(t)
+				m := newStorage(t)
 				m.On("Set", "key", "val").Return(fmt.Errorf("storage error")).Once()
 				return m
 			},
-			want: "storage error",
+			want: database.ErrorResponse(fmt.Errorf("storage error")),
 		},
 		{
 			name:    "Valid GET query",
@@ -78,11 +89,11 @@ func TestDatabase_HandleRequest(t *testing.T) {
 				return m
 			},
 			storage: func() database.Engine {
-				m := database_mocks.NewStorage(t)
+				m := newStorage(t)
 				m.On("Get", "key").Return("val", nil).Once()
 				return m
 			},
-			want: "val",
+			want: database.Value("val"),
 		},
 		{
 			name:    "GET query with storage error",
@@ -94,11 +105,11 @@ func TestDatabase_HandleRequest(t *testing.T) {
 				return m
 			},
 			storage: func() database.Engine {
-				m := database_mocks.NewStorage(t)
+				m := newStorage(t)
 				m.On("Get", "key").Return("", fmt.Errorf("storage error")).Once()
 				return m
 			},
-			want: "storage error",
+			want: database.ErrorResponse(fmt.Errorf("storage error")),
 		},
 		{
 			name:    "GET query with not found error",
@@ -110,11 +121,11 @@ func TestDatabase_HandleRequest(t *testing.T) {
 				return m
 			},
 			storage: func() database.Engine {
-				m := database_mocks.NewStorage(t)
+				m := newStorage(t)
 				m.On("Get", "key").Return("", engine.ErrNotFound).Once()
 				return m
 			},
-			want: engine.ErrNotFound.Error(),
+			want: database.Nil(),
 		},
 		{
 			name:    "Valid DEL query",
@@ -127,11 +138,11 @@ func TestDatabase_HandleRequest(t *testing.T) {
 				return m
 			},
 			storage: func() database.Engine {
-				m := database_mocks.NewStorage(t)
+				m := newStorage(t)
 				m.On("Del", "key").Return(nil).Once()
 				return m
 			},
-			want: "",
+			want: database.OK(),
 		},
 		{
 			name:    "DEL query with storage error",
@@ -143,11 +154,11 @@ func TestDatabase_HandleRequest(t *testing.T) {
 				return m
 			},
 			storage: func() database.Engine {
-				m := database_mocks.NewStorage(t)
+				m := newStorage(t)
 				m.On("Del", "key").Return(fmt.Errorf("storage error")).Once()
 				return m
 			},
-			want: "storage error",
+			want: database.ErrorResponse(fmt.Errorf("storage error")),
 		},
 	}
 	for _, tt := range tests {
@@ -155,12 +166,1267 @@ func TestDatabase_HandleRequest(t *testing.T) {
 			db, err := database.NewDatabase(tt.parser(), tt.storage(), zap.NewNop())
 			require.NoError(t, err)
 
-			got := db.HandleRequest(tt.request)
-			assert.True(t, got == tt.want, "HandleRequest() = %v, want %v", got, tt.want)
+			got := db.HandleRequest(session.New(""), tt.request)
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
+func TestDatabase_HandleRequestWithWAL(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		query   compute.Query
+		storage func() database.Engine
+		wal     func() database.WAL
+		want    database.Response
+	}{
+		{
+			name:    "SET query is logged",
+			request: "SET key val",
+			query:   compute.NewQuery(compute.SetCommand, []string{"key", "val"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Set", "key", "val").Return(nil).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("Append", wal.Record{
+					CommandID: compute.SetCommand,
+					Arguments: []string{"key", "val"},
+				}).Return(resolvedFuture(nil)).Once()
+				return m
+			},
+			want: database.OK(),
+		},
+		{
+			name:    "DEL query is logged",
+			request: "DEL key",
+			query:   compute.NewQuery(compute.DelCommand, []string{"key"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Del", "key").Return(nil).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("Append", wal.Record{
+					CommandID: compute.DelCommand,
+					Arguments: []string{"key"},
+				}).Return(resolvedFuture(nil)).Once()
+				return m
+			},
+			want: database.OK(),
+		},
+		{
+			name:    "MSET query is logged as one record",
+			request: "MSET key_1 val_1 key_2 val_2",
+			query:   compute.NewQuery(compute.MSetCommand, []string{"key_1", "val_1", "key_2", "val_2"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("MSet", []engine.Entry{{Key: "key_1", Value: "val_1"}, {Key: "key_2", Value: "val_2"}}).Return(nil).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("Append", wal.Record{
+					CommandID: compute.MSetCommand,
+					Arguments: []string{"key_1", "val_1", "key_2", "val_2"},
+				}).Return(resolvedFuture(nil)).Once()
+				return m
+			},
+			want: database.OK(),
+		},
+		{
+			name:    "DEL query with many keys is logged as one record",
+			request: "DEL key_1 key_2",
+			query:   compute.NewQuery(compute.DelCommand, []string{"key_1", "key_2"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("MDel", []string{"key_1", "key_2"}).Return(nil).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("Append", wal.Record{
+					CommandID: compute.DelCommand,
+					Arguments: []string{"key_1", "key_2"},
+				}).Return(resolvedFuture(nil)).Once()
+				return m
+			},
+			want: database.OK(),
+		},
+		{
+			name:    "Failed SET query is not logged",
+			request: "SET key val",
+			query:   compute.NewQuery(compute.SetCommand, []string{"key", "val"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Set", "key", "val").Return(fmt.Errorf("storage error")).Once()
+				return m
+			},
+			wal:  func() database.WAL { return database_mocks.NewWAL(t) },
+			want: database.ErrorResponse(fmt.Errorf("storage error")),
+		},
+		{
+			name:    "SET query with wal error is rolled back",
+			request: "SET key val",
+			query:   compute.NewQuery(compute.SetCommand, []string{"key", "val"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Get", "key").Return("old", nil).Once()
+				m.On("Set", "key", "val").Return(nil).Once()
+				m.On("Get", "key").Return("val", nil).Twice()
+				m.On("Deadline", "key").Return(time.Time{}, nil)
+				m.On("Set", "key", "old").Return(nil).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("Append", mock.Anything).Return(resolvedFuture(fmt.Errorf("disk error"))).Once()
+				return m
+			},
+			want: database.ErrorResponse(database.ErrNotLogged),
+		},
+		{
+			name:    "GET query is not logged",
+			request: "GET key",
+			query:   compute.NewQuery(compute.GetCommand, []string{"key"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Get", "key").Return("val", nil).Once()
+				return m
+			},
+			wal:  func() database.WAL { return database_mocks.NewWAL(t) },
+			want: database.Value("val"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := database_mocks.NewRequestParser(t)
+			parser.On("Parse", tt.request).Return(tt.query, nil).Once()
+
+			db, err := database.NewDatabase(parser, withMissingKeys(tt.storage()), zap.NewNop(), database.WithWAL(tt.wal()))
+			require.NoError(t, err)
+
+			got := db.HandleRequest(session.New(""), tt.request)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// newStorage returns the storage mock that evicts nothing.
+func newStorage(t *testing.T) *database_mocks.Storage {
+	m := database_mocks.NewStorage(t)
+	m.On("Evicted").Return(nil).Maybe()
+	return m
+}
+
+// withMissingKeys makes the storage mock report the keys as missing when the database
+// saves their states before a mutation, the expected calls registered before take precedence.
+func withMissingKeys(storage database.Engine) database.Engine {
+	if m, ok := storage.(*database_mocks.Storage); ok {
+		m.On("Get", mock.Anything).Return("", engine.ErrNotFound).Maybe()
+	}
+	return storage
+}
+
+func TestDatabase_RollbackNotLogged(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	log := database_mocks.NewWAL(t)
+	log.On("Append", mock.Anything).Return(resolvedFuture(fmt.Errorf("disk error")))
+	log.On("Append", mock.Anything, mock.Anything).Return(resolvedFuture(fmt.Errorf("disk error"))).Once()
+
+	storage := engine.NewMemEngine(0)
+	require.NoError(t, storage.Set("key", "old"))
+	require.NoError(t, storage.SetWithDeadline("expiring", "old", time.Now().Add(time.Hour)))
+
+	db, err := database.NewDatabase(parser, storage, zap.NewNop(), database.WithWAL(log))
+	require.NoError(t, err)
+
+	s := session.New("")
+	for _, request := range []string{"SET key new", "DEL key", "SET other new", "INCR counter", "PERSIST expiring", "DEL expiring"} {
+		assert.Equal(t, database.ErrorResponse(database.ErrNotLogged), db.HandleRequest(s, request))
+	}
+	for _, request := range []string{"MULTI", "SET key new", "DEL expiring"} {
+		db.HandleRequest(s, request)
+	}
+	assert.Equal(t, database.ErrorResponse(database.ErrNotLogged), db.HandleRequest(s, "EXEC"))
+
+	assert.Equal(t, database.Value("old"), db.HandleRequest(s, "GET key"))
+	assert.Equal(t, database.Nil(), db.HandleRequest(s, "GET other"))
+	assert.Equal(t, database.Value("old"), db.HandleRequest(s, "GET expiring"))
+	assert.Equal(t, database.Integer(3600), db.HandleRequest(s, "TTL expiring"))
+	assert.Equal(t, database.Nil(), db.HandleRequest(s, "GET counter"))
+}
+
+func TestDatabase_LogEvictions(t *testing.T) {
+	evicted := wal.Record{CommandID: compute.DelCommand, Arguments: []string{"key_1"}}
+	set := wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key_3", "val_3"}}
+
+	tests := []struct {
+		name      string
+		logErr    error
+		want      database.Response
+		wantState []engine.Entry
+	}{
+		{
+			name:      "Eviction is logged before mutation",
+			want:      database.OK(),
+			wantState: []engine.Entry{{Key: "key_2", Value: "val_2"}, {Key: "key_3", Value: "val_3"}},
+		},
+		{
+			name:      "Eviction is rolled back with mutation",
+			logErr:    fmt.Errorf("disk error"),
+			want:      database.ErrorResponse(database.ErrNotLogged),
+			wantState: []engine.Entry{{Key: "key_1", Value: "val_1"}, {Key: "key_2", Value: "val_2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := compute.NewParser(zap.NewNop())
+			require.NoError(t, err)
+
+			log := database_mocks.NewWAL(t)
+			log.On("Append", evicted, set).Return(resolvedFuture(tt.logErr)).Once()
+
+			// The storage holds two entries, so the least recently used one is evicted for the third.
+			storage := engine.NewMemEngine(0, engine.WithMaxMemory(2*(5+5+64)), engine.WithEvictionPolicy(engine.AllKeysLRU))
+			require.NoError(t, storage.Set("key_1", "val_1"))
+			require.NoError(t, storage.Set("key_2", "val_2"))
+
+			db, err := database.NewDatabase(parser, storage, zap.NewNop(), database.WithWAL(log))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, db.HandleRequest(session.New(""), "SET key_3 val_3"))
+			assert.ElementsMatch(t, tt.wantState, storage.Dump())
+			assert.Empty(t, storage.Evicted())
+		})
+	}
+}
+
+func TestDatabase_HandleExpirationRequest(t *testing.T) {
+	inAnHour := mock.MatchedBy(func(deadline time.Time) bool {
+		return time.Until(deadline).Round(time.Minute) == time.Hour
+	})
+	loggedInAnHour := func(commandID compute.CommandID, args ...string) interface{} {
+		return mock.MatchedBy(func(r wal.Record) bool {
+			if r.CommandID != commandID || len(r.Arguments) != len(args)+1 {
+				return false
+			}
+			nanos, err := strconv.ParseInt(r.Arguments[len(args)], 10, 64)
+			return err == nil && reflect.DeepEqual(r.Arguments[:len(args)], args) &&
+				time.Until(time.Unix(0, nanos)).Round(time.Minute) == time.Hour
+		})
+	}
+
+	tests := []struct {
+		name    string
+		request string
+		query   compute.Query
+		storage func() database.Engine
+		wal     func() database.WAL
+		want    database.Response
+	}{
+		{
+			name:    "SET EX query is logged with deadline",
+			request: "SET key val EX 3600",
+			query:   compute.NewQuery(compute.SetCommand, []string{"key", "val"}).WithOption(compute.ExpireOption, "3600"),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("SetWithDeadline", "key", "val", inAnHour).Return(nil).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("Append", loggedInAnHour(compute.SetCommand, "key", "val")).Return(resolvedFuture(nil)).Once()
+				return m
+			},
+			want: database.OK(),
+		},
+		{
+			name:    "SET EX query with invalid expire time",
+			request: "SET key val EX 0",
+			query:   compute.NewQuery(compute.SetCommand, []string{"key", "val"}).WithOption(compute.ExpireOption, "0"),
+			storage: func() database.Engine { return newStorage(t) },
+			wal:     func() database.WAL { return database_mocks.NewWAL(t) },
+			want:    database.ErrorResponse(database.ErrInvalidExpireTime),
+		},
+		{
+			name:    "SET EX query with overflowing expire time",
+			request: "SET key val EX 9223372036854775807",
+			query:   compute.NewQuery(compute.SetCommand, []string{"key", "val"}).WithOption(compute.ExpireOption, "9223372036854775807"),
+			storage: func() database.Engine { return newStorage(t) },
+			wal:     func() database.WAL { return database_mocks.NewWAL(t) },
+			want:    database.ErrorResponse(database.ErrInvalidExpireTime),
+		},
+		{
+			name:    "EXPIRE query is logged with deadline",
+			request: "EXPIRE key 3600",
+			query:   compute.NewQuery(compute.ExpireCommand, []string{"key", "3600"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Expire", "key", inAnHour).Return(true, nil).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("Append", loggedInAnHour(compute.ExpireCommand, "key")).Return(resolvedFuture(nil)).Once()
+				return m
+			},
+			want: database.Integer(1),
+		},
+		{
+			name:    "EXPIRE query with invalid expire time",
+			request: "EXPIRE key soon",
+			query:   compute.NewQuery(compute.ExpireCommand, []string{"key", "soon"}),
+			storage: func() database.Engine { return newStorage(t) },
+			wal:     func() database.WAL { return database_mocks.NewWAL(t) },
+			want:    database.ErrorResponse(database.ErrInvalidExpireTime),
+		},
+		{
+			name:    "EXPIRE query with overflowing expire time",
+			request: "EXPIRE key 9223372036",
+			query:   compute.NewQuery(compute.ExpireCommand, []string{"key", "9223372036"}),
+			storage: func() database.Engine { return newStorage(t) },
+			wal:     func() database.WAL { return database_mocks.NewWAL(t) },
+			want:    database.ErrorResponse(database.ErrInvalidExpireTime),
+		},
+		{
+			name:    "EXPIRE query with underflowing expire time",
+			request: "EXPIRE key -9223372036854775807",
+			query:   compute.NewQuery(compute.ExpireCommand, []string{"key", "-9223372036854775807"}),
+			storage: func() database.Engine { return newStorage(t) },
+			wal:     func() database.WAL { return database_mocks.NewWAL(t) },
+			want:    database.ErrorResponse(database.ErrInvalidExpireTime),
+		},
+		{
+			name:    "EXPIRE query of missing key is not logged",
+			request: "EXPIRE key 3600",
+			query:   compute.NewQuery(compute.ExpireCommand, []string{"key", "3600"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Expire", "key", inAnHour).Return(false, nil).Once()
+				return m
+			},
+			wal:  func() database.WAL { return database_mocks.NewWAL(t) },
+			want: database.Integer(0),
+		},
+		{
+			name:    "PERSIST query of key with deadline",
+			request: "PERSIST key",
+			query:   compute.NewQuery(compute.PersistCommand, []string{"key"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Persist", "key").Return(true, nil).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("Append", wal.Record{
+					CommandID: compute.PersistCommand,
+					Arguments: []string{"key"},
+				}).Return(resolvedFuture(nil)).Once()
+				return m
+			},
+			want: database.Integer(1),
+		},
+		{
+			name:    "PERSIST query of key without deadline is not logged",
+			request: "PERSIST key",
+			query:   compute.NewQuery(compute.PersistCommand, []string{"key"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Persist", "key").Return(false, nil).Once()
+				return m
+			},
+			wal:  func() database.WAL { return database_mocks.NewWAL(t) },
+			want: database.Integer(0),
+		},
+		{
+			name:    "TTL query of key with deadline",
+			request: "TTL key",
+			query:   compute.NewQuery(compute.TTLCommand, []string{"key"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Deadline", "key").Return(time.Now().Add(time.Minute), nil).Once()
+				return m
+			},
+			wal:  func() database.WAL { return database_mocks.NewWAL(t) },
+			want: database.Integer(60),
+		},
+		{
+			name:    "TTL query of key without deadline",
+			request: "TTL key",
+			query:   compute.NewQuery(compute.TTLCommand, []string{"key"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Deadline", "key").Return(time.Time{}, nil).Once()
+				return m
+			},
+			wal:  func() database.WAL { return database_mocks.NewWAL(t) },
+			want: database.Integer(-1),
+		},
+		{
+			name:    "TTL query of missing key",
+			request: "TTL key",
+			query:   compute.NewQuery(compute.TTLCommand, []string{"key"}),
+			storage: func() database.Engine {
+				m := newStorage(t)
+				m.On("Deadline", "key").Return(time.Time{}, engine.ErrNotFound).Once()
+				return m
+			},
+			wal:  func() database.WAL { return database_mocks.NewWAL(t) },
+			want: database.Integer(-2),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := database_mocks.NewRequestParser(t)
+			parser.On("Parse", tt.request).Return(tt.query, nil).Once()
+
+			db, err := database.NewDatabase(parser, withMissingKeys(tt.storage()), zap.NewNop(), database.WithWAL(tt.wal()))
+			require.NoError(t, err)
+
+			got := db.HandleRequest(session.New(""), tt.request)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDatabase_RecoverDeadlines(t *testing.T) {
+	deadline := time.Unix(0, 1700000000000000000)
+
+	storage := newStorage(t)
+	storage.On("SetWithDeadline", "key", "val", deadline).Return(nil).Once()
+	storage.On("Expire", "key", deadline).Return(true, nil).Once()
+	storage.On("Persist", "key").Return(true, nil).Once()
+
+	records := []wal.Record{
+		{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key", "val", "1700000000000000000"}},
+		{LSN: 2, CommandID: compute.ExpireCommand, Arguments: []string{"key", "1700000000000000000"}},
+		{LSN: 3, CommandID: compute.PersistCommand, Arguments: []string{"key"}},
+	}
+	log := database_mocks.NewWAL(t)
+	log.On("Recover", uint64(0), mock.Anything).Return(func(_ uint64, apply func(wal.Record) error) error {
+		for _, r := range records {
+			if err := apply(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Once()
+
+	db, err := database.NewDatabase(database_mocks.NewRequestParser(t), storage, zap.NewNop(), database.WithWAL(log))
+	require.NoError(t, err)
+	require.NoError(t, db.Recover())
+}
+
+func TestDatabase_Recover(t *testing.T) {
+	storage := newStorage(t)
+	storage.On("Set", "key_1", "val_1").Return(nil).Once()
+	storage.On("Set", "key_2", "val_2").Return(nil).Once()
+	storage.On("Del", "key_1").Return(nil).Once()
+	storage.On("MSet", []engine.Entry{{Key: "key_3", Value: "val_3"}, {Key: "key_4", Value: "val_4"}}).Return(nil).Once()
+	storage.On("MDel", []string{"key_2", "key_3"}).Return(nil).Once()
+
+	records := []wal.Record{
+		{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key_1", "val_1"}},
+		{LSN: 2, CommandID: compute.SetCommand, Arguments: []string{"key_2", "val_2"}},
+		{LSN: 3, CommandID: compute.DelCommand, Arguments: []string{"key_1"}},
+		{LSN: 4, CommandID: compute.MSetCommand, Arguments: []string{"key_3", "val_3", "key_4", "val_4"}},
+		{LSN: 5, CommandID: compute.DelCommand, Arguments: []string{"key_2", "key_3"}},
+	}
+	log := database_mocks.NewWAL(t)
+	log.On("Recover", uint64(0), mock.Anything).Return(func(_ uint64, apply func(wal.Record) error) error {
+		for _, r := range records {
+			if err := apply(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Once()
+
+	db, err := database.NewDatabase(database_mocks.NewRequestParser(t), storage, zap.NewNop(), database.WithWAL(log))
+	require.NoError(t, err)
+	require.NoError(t, db.Recover())
+}
+
+func TestDatabase_RecoverInvalidRecord(t *testing.T) {
+	log := database_mocks.NewWAL(t)
+	log.On("Recover", uint64(0), mock.Anything).Return(func(_ uint64, apply func(wal.Record) error) error {
+		return apply(wal.Record{LSN: 1, CommandID: compute.GetCommand, Arguments: []string{"key"}})
+	}).Once()
+
+	db, err := database.NewDatabase(
+		database_mocks.NewRequestParser(t), newStorage(t), zap.NewNop(), database.WithWAL(log),
+	)
+	require.NoError(t, err)
+	assert.ErrorIs(t, db.Recover(), database.ErrInvalidRecord)
+}
+
+func TestDatabase_RecoverFromSnapshot(t *testing.T) {
+	entries := []engine.Entry{{Key: "key", Value: "val"}}
+
+	snapshots := database_mocks.NewSnapshots(t)
+	snapshots.On("Latest").Return(snapshot.Snapshot{LSN: 5, Entries: entries}, nil).Once()
+
+	storage := newStorage(t)
+	storage.On("Restore", entries).Return(nil).Once()
+
+	log := database_mocks.NewWAL(t)
+	log.On("Recover", uint64(5), mock.Anything).Return(nil).Once()
+
+	db, err := database.NewDatabase(database_mocks.NewRequestParser(t), storage, zap.NewNop(),
+		database.WithWAL(log),
+		database.WithSnapshots(snapshots),
+	)
+	require.NoError(t, err)
+	require.NoError(t, db.Recover())
+}
+
+func TestDatabase_RecoverWithoutSnapshot(t *testing.T) {
+	snapshots := database_mocks.NewSnapshots(t)
+	snapshots.On("Latest").Return(snapshot.Snapshot{}, snapshot.ErrNotFound).Once()
+
+	log := database_mocks.NewWAL(t)
+	log.On("Recover", uint64(0), mock.Anything).Return(nil).Once()
+
+	db, err := database.NewDatabase(database_mocks.NewRequestParser(t), newStorage(t), zap.NewNop(),
+		database.WithWAL(log),
+		database.WithSnapshots(snapshots),
+	)
+	require.NoError(t, err)
+	require.NoError(t, db.Recover())
+}
+
+func TestDatabase_Snapshot(t *testing.T) {
+	entries := []engine.Entry{{Key: "key", Value: "val"}}
+
+	tests := []struct {
+		name      string
+		snapshots func() database.Snapshots
+		wal       func() database.WAL
+		wantErr   error
+	}{
+		{
+			name:    "Snapshots are disabled",
+			wantErr: database.ErrSnapshotsDisabled,
+		},
+		{
+			name: "Snapshot is saved and wal is compacted",
+			snapshots: func() database.Snapshots {
+				m := database_mocks.NewSnapshots(t)
+				m.On("Save", snapshot.Snapshot{LSN: 7, Entries: entries}).Return(nil).Once()
+				m.On("CompactionLSN").Return(uint64(3)).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("LSN").Return(uint64(7)).Once()
+				m.On("Compact", uint64(3)).Return(nil).Once()
+				return m
+			},
+		},
+		{
+			name: "Snapshot is saved without wal",
+			snapshots: func() database.Snapshots {
+				m := database_mocks.NewSnapshots(t)
+				m.On("Save", snapshot.Snapshot{Entries: entries}).Return(nil).Once()
+				return m
+			},
+		},
+		{
+			name: "Snapshot is not saved",
+			snapshots: func() database.Snapshots {
+				m := database_mocks.NewSnapshots(t)
+				m.On("Save", mock.Anything).Return(fmt.Errorf("disk error")).Once()
+				return m
+			},
+			wal: func() database.WAL {
+				m := database_mocks.NewWAL(t)
+				m.On("LSN").Return(uint64(7)).Once()
+				return m
+			},
+			wantErr: database.ErrSnapshotFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newStorage(t)
+
+			var options []database.Option
+			if tt.snapshots != nil {
+				storage.On("Dump").Return(entries).Once()
+				options = append(options, database.WithSnapshots(tt.snapshots()))
+			}
+			if tt.wal != nil {
+				options = append(options, database.WithWAL(tt.wal()))
+			}
+
+			db, err := database.NewDatabase(database_mocks.NewRequestParser(t), storage, zap.NewNop(), options...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantErr, db.Snapshot())
+		})
+	}
+}
+
+func TestDatabase_SnapshotWaitsForPendingMutations(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	logged := concurrency.NewPromise[error]()
+	appended := make(chan struct{})
+	log := database_mocks.NewWAL(t)
+	log.On("Append", mock.Anything).Run(func(mock.Arguments) { close(appended) }).Return(logged.Future()).Once()
+	log.On("LSN").Return(uint64(0)).Once()
+
+	snapshots := database_mocks.NewSnapshots(t)
+	snapshots.On("Save", snapshot.Snapshot{Entries: []engine.Entry{{Key: "key", Value: "old"}}}).Return(nil).Once()
+	snapshots.On("CompactionLSN").Return(uint64(0)).Once()
+
+	storage := engine.NewMemEngine(0)
+	require.NoError(t, storage.Set("key", "old"))
+
+	db, err := database.NewDatabase(parser, storage, zap.NewNop(), database.WithWAL(log), database.WithSnapshots(snapshots))
+	require.NoError(t, err)
+
+	resp := make(chan database.Response)
+	go func() { resp <- db.HandleRequest(session.New(""), "SET key new") }()
+	<-appended
+
+	saved := make(chan error)
+	go func() { saved <- db.Snapshot() }()
+	select {
+	case <-saved:
+		t.Fatal("snapshot must wait for the pending mutation")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The mutation that fails to be logged is rolled back before the state is copied.
+	logged.Set(fmt.Errorf("disk error"))
+	assert.Equal(t, database.ErrorResponse(database.ErrNotLogged), <-resp)
+	require.NoError(t, <-saved)
+}
+
+func TestDatabase_HandleSnapshotRequest(t *testing.T) {
+	parser := database_mocks.NewRequestParser(t)
+	parser.On("Parse", "SNAPSHOT").Return(compute.NewQuery(compute.SnapshotCommand, nil), nil).Once()
+
+	storage := newStorage(t)
+	storage.On("Dump").Return([]engine.Entry(nil)).Once()
+
+	snapshots := database_mocks.NewSnapshots(t)
+	snapshots.On("Save", snapshot.Snapshot{}).Return(nil).Once()
+
+	db, err := database.NewDatabase(parser, storage, zap.NewNop(), database.WithSnapshots(snapshots))
+	require.NoError(t, err)
+	assert.Equal(t, database.OK(), db.HandleRequest(session.New(""), "SNAPSHOT"))
+}
+
+func TestDatabase_ReadOnly(t *testing.T) {
+	parser := database_mocks.NewRequestParser(t)
+	parser.On("Parse", "SET key val").Return(compute.NewQuery(compute.SetCommand, []string{"key", "val"}), nil).Once()
+	parser.On("Parse", "DEL key").Return(compute.NewQuery(compute.DelCommand, []string{"key"}), nil).Once()
+	parser.On("Parse", "GET key").Return(compute.NewQuery(compute.GetCommand, []string{"key"}), nil).Once()
+
+	storage := newStorage(t)
+	storage.On("Get", "key").Return("val", nil).Once()
+
+	db, err := database.NewDatabase(
+		parser, storage, zap.NewNop(), database.WithWAL(database_mocks.NewWAL(t)), database.WithReadOnly(),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, database.ErrorResponse(database.ErrReadOnly), db.HandleRequest(session.New(""), "SET key val"))
+	assert.Equal(t, database.ErrorResponse(database.ErrReadOnly), db.HandleRequest(session.New(""), "DEL key"))
+	assert.Equal(t, database.Value("val"), db.HandleRequest(session.New(""), "GET key"))
+}
+
+func TestDatabase_Transaction(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop())
+	require.NoError(t, err)
+
+	type step struct {
+		session int
+		request string
+		want    database.Response
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "Queued queries are executed by EXEC",
+			steps: []step{
+				{request: "SET from 10", want: database.OK()},
+				{request: "MULTI", want: database.OK()},
+				{request: "SET from 0", want: database.Queued()},
+				{request: "SET to 10", want: database.Queued()},
+				{request: "GET to", want: database.Queued()},
+				{session: 1, request: "GET to", want: database.Nil()},
+				{request: "EXEC", want: database.Array(database.OK(), database.OK(), database.Value("10"))},
+				{session: 1, request: "GET from", want: database.Value("0")},
+			},
+		},
+		{
+			name: "Queries are discarded by DISCARD",
+			steps: []step{
+				{request: "MULTI", want: database.OK()},
+				{request: "DEL from", want: database.Queued()},
+				{request: "DISCARD", want: database.OK()},
+				{request: "GET from", want: database.Value("0")},
+				{request: "EXEC", want: database.ErrorResponse(database.ErrExecWithoutMulti)},
+			},
+		},
+		{
+			name: "EXEC aborts if a watched key is changed",
+			steps: []step{
+				{request: "WATCH from to", want: database.OK()},
+				{session: 1, request: "SET to 20", want: database.OK()},
+				{request: "MULTI", want: database.OK()},
+				{request: "SET to 30", want: database.Queued()},
+				{request: "EXEC", want: database.ErrorResponse(database.ErrWatchedKeyChanged)},
+				{request: "GET to", want: database.Value("20")},
+			},
+		},
+		{
+			name: "EXEC succeeds if the watched keys are not changed",
+			steps: []step{
+				{request: "WATCH to", want: database.OK()},
+				{session: 1, request: "SET from 20", want: database.OK()},
+				{request: "MULTI", want: database.OK()},
+				{request: "SET to 30", want: database.Queued()},
+				{request: "EXEC", want: database.Array(database.OK())},
+				{request: "GET to", want: database.Value("30")},
+			},
+		},
+		{
+			name: "EXEC aborts after a query failed to be queued",
+			steps: []step{
+				{request: "MULTI", want: database.OK()},
+				{request: "SET to", want: database.ErrorResponse(compute.ErrInvalidArgsNumber)},
+				{request: "SET from 40", want: database.Queued()},
+				{request: "EXEC", want: database.ErrorResponse(database.ErrExecAborted)},
+				{request: "GET from", want: database.Value("20")},
+			},
+		},
+		{
+			name: "Invalid transaction commands",
+			steps: []step{
+				{request: "DISCARD", want: database.ErrorResponse(database.ErrDiscardWithoutMulti)},
+				{request: "MULTI", want: database.OK()},
+				{request: "MULTI", want: database.ErrorResponse(database.ErrNestedMulti)},
+				{request: "WATCH to", want: database.ErrorResponse(database.ErrWatchInMulti)},
+				{request: "SNAPSHOT", want: database.ErrorResponse(database.ErrNotInTransaction)},
+				{request: "DISCARD", want: database.OK()},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := []*session.Session{session.New(""), session.New("")}
+			defer func() {
+				for _, s := range sessions {
+					s.Close()
+				}
+			}()
+
+			for _, s := range tt.steps {
+				got := db.HandleRequest(sessions[s.session], s.request)
+				assert.Equal(t, s.want, got, "request %q", s.request)
+			}
+		})
+	}
+}
+
+func TestDatabase_TransactionWithWAL(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	log := database_mocks.NewWAL(t)
+	log.On("Append",
+		wal.Record{CommandID: compute.SetCommand, Arguments: []string{"from", "0"}},
+		wal.Record{CommandID: compute.SetCommand, Arguments: []string{"to", "10"}},
+	).Return(resolvedFuture(nil)).Once()
+
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop(), database.WithWAL(log))
+	require.NoError(t, err)
+
+	s := session.New("")
+	defer s.Close()
+	for _, request := range []string{"MULTI", "SET from 0", "GET to", "SET to 10"} {
+		db.HandleRequest(s, request)
+	}
+
+	assert.Equal(t, database.Array(database.OK(), database.Nil(), database.OK()), db.HandleRequest(s, "EXEC"))
+}
+
+func TestDatabase_Auth(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	authenticator := database_mocks.NewAuthenticator(t)
+	authenticator.On("Authenticate", "10.0.0.1:5000", "svc-a", "wrong").Return(errors.New("invalid credentials")).Once()
+	authenticator.On("Authenticate", "10.0.0.1:5000", "svc-a", "secret").Return(nil).Once()
+	authenticator.On("Authorize", "svc-a", compute.WriteCategory, []string{"key"}).Return(nil).Once()
+	authenticator.On("Authorize", "svc-a", compute.ReadCategory, []string{"key"}).Return(nil).Once()
+	authenticator.On("Authorize", "svc-a", compute.WriteCategory, []string{"other"}).Return(errors.New("no permission")).Once()
+	authenticator.On("Authorize", "svc-a", compute.NoCategory, []string(nil)).Return(nil).Twice()
+
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop(), database.WithAuthenticator(authenticator))
+	require.NoError(t, err)
+
+	s := session.New("10.0.0.1:5000")
+	steps := []struct {
+		request string
+		want    database.Response
+	}{
+		{request: "SET key val", want: database.ErrorResponse(database.ErrNoAuth)},
+		{request: "MULTI", want: database.ErrorResponse(database.ErrNoAuth)},
+		{request: "PING", want: database.ErrorResponse(database.ErrNoAuth)},
+		{request: "AUTH svc-a wrong", want: database.ErrorResponse(errors.New("invalid credentials"))},
+		{request: "GET key", want: database.ErrorResponse(database.ErrNoAuth)},
+		{request: "AUTH svc-a secret", want: database.OK()},
+		{request: "SET key val", want: database.OK()},
+		{request: "GET key", want: database.Value("val")},
+		{request: "DEL other", want: database.ErrorResponse(errors.New("no permission"))},
+		{request: "EXEC", want: database.ErrorResponse(database.ErrExecWithoutMulti)},
+		{request: "PING", want: database.Value("PONG")},
+	}
+	for _, step := range steps {
+		assert.Equal(t, step.want, db.HandleRequest(s, step.request), "request %q", step.request)
+	}
+	assert.Equal(t, "svc-a", s.User())
+}
+
+func TestDatabase_AuthDefaultUser(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	authenticator := database_mocks.NewAuthenticator(t)
+	authenticator.On("Authenticate", "10.0.0.1:5000", auth.DefaultUser, "secret").Return(nil).Once()
+
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop(), database.WithAuthenticator(authenticator))
+	require.NoError(t, err)
+
+	s := session.New("10.0.0.1:5000")
+	assert.Equal(t, database.OK(), db.HandleRequest(s, "AUTH secret"))
+	assert.Equal(t, auth.DefaultUser, s.User())
+}
+
+func TestDatabase_AuthDisabled(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop())
+	require.NoError(t, err)
+
+	s := session.New("")
+	assert.Equal(t, database.ErrorResponse(database.ErrAuthDisabled), db.HandleRequest(s, "AUTH svc-a secret"))
+	assert.False(t, s.Authenticated())
+}
+
+func TestDatabase_ACL(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	authenticator := database_mocks.NewAuthenticator(t)
+	authenticator.On("Authorize", "admin", compute.AdminCategory, []string(nil)).Return(nil).Times(3)
+	authenticator.On("SetUser", "svc-a", []string{"+@read", "~svc-a:*"}).Return(nil).Once()
+	authenticator.On("GetUser", "svc-a").Return("user svc-a ~svc-a:* -@all +@read", nil).Once()
+	authenticator.On("ListUsers").Return([]string{"user admin ~* +@all", "user svc-a ~svc-a:* -@all +@read"}).Once()
+
+	db, err := database.NewDatabase(parser, database_mocks.NewStorage(t), zap.NewNop(), database.WithAuthenticator(authenticator))
+	require.NoError(t, err)
+
+	s := session.New("")
+	s.Authenticate("admin")
+	assert.Equal(t, database.OK(), db.HandleRequest(s, "ACL SETUSER svc-a +@read ~svc-a:*"))
+	assert.Equal(t, database.Value("user svc-a ~svc-a:* -@all +@read"), db.HandleRequest(s, "ACL getuser svc-a"))
+	assert.Equal(t, database.Array(
+		database.Value("user admin ~* +@all"),
+		database.Value("user svc-a ~svc-a:* -@all +@read"),
+	), db.HandleRequest(s, "ACL LIST"))
+}
+
+func TestDatabase_Metrics(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	metrics := database_mocks.NewMetrics(t)
+	metrics.On("ObserveCommand", compute.SetCommand, mock.AnythingOfType("time.Duration"), nil).Once()
+	metrics.On("ObserveCommand", compute.GetCommand, mock.AnythingOfType("time.Duration"), nil).Once()
+	metrics.On("ObserveCommand", compute.MultiCommand, mock.AnythingOfType("time.Duration"), nil).Once()
+	metrics.On("ObserveCommand", compute.DelCommand, mock.AnythingOfType("time.Duration"), nil).Once()
+	metrics.On("ObserveCommand", compute.ExecCommand, mock.AnythingOfType("time.Duration"), nil).Once()
+
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop(), database.WithMetrics(metrics))
+	require.NoError(t, err)
+
+	s := session.New("")
+	assert.Equal(t, database.OK(), db.HandleRequest(s, "SET key value"))
+	assert.Equal(t, database.Nil(), db.HandleRequest(s, "GET missing"))
+	assert.Equal(t, database.OK(), db.HandleRequest(s, "MULTI"))
+	assert.Equal(t, database.Queued(), db.HandleRequest(s, "DEL key"))
+	assert.Equal(t, database.Array(database.OK()), db.HandleRequest(s, "EXEC"), "EXEC is observed once")
+}
+
+func TestDatabase_MultiKey(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewPartitionedEngine(4, 0), zap.NewNop())
+	require.NoError(t, err)
+
+	s := session.New("")
+	assert.Equal(t, database.OK(), db.HandleRequest(s, "MSET key_1 val_1 key_2 val_2 key_3 val_3"))
+	assert.Equal(t, database.Array(database.Value("val_1"), database.Nil(), database.Value("val_3")), db.HandleRequest(s, "MGET key_1 missing key_3"))
+	assert.Equal(t, database.OK(), db.HandleRequest(s, "DEL key_1 key_3"))
+	assert.Equal(t, database.Array(database.Nil(), database.Value("val_2"), database.Nil()), db.HandleRequest(s, "MGET key_1 key_2 key_3"))
+	assert.Equal(t, database.ErrorResponse(compute.ErrInvalidArgsNumber), db.HandleRequest(s, "MSET key_1 val_1 key_2"))
+}
+
+func TestDatabase_MultiKeyInvalidatesWatches(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop())
+	require.NoError(t, err)
+
+	watcher, other := session.New(""), session.New("")
+	assert.Equal(t, database.OK(), db.HandleRequest(watcher, "WATCH key_2"))
+	assert.Equal(t, database.OK(), db.HandleRequest(other, "MSET key_1 val_1 key_2 val_2"))
+	assert.Equal(t, database.OK(), db.HandleRequest(watcher, "MULTI"))
+	assert.Equal(t, database.Queued(), db.HandleRequest(watcher, "GET key_2"))
+	assert.Equal(t, database.ErrorResponse(database.ErrWatchedKeyChanged), db.HandleRequest(watcher, "EXEC"))
+
+	assert.Equal(t, database.OK(), db.HandleRequest(watcher, "WATCH key_2"))
+	assert.Equal(t, database.OK(), db.HandleRequest(other, "DEL key_1 key_2"))
+	assert.Equal(t, database.OK(), db.HandleRequest(watcher, "MULTI"))
+	assert.Equal(t, database.Queued(), db.HandleRequest(watcher, "GET key_2"))
+	assert.Equal(t, database.ErrorResponse(database.ErrWatchedKeyChanged), db.HandleRequest(watcher, "EXEC"))
+}
+
+func TestDatabase_Counters(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewPartitionedEngine(4, 0), zap.NewNop())
+	require.NoError(t, err)
+
+	tests := []struct {
+		request string
+		want    database.Response
+	}{
+		{request: "INCR counter", want: database.Integer(1)},
+		{request: "INCRBY counter 10", want: database.Integer(11)},
+		{request: "DECR counter", want: database.Integer(10)},
+		{request: "INCRBY counter -20", want: database.Integer(-10)},
+		{request: "INCRBYFLOAT counter 0.25", want: database.Value("-9.75")},
+		{request: "INCR counter", want: database.ErrorResponse(engine.ErrNotInteger)},
+		{request: "INCRBY other 1.5", want: database.ErrorResponse(engine.ErrNotInteger)},
+		{request: "INCRBYFLOAT other nan", want: database.ErrorResponse(engine.ErrNotFloat)},
+		{request: "SET max 9223372036854775807", want: database.OK()},
+		{request: "INCR max", want: database.ErrorResponse(engine.ErrOverflow)},
+		{request: "GET counter", want: database.Value("-9.75")},
+	}
+	s := session.New("")
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, db.HandleRequest(s, tt.request), "request %q", tt.request)
+	}
+}
+
+func TestDatabase_CountersAreLoggedAsSet(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	var records []wal.Record
+	log := database_mocks.NewWAL(t)
+	log.On("Append", mock.Anything).Return(func(rs ...wal.Record) concurrency.Future[error] {
+		records = append(records, rs...)
+		return resolvedFuture(nil)
+	})
+
+	eng := engine.NewMemEngine(0)
+	db, err := database.NewDatabase(parser, eng, zap.NewNop(), database.WithWAL(log))
+	require.NoError(t, err)
+
+	s := session.New("")
+	assert.Equal(t, database.OK(), db.HandleRequest(s, "SET limited 10 EX 100"))
+	assert.Equal(t, database.Integer(11), db.HandleRequest(s, "INCR limited"))
+	assert.Equal(t, database.Value("1.5"), db.HandleRequest(s, "INCRBYFLOAT counter 1.5"))
+	assert.Equal(t, database.ErrorResponse(engine.ErrNotInteger), db.HandleRequest(s, "INCR counter"))
+
+	require.Len(t, records, 3, "the failed increment is not logged")
+	deadline := records[0].Arguments[2]
+	assert.Equal(t, wal.Record{CommandID: compute.SetCommand, Arguments: []string{"limited", "11", deadline}}, records[1],
+		"the deadline is logged with the value")
+	assert.Equal(t, wal.Record{CommandID: compute.SetCommand, Arguments: []string{"counter", "1.5"}}, records[2])
+
+	replayLog := database_mocks.NewWAL(t)
+	replayLog.On("LSN").Return(uint64(0)).Maybe()
+	replayLog.On("Recover", uint64(0), mock.Anything).Return(func(_ uint64, apply func(wal.Record) error) error {
+		for _, r := range records {
+			if err := apply(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Once()
+
+	replayed := engine.NewMemEngine(0)
+	replica, err := database.NewDatabase(parser, replayed, zap.NewNop(), database.WithWAL(replayLog))
+	require.NoError(t, err)
+	require.NoError(t, replica.Recover())
+	assert.ElementsMatch(t, eng.Dump(), replayed.Dump())
+}
+
+func TestDatabase_ConditionalSet(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewPartitionedEngine(4, 0), zap.NewNop())
+	require.NoError(t, err)
+
+	tests := []struct {
+		request string
+		want    database.Response
+	}{
+		{request: "SET lock owner_1 NX", want: database.OK()},
+		{request: "SET lock owner_2 NX", want: database.Nil()},
+		{request: "SET lock owner_2 NX GET", want: database.Value("owner_1")},
+		{request: "SET missing val XX", want: database.Nil()},
+		{request: "SET missing val XX GET", want: database.Nil()},
+		{request: "SET lock owner_2 XX GET", want: database.Value("owner_1")},
+		{request: "SET other val GET", want: database.Nil()},
+		{request: "SET lock owner_3 EX 100 XX", want: database.OK()},
+		{request: "TTL lock", want: database.Integer(100)},
+		{request: "SET lock owner_4 EX 0 NX", want: database.ErrorResponse(database.ErrInvalidExpireTime)},
+		{request: "CAS lock owner_1 owner_4", want: database.Integer(0)},
+		{request: "CAS lock owner_3 owner_4", want: database.Integer(1)},
+		{request: "TTL lock", want: database.Integer(-1)},
+		{request: "CAS missing owner_1 owner_2", want: database.Integer(0)},
+		{request: `CAS missing "" owner_2`, want: database.ErrorResponse(engine.ErrInvalidEntityData)},
+		{request: "MGET lock missing other", want: database.Array(database.Value("owner_4"), database.Nil(), database.Value("val"))},
+	}
+	s := session.New("")
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, db.HandleRequest(s, tt.request), "request %q", tt.request)
+	}
+}
+
+func TestDatabase_ConditionalSetIsLoggedIfSet(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	var records []wal.Record
+	log := database_mocks.NewWAL(t)
+	log.On("Append", mock.Anything).Return(func(rs ...wal.Record) concurrency.Future[error] {
+		records = append(records, rs...)
+		return resolvedFuture(nil)
+	})
+
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop(), database.WithWAL(log))
+	require.NoError(t, err)
+
+	watcher, s := session.New(""), session.New("")
+	assert.Equal(t, database.OK(), db.HandleRequest(s, "SET lock owner_1 NX"))
+	assert.Equal(t, database.OK(), db.HandleRequest(watcher, "WATCH lock"))
+	assert.Equal(t, database.Nil(), db.HandleRequest(s, "SET lock owner_2 NX"))
+	assert.Equal(t, database.Integer(0), db.HandleRequest(s, "CAS lock owner_2 owner_3"))
+	assert.Equal(t, database.OK(), db.HandleRequest(watcher, "MULTI"))
+	assert.Equal(t, database.Queued(), db.HandleRequest(watcher, "CAS lock owner_1 owner_3"))
+	assert.Equal(t, database.Array(database.Integer(1)), db.HandleRequest(watcher, "EXEC"),
+		"the key that is not set is not modified")
+
+	assert.Equal(t, []wal.Record{
+		{CommandID: compute.SetCommand, Arguments: []string{"lock", "owner_1"}},
+		{CommandID: compute.SetCommand, Arguments: []string{"lock", "owner_3"}},
+	}, records)
+}
+
+func TestDatabase_Info(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+
+	storage := database_mocks.NewStorage(t)
+	storage.On("Stats").Return(engine.Stats{
+		Keys: 3, ExpiringKeys: 1, UsedMemory: 128, MaxMemory: 1024, EvictionPolicy: engine.AllKeysLRU,
+	})
+
+	log := database_mocks.NewWAL(t)
+	log.On("Stats").Return(wal.Stats{LSN: 7, Segments: 2, FlushPolicy: wal.FlushPolicyBatch}, nil)
+	log.On("LSN").Return(uint64(7))
+
+	listener := database_mocks.NewListener(t)
+	listener.On("Stats").Return(database.ListenerStats{
+		Address:             "127.0.0.1:3223",
+		Protocol:            "fastkey",
+		Connections:         2,
+		RejectedConnections: 1,
+		BytesRead:           10,
+		BytesWritten:        20,
+	})
+
+	repl := database_mocks.NewReplication(t)
+	repl.On("Stats").Return(database.ReplicationStats{Role: "master", MasterAddress: "127.0.0.1:3232", Slaves: 1})
+
+	db, err := database.NewDatabase(parser, storage, zap.NewNop(),
+		database.WithWAL(log),
+		database.WithListeners(listener),
+		database.WithReplication(repl),
+		database.WithServerInfo(database.ServerInfo{
+			Version:  "1.0.0",
+			Settings: []database.Setting{{Name: "engine_partitions", Value: "4"}},
+		}),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		req  string
+		want database.Response
+	}{
+		{
+			name: "Server section",
+			req:  "INFO server",
+			want: database.Value("# Server\nversion:1.0.0\nuptime_in_seconds:0\nengine_partitions:4"),
+		},
+		{
+			name: "Clients section",
+			req:  "INFO CLIENTS",
+			want: database.Value("# Clients\nconnected_clients:2\nlistener0:address=127.0.0.1:3223,protocol=fastkey,connections=2"),
+		},
+		{
+			name: "Memory section",
+			req:  "INFO memory",
+			want: database.Value("# Memory\nused_memory:128\nmaxmemory:1024\nmaxmemory_policy:allkeys-lru"),
+		},
+		{
+			name: "Stats section counts the INFO commands",
+			req:  "INFO stats",
+			want: database.Value("# Stats\ntotal_commands_processed:3\nrejected_connections:1\ntotal_net_input_bytes:10\ntotal_net_output_bytes:20"),
+		},
+		{
+			name: "Replication section",
+			req:  "INFO replication",
+			want: database.Value("# Replication\nrole:master\nmaster_address:127.0.0.1:3232\nconnected_slaves:1\nlsn:7"),
+		},
+		{
+			name: "WAL section",
+			req:  "INFO wal",
+			want: database.Value("# WAL\nwal_enabled:1\nlsn:7\nsegments:2\npending_records:0\nflush_policy:batch\n" +
+				"last_flush_seconds_ago:-1\nlast_flush_status:ok"),
+		},
+		{
+			name: "Keyspace section",
+			req:  "INFO keyspace",
+			want: database.Value("# Keyspace\nkeys:3\nexpires:1"),
+		},
+		{
+			name: "Unknown section",
+			req:  "INFO unknown",
+			want: database.ErrorResponse(database.ErrUnknownInfoSection),
+		},
+	}
+	s := session.New("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, db.HandleRequest(s, tt.req))
+		})
+	}
+
+	all := db.HandleRequest(s, "INFO")
+	require.Equal(t, database.ValueStatus, all.Status)
+	sections := strings.Split(all.Value, "\n\n")
+	require.Len(t, sections, 7)
+	for i, header := range []string{"# Server", "# Clients", "# Memory", "# Stats", "# Replication", "# WAL", "# Keyspace"} {
+		assert.True(t, strings.HasPrefix(sections[i], header+"\n"), sections[i])
+	}
+}
+
+func TestDatabase_InfoStandalone(t *testing.T) {
+	parser, err := compute.NewParser(zap.NewNop())
+	require.NoError(t, err)
+	db, err := database.NewDatabase(parser, engine.NewMemEngine(0), zap.NewNop())
+	require.NoError(t, err)
+
+	s := session.New("")
+	assert.Equal(t, database.Value("# Replication\nrole:standalone"), db.HandleRequest(s, "INFO replication"))
+	assert.Equal(t, database.Value("# WAL\nwal_enabled:0"), db.HandleRequest(s, "INFO wal"))
+	assert.Equal(t, database.Value("# Clients\nconnected_clients:0"), db.HandleRequest(s, "INFO clients"))
+}
+
+func TestDatabase_ApplySegment(t *testing.T) {
+	data := []byte("segment")
+
+	storage := newStorage(t)
+	storage.On("Set", "key", "val").Return(nil).Once()
+
+	log := database_mocks.NewWAL(t)
+	log.On("ApplySegment", uint64(5), data, mock.Anything).Return(func(_ uint64, _ []byte, apply func(wal.Record) error) error {
+		return apply(wal.Record{LSN: 5, CommandID: compute.SetCommand, Arguments: []string{"key", "val"}})
+	}).Once()
+	log.On("LSN").Return(uint64(5)).Once()
+
+	db, err := database.NewDatabase(
+		database_mocks.NewRequestParser(t), storage, zap.NewNop(), database.WithWAL(log), database.WithReadOnly(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, db.ApplySegment(5, data))
+	assert.EqualValues(t, 5, db.LSN())
+}
+
+func TestDatabase_ApplySegmentWithoutWAL(t *testing.T) {
+	db, err := database.NewDatabase(database_mocks.NewRequestParser(t), newStorage(t), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, database.ErrWALDisabled, db.ApplySegment(1, nil))
+	assert.Zero(t, db.LSN())
+}
+
+func TestDatabase_ApplySnapshot(t *testing.T) {
+	snap := snapshot.Snapshot{LSN: 7, Entries: []engine.Entry{{Key: "key", Value: "val"}}}
+
+	t.Run("Replica is restored from snapshot", func(t *testing.T) {
+		storage := newStorage(t)
+		storage.On("Restore", snap.Entries).Return(nil).Once()
+
+		snapshots := database_mocks.NewSnapshots(t)
+		snapshots.On("Save", snap).Return(nil).Once()
+
+		log := database_mocks.NewWAL(t)
+		log.On("Reset", uint64(7)).Return(nil).Once()
+
+		db, err := database.NewDatabase(
+			database_mocks.NewRequestParser(t), storage, zap.NewNop(),
+			database.WithWAL(log), database.WithSnapshots(snapshots), database.WithReadOnly(),
+		)
+		require.NoError(t, err)
+		require.NoError(t, db.ApplySnapshot(snap))
+	})
+
+	t.Run("Replica is kept if snapshot is not saved", func(t *testing.T) {
+		snapshots := database_mocks.NewSnapshots(t)
+		snapshots.On("Save", snap).Return(fmt.Errorf("disk error")).Once()
+
+		db, err := database.NewDatabase(
+			database_mocks.NewRequestParser(t), newStorage(t), zap.NewNop(),
+			database.WithWAL(database_mocks.NewWAL(t)), database.WithSnapshots(snapshots), database.WithReadOnly(),
+		)
+		require.NoError(t, err)
+		assert.EqualError(t, db.ApplySnapshot(snap), "save snapshot: disk error")
+	})
+
+	t.Run("Snapshots are disabled", func(t *testing.T) {
+		db, err := database.NewDatabase(
+			database_mocks.NewRequestParser(t), newStorage(t), zap.NewNop(),
+			database.WithWAL(database_mocks.NewWAL(t)), database.WithReadOnly(),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, database.ErrSnapshotsDisabled, db.ApplySnapshot(snap))
+	})
+}
+
 func TestNewDatabase(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -173,7 +1439,7 @@ func TestNewDatabase(t *testing.T) {
 		{
 			name:          "Create without parser",
 			parser:        nil,
-			db:            database_mocks.NewStorage(t),
+			db:            newStorage(t),
 			logger:        zap.NewNop(),
 			wantErr:       fmt.Errorf("parser is nil"),
 			wantNilObject: true,
@@ -189,7 +1455,7 @@ func TestNewDatabase(t *testing.T) {
 		{
 			name:          "Create without logger",
 			parser:        database_mocks.NewRequestParser(t),
-			db:            database_mocks.NewStorage(t),
+			db:            newStorage(t),
 			logger:        nil,
 			wantErr:       fmt.Errorf("logger is nil"),
 			wantNilObject: true,
@@ -197,7 +1463,7 @@ func TestNewDatabase(t *testing.T) {
 		{
 			name:          "Created",
 			parser:        database_mocks.NewRequestParser(t),
-			db:            database_mocks.NewStorage(t),
+			db:            newStorage(t),
 			logger:        zap.NewNop(),
 			wantErr:       nil,
 			wantNilObject: false,
@@ -216,3 +1482,9 @@ func TestNewDatabase(t *testing.T) {
 		})
 	}
 }
+
+func resolvedFuture(err error) concurrency.Future[error] {
+	promise := concurrency.NewPromise[error]()
+	promise.Set(err)
+	return promise.Future()
+}
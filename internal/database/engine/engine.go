@@ -1,24 +1,74 @@
 package engine
 
-import "sync"
+import (
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry defines a key-value pair of the engine.
+type Entry struct {
+	Key   string
+	Value string
+	// ExpiresAt is the Unix time in nanoseconds the pair expires at, zero if it never expires.
+	ExpiresAt int64
+}
+
+// item defines the stored value and its usage statistics for the eviction.
+type item struct {
+	value string
+	// access is the engine clock value of the last access.
+	access atomic.Uint64
+	hits   atomic.Uint32
+}
 
 // MemEngine defines a key-value data store.
+//
+// Expired keys are hidden on access and removed either by the access
+// or by DeleteExpired that is called periodically.
+//
+// If the max memory is set, the engine evicts keys by its eviction policy
+// once the approximate size of the entries reaches the limit. The evicted
+// entries are kept until they are taken by Evicted.
 type MemEngine struct {
-	mtx sync.Mutex
-	m   map[string]string
+	mtx     sync.RWMutex
+	m       map[string]*item
+	expires map[string]int64
+	used    int
+	clock   atomic.Uint64
+	// evicted holds the evicted entries that are not taken yet, hasEvicted
+	// lets Evicted skip the lock when there are none.
+	evicted    []Entry
+	hasEvicted atomic.Bool
+
+	// memory is the max memory budget, the partitions of an engine share it.
+	memory         *memoryBudget
+	evictionPolicy EvictionPolicy
 }
 
 // NewMemEngine creates a new Engine.
-func NewMemEngine(cap int) *MemEngine {
+func NewMemEngine(cap int, options ...Option) *MemEngine {
 	if cap == 0 {
 		cap = 128
 	}
-	return &MemEngine{
-		m: make(map[string]string, cap),
+
+	e := &MemEngine{
+		m:              make(map[string]*item, cap),
+		expires:        make(map[string]int64),
+		memory:         &memoryBudget{},
+		evictionPolicy: NoEviction,
+	}
+
+	for _, option := range options {
+		option(e)
 	}
+
+	return e
 }
 
-// Set sets a new key-value pair.
+// Set sets a new key-value pair that never expires.
 func (e *MemEngine) Set(k, v string) error {
 	if len(k) == 0 {
 		return ErrInvalidEntityID
@@ -28,8 +78,35 @@ func (e *MemEngine) Set(k, v string) error {
 	}
 
 	e.mtx.Lock()
-	e.m[k] = v
-	e.mtx.Unlock()
+	defer e.mtx.Unlock()
+
+	if err := e.put(k, v); err != nil {
+		return err
+	}
+	delete(e.expires, k)
+	return nil
+}
+
+// SetWithDeadline sets a new key-value pair that expires at the deadline.
+func (e *MemEngine) SetWithDeadline(k, v string, deadline time.Time) error {
+	if len(k) == 0 {
+		return ErrInvalidEntityID
+	}
+	if len(v) == 0 {
+		return ErrInvalidEntityData
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if !deadline.After(time.Now()) {
+		e.delete(k)
+		return nil
+	}
+	if err := e.put(k, v); err != nil {
+		return err
+	}
+	e.expires[k] = deadline.UnixNano()
 	return nil
 }
 
@@ -39,14 +116,22 @@ func (e *MemEngine) Get(k string) (string, error) {
 		return "", ErrInvalidEntityID
 	}
 
-	e.mtx.Lock()
-	defer e.mtx.Unlock()
+	e.mtx.RLock()
+	it, found := e.m[k]
+	expired := found && e.expired(k, time.Now().UnixNano())
+	if found && !expired {
+		e.touch(it)
+	}
+	e.mtx.RUnlock()
 
-	if val, found := e.m[k]; !found {
+	if expired {
+		e.deleteIfExpired(k)
 		return "", ErrNotFound
-	} else {
-		return val, nil
 	}
+	if !found {
+		return "", ErrNotFound
+	}
+	return it.value, nil
 }
 
 // Del deletes the value by key.
@@ -55,6 +140,542 @@ func (e *MemEngine) Del(k string) error {
 		return ErrInvalidEntityID
 	}
 
-	delete(e.m, k)
+	e.mtx.Lock()
+	e.delete(k)
+	e.mtx.Unlock()
+	return nil
+}
+
+// Expire sets the deadline of the key, the key is deleted if the deadline has passed.
+//
+// It reports whether the key exists.
+func (e *MemEngine) Expire(k string, deadline time.Time) (bool, error) {
+	if len(k) == 0 {
+		return false, ErrInvalidEntityID
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if !e.exists(k, time.Now().UnixNano()) {
+		return false, nil
+	}
+	if !deadline.After(time.Now()) {
+		e.delete(k)
+		return true, nil
+	}
+	e.expires[k] = deadline.UnixNano()
+	return true, nil
+}
+
+// Persist removes the deadline of the key.
+//
+// It reports whether the key existed and had a deadline.
+func (e *MemEngine) Persist(k string) (bool, error) {
+	if len(k) == 0 {
+		return false, ErrInvalidEntityID
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if !e.exists(k, time.Now().UnixNano()) {
+		return false, nil
+	}
+	if _, found := e.expires[k]; !found {
+		return false, nil
+	}
+	delete(e.expires, k)
+	return true, nil
+}
+
+// Deadline returns the time the key expires at, zero time if the key never expires.
+func (e *MemEngine) Deadline(k string) (time.Time, error) {
+	if len(k) == 0 {
+		return time.Time{}, ErrInvalidEntityID
+	}
+
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	if !e.exists(k, time.Now().UnixNano()) {
+		return time.Time{}, ErrNotFound
+	}
+	if deadline, found := e.expires[k]; found {
+		return time.Unix(0, deadline), nil
+	}
+	return time.Time{}, nil
+}
+
+// MSet sets the key-value pairs that never expire, either all of them or none.
+func (e *MemEngine) MSet(entries []Entry) error {
+	if err := validateEntries(entries); err != nil {
+		return err
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	batch, _, err := e.reserveAll(entries, 0)
+	if err != nil {
+		return err
+	}
+	e.storeAll(batch)
+	return nil
+}
+
+// MGet returns the values of the keys in order, the value of a missing key is empty
+// as the stored values are never empty.
+func (e *MemEngine) MGet(keys []string) ([]string, error) {
+	if err := validateKeys(keys); err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixNano()
+
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i], _ = e.get(k, now)
+	}
+	return values, nil
+}
+
+// MDel deletes the values by keys.
+func (e *MemEngine) MDel(keys []string) error {
+	if err := validateKeys(keys); err != nil {
+		return err
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	for _, k := range keys {
+		e.delete(k)
+	}
+	return nil
+}
+
+// CompareAndSet sets the value of the key if check accepts its current value,
+// empty if the key does not exist as the stored values are never empty.
+// The value expires at the deadline, it never expires if the deadline is zero.
+//
+// It returns the current value and whether the value is set.
+func (e *MemEngine) CompareAndSet(k, v string, deadline time.Time, check func(current string) bool) (string, bool, error) {
+	if len(k) == 0 {
+		return "", false, ErrInvalidEntityID
+	}
+	if len(v) == 0 {
+		return "", false, ErrInvalidEntityData
+	}
+	now := time.Now()
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if e.expired(k, now.UnixNano()) {
+		e.delete(k)
+	}
+	current, _ := e.get(k, now.UnixNano())
+	if !check(current) {
+		return current, false, nil
+	}
+
+	if !deadline.IsZero() && !deadline.After(now) {
+		e.delete(k)
+		return current, true, nil
+	}
+	if err := e.put(k, v); err != nil {
+		return current, false, err
+	}
+	if deadline.IsZero() {
+		delete(e.expires, k)
+	} else {
+		e.expires[k] = deadline.UnixNano()
+	}
+	return current, true, nil
+}
+
+// IncrBy adds the delta to the integer value of the key, a missing key is 0.
+// The time to live of the key is kept.
+//
+// It returns the stored entry.
+func (e *MemEngine) IncrBy(k string, delta int64) (Entry, error) {
+	return e.update(k, func(v string) (string, error) {
+		var n int64
+		if v != "" {
+			var err error
+			if n, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return "", ErrNotInteger
+			}
+		}
+		if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+			return "", ErrOverflow
+		}
+		return strconv.FormatInt(n+delta, 10), nil
+	})
+}
+
+// IncrByFloat adds the delta to the float value of the key, a missing key is 0.
+// The time to live of the key is kept.
+//
+// It returns the stored entry.
+func (e *MemEngine) IncrByFloat(k string, delta float64) (Entry, error) {
+	if !finite(delta) {
+		return Entry{}, ErrNotFloat
+	}
+
+	return e.update(k, func(v string) (string, error) {
+		var n float64
+		if v != "" {
+			var err error
+			if n, err = strconv.ParseFloat(v, 64); err != nil || !finite(n) {
+				return "", ErrNotFloat
+			}
+		}
+		if n += delta; !finite(n) {
+			return "", ErrOverflow
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	})
+}
+
+// DeleteExpired checks up to limit keys with a deadline and deletes the expired ones.
+//
+// It returns the number of the deleted keys.
+func (e *MemEngine) DeleteExpired(limit int) int {
+	now := time.Now().UnixNano()
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	checked, deleted := 0, 0
+	for k, deadline := range e.expires {
+		if checked == limit {
+			break
+		}
+		checked++
+
+		if deadline <= now {
+			e.delete(k)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Dump returns a copy of all key-value pairs that are not expired.
+func (e *MemEngine) Dump() []Entry {
+	now := time.Now().UnixNano()
+
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	entries := make([]Entry, 0, len(e.m))
+	for k, it := range e.m {
+		if e.expired(k, now) {
+			continue
+		}
+		entries = append(entries, Entry{Key: k, Value: it.value, ExpiresAt: e.expires[k]})
+	}
+	return entries
+}
+
+// Restore replaces all key-value pairs with the entries that are not expired.
+//
+// The entries over the max memory are evicted by the policy, the state is kept
+// as is and ErrOutOfMemory is returned if they can't be evicted.
+func (e *MemEngine) Restore(entries []Entry) error {
+	now := time.Now().UnixNano()
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	replaced := e.detach()
+	if err := e.fill(entries, now); err != nil {
+		e.reset(replaced)
+		return err
+	}
+	return nil
+}
+
+// Evicted returns the entries evicted since the last call, so the evictions can be logged.
+func (e *MemEngine) Evicted() []Entry {
+	if !e.hasEvicted.Load() {
+		return nil
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	evicted := e.evicted
+	e.evicted = nil
+	e.hasEvicted.Store(false)
+	return evicted
+}
+
+// UsedMemory returns the approximate number of bytes held by the entries.
+func (e *MemEngine) UsedMemory() int {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	return e.used
+}
+
+// Len returns the number of the keys, including the expired ones that are not removed yet.
+func (e *MemEngine) Len() int {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	return len(e.m)
+}
+
+// Stats defines the state of the engine.
+type Stats struct {
+	Keys           int
+	ExpiringKeys   int
+	UsedMemory     int
+	MaxMemory      int
+	EvictionPolicy EvictionPolicy
+}
+
+// Stats returns the current state of the engine, the keys include the expired ones that are not removed yet.
+func (e *MemEngine) Stats() Stats {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	return Stats{
+		Keys:           len(e.m),
+		ExpiringKeys:   len(e.expires),
+		UsedMemory:     e.used,
+		MaxMemory:      e.memory.max,
+		EvictionPolicy: e.evictionPolicy,
+	}
+}
+
+// memState defines the entries of the engine replaced by detach.
+type memState struct {
+	m       map[string]*item
+	expires map[string]int64
+	used    int
+	evicted []Entry
+}
+
+// detach replaces the entries with no entries and returns the replaced state.
+func (e *MemEngine) detach() memState {
+	replaced := memState{m: e.m, expires: e.expires, used: e.used, evicted: e.evicted}
+
+	e.m = make(map[string]*item, len(replaced.m))
+	e.expires = make(map[string]int64)
+	e.grow(-e.used)
+	return replaced
+}
+
+// fill stores the entries that are not expired evicting them over the max memory.
+//
+// The evictions of the stored entries are not reported, as the restored state is not logged.
+func (e *MemEngine) fill(entries []Entry, now int64) error {
+	for _, entry := range entries {
+		if entry.ExpiresAt != 0 && entry.ExpiresAt <= now {
+			continue
+		}
+
+		e.store(entry.Key, entry.Value)
+		if entry.ExpiresAt != 0 {
+			e.expires[entry.Key] = entry.ExpiresAt
+		} else {
+			delete(e.expires, entry.Key)
+		}
+	}
+
+	if e.memory.max == 0 {
+		return nil
+	}
+	reported := len(e.evicted)
+	err := e.evict(0, func(string) bool { return false })
+	e.evicted = e.evicted[:reported]
+	e.hasEvicted.Store(len(e.evicted) != 0)
+	return err
+}
+
+// reset sets the state replaced by detach back.
+func (e *MemEngine) reset(s memState) {
+	e.grow(s.used - e.used)
+	e.m = s.m
+	e.expires = s.expires
+	e.evicted = s.evicted
+	e.hasEvicted.Store(len(e.evicted) != 0)
+}
+
+// grow adds delta to the memory used by the entries and to the budget.
+func (e *MemEngine) grow(delta int) {
+	e.used += delta
+	e.memory.used.Add(int64(delta))
+}
+
+// exists reports whether the key is set and not expired.
+func (e *MemEngine) exists(k string, now int64) bool {
+	_, found := e.m[k]
+	return found && !e.expired(k, now)
+}
+
+func (e *MemEngine) expired(k string, now int64) bool {
+	deadline, found := e.expires[k]
+	return found && deadline <= now
+}
+
+func (e *MemEngine) deleteIfExpired(k string) {
+	e.mtx.Lock()
+	if e.expired(k, time.Now().UnixNano()) {
+		e.delete(k)
+	}
+	e.mtx.Unlock()
+}
+
+// put stores the value making room for it if the memory is limited.
+func (e *MemEngine) put(k, v string) error {
+	if e.memory.max != 0 && entrySize(k, v) > e.memory.max {
+		return ErrOutOfMemory
+	}
+	if err := e.reserve(e.growth(k, v), func(key string) bool { return key == k }); err != nil {
+		return err
+	}
+
+	e.store(k, v)
+	return nil
+}
+
+// growth returns the number of bytes the value adds replacing the current one.
+func (e *MemEngine) growth(k, v string) int {
+	size := entrySize(k, v)
+	if old, found := e.m[k]; found {
+		size -= entrySize(k, old.value)
+	}
+	return size
+}
+
+// store stores the value the room is made for.
+func (e *MemEngine) store(k, v string) {
+	it := &item{value: v}
+	if old, found := e.m[k]; found {
+		it.hits.Store(old.hits.Load())
+		e.grow(-entrySize(k, old.value))
+	}
+	e.touch(it)
+
+	e.m[k] = it
+	e.grow(entrySize(k, v))
+}
+
+// get returns the value if the key is set and not expired.
+func (e *MemEngine) get(k string, now int64) (string, bool) {
+	it, found := e.m[k]
+	if !found || e.expired(k, now) {
+		return "", false
+	}
+	e.touch(it)
+	return it.value, true
+}
+
+// update replaces the value of the key with the value computed from the current one,
+// empty if the key does not exist. It returns the stored entry.
+func (e *MemEngine) update(k string, compute func(v string) (string, error)) (Entry, error) {
+	if len(k) == 0 {
+		return Entry{}, ErrInvalidEntityID
+	}
+	now := time.Now().UnixNano()
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if e.expired(k, now) {
+		e.delete(k)
+	}
+	current, _ := e.get(k, now)
+	v, err := compute(current)
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := e.put(k, v); err != nil {
+		return Entry{}, err
+	}
+	return Entry{Key: k, Value: v, ExpiresAt: e.expires[k]}, nil
+}
+
+// reserveAll makes room for the entries evicting the keys other than theirs, the room
+// reserved by the other partitions is kept. It returns the values by keys, the last value
+// of a repeated key wins, and the number of bytes they add.
+func (e *MemEngine) reserveAll(entries []Entry, reserved int) (map[string]string, int, error) {
+	batch := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		batch[entry.Key] = entry.Value
+	}
+
+	size := 0
+	for k, v := range batch {
+		if e.memory.max != 0 && entrySize(k, v) > e.memory.max {
+			return nil, 0, ErrOutOfMemory
+		}
+		size += e.growth(k, v)
+	}
+	if size <= 0 {
+		return batch, size, nil
+	}
+
+	err := e.reserve(reserved+size, func(key string) bool {
+		_, found := batch[key]
+		return found
+	})
+	return batch, size, err
+}
+
+// storeAll stores the values the room is made for by reserveAll, they never expire.
+func (e *MemEngine) storeAll(batch map[string]string) {
+	for k, v := range batch {
+		e.store(k, v)
+		delete(e.expires, k)
+	}
+}
+
+// touch records the access to the item.
+func (e *MemEngine) touch(it *item) {
+	it.access.Store(e.clock.Add(1))
+	if hits := it.hits.Load(); hits != math.MaxUint32 {
+		it.hits.CompareAndSwap(hits, hits+1)
+	}
+}
+
+func (e *MemEngine) delete(k string) {
+	if it, found := e.m[k]; found {
+		e.grow(-entrySize(k, it.value))
+		delete(e.m, k)
+	}
+	delete(e.expires, k)
+}
+
+// finite reports whether the float is neither infinity nor NaN.
+func finite(f float64) bool {
+	return !math.IsInf(f, 0) && !math.IsNaN(f)
+}
+
+func validateKeys(keys []string) error {
+	for _, k := range keys {
+		if len(k) == 0 {
+			return ErrInvalidEntityID
+		}
+	}
+	return nil
+}
+
+func validateEntries(entries []Entry) error {
+	for _, entry := range entries {
+		if len(entry.Key) == 0 {
+			return ErrInvalidEntityID
+		}
+		if len(entry.Value) == 0 {
+			return ErrInvalidEntityData
+		}
+	}
 	return nil
 }
@@ -0,0 +1,18 @@
+package engine
+
+// Option defines an optional MemEngine setting.
+type Option func(*MemEngine)
+
+// WithMaxMemory sets the approximate number of bytes the engine may hold, zero means no limit.
+func WithMaxMemory(size uint) Option {
+	return func(e *MemEngine) {
+		e.memory.max = int(size)
+	}
+}
+
+// WithEvictionPolicy sets the policy of choosing the keys to evict when the memory is exhausted.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(e *MemEngine) {
+		e.evictionPolicy = policy
+	}
+}
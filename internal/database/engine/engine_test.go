@@ -1,7 +1,9 @@
 package engine_test
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/alukart32/go-fast-key/internal/database/engine"
 	"github.com/stretchr/testify/assert"
@@ -184,3 +186,286 @@ func TestMemEngine_Del(t *testing.T) {
 		})
 	}
 }
+
+func TestMemEngine_DumpAndRestore(t *testing.T) {
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.Set("key_1", "val_1"))
+	require.NoError(t, eng.Set("key_2", "val_2"))
+
+	entries := eng.Dump()
+	assert.ElementsMatch(t, []engine.Entry{
+		{Key: "key_1", Value: "val_1"},
+		{Key: "key_2", Value: "val_2"},
+	}, entries)
+
+	restored := engine.NewMemEngine(0)
+	require.NoError(t, restored.Set("key_3", "val_3"))
+	require.NoError(t, restored.Restore(entries))
+
+	assert.ElementsMatch(t, entries, restored.Dump())
+	assert.Equal(t, 2, restored.Len())
+	_, err := restored.Get("key_3")
+	assert.ErrorIs(t, err, engine.ErrNotFound, "restore must replace the old entries")
+}
+
+func TestMemEngine_Expiration(t *testing.T) {
+	eng := engine.NewMemEngine(0)
+
+	deadline := time.Now().Add(time.Hour)
+	require.NoError(t, eng.SetWithDeadline("key", "val", deadline))
+
+	got, err := eng.Deadline("key")
+	require.NoError(t, err)
+	assert.Equal(t, deadline.UnixNano(), got.UnixNano())
+
+	persisted, err := eng.Persist("key")
+	require.NoError(t, err)
+	assert.True(t, persisted)
+
+	got, err = eng.Deadline("key")
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	persisted, err = eng.Persist("key")
+	require.NoError(t, err)
+	assert.False(t, persisted, "the key has no deadline")
+
+	exists, err := eng.Expire("missing", deadline)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = eng.Expire("key", time.Now().Add(20*time.Millisecond))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, err = eng.Get("key")
+	assert.ErrorIs(t, err, engine.ErrNotFound)
+	_, err = eng.Deadline("key")
+	assert.ErrorIs(t, err, engine.ErrNotFound)
+}
+
+func TestMemEngine_SetResetsDeadline(t *testing.T) {
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.SetWithDeadline("key", "val", time.Now().Add(time.Hour)))
+	require.NoError(t, eng.Set("key", "new"))
+
+	got, err := eng.Deadline("key")
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+}
+
+func TestMemEngine_ExpireInThePast(t *testing.T) {
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.Set("key", "val"))
+
+	exists, err := eng.Expire("key", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	_, err = eng.Get("key")
+	assert.ErrorIs(t, err, engine.ErrNotFound)
+}
+
+func TestMemEngine_DeleteExpired(t *testing.T) {
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.Set("key_1", "val_1"))
+	require.NoError(t, eng.SetWithDeadline("key_2", "val_2", time.Now().Add(time.Hour)))
+	require.NoError(t, eng.SetWithDeadline("key_3", "val_3", time.Now().Add(10*time.Millisecond)))
+
+	time.Sleep(20 * time.Millisecond)
+
+	assert.Equal(t, 1, eng.DeleteExpired(10))
+	assert.Equal(t, 0, eng.DeleteExpired(10))
+	assert.Len(t, eng.Dump(), 2)
+}
+
+func TestMemEngine_DumpAndRestoreDeadlines(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).UnixNano()
+
+	restored := engine.NewMemEngine(0)
+	err := restored.Restore([]engine.Entry{
+		{Key: "key_1", Value: "val_1", ExpiresAt: deadline},
+		{Key: "key_2", Value: "val_2", ExpiresAt: time.Now().Add(-time.Hour).UnixNano()},
+		{Key: "key_3", Value: "val_3"},
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []engine.Entry{
+		{Key: "key_1", Value: "val_1", ExpiresAt: deadline},
+		{Key: "key_3", Value: "val_3"},
+	}, restored.Dump())
+}
+
+func TestMemEngine_Batch(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.SetWithDeadline("key_1", "old", time.Now().Add(time.Minute)))
+
+	require.NoError(t, eng.MSet([]engine.Entry{
+		{Key: "key_1", Value: "val_1"},
+		{Key: "key_2", Value: "val_2"},
+		{Key: "key_2", Value: "new_2"},
+	}))
+	deadline, err := eng.Deadline("key_1")
+	require.NoError(t, err)
+	assert.True(t, deadline.IsZero(), "MSET removes the deadline")
+
+	values, err := eng.MGet([]string{"key_1", "missing", "key_2", "key_1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"val_1", "", "new_2", "val_1"}, values, "the last value of a repeated key wins")
+
+	require.NoError(t, eng.MDel([]string{"key_1", "missing"}))
+	values, err = eng.MGet([]string{"key_1", "key_2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "new_2"}, values)
+}
+
+func TestMemEngine_BatchInvalidEntries(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewMemEngine(0)
+	assert.ErrorIs(t, eng.MSet([]engine.Entry{{Key: "key", Value: "val"}, {Key: "", Value: "val"}}), engine.ErrInvalidEntityID)
+	assert.ErrorIs(t, eng.MSet([]engine.Entry{{Key: "key", Value: "val"}, {Key: "key", Value: ""}}), engine.ErrInvalidEntityData)
+	assert.Empty(t, eng.Dump(), "nothing is set from the invalid batch")
+
+	_, err := eng.MGet([]string{"key", ""})
+	assert.ErrorIs(t, err, engine.ErrInvalidEntityID)
+	assert.ErrorIs(t, eng.MDel([]string{""}), engine.ErrInvalidEntityID)
+}
+
+func TestMemEngine_IncrBy(t *testing.T) {
+	t.Parallel()
+
+	deadline := time.Now().Add(time.Hour).UnixNano()
+
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.Set("text", "val"))
+	require.NoError(t, eng.Set("max", "9223372036854775806"))
+	require.NoError(t, eng.SetWithDeadline("limited", "10", time.Unix(0, deadline)))
+
+	got, err := eng.IncrBy("missing", -3)
+	require.NoError(t, err)
+	assert.Equal(t, engine.Entry{Key: "missing", Value: "-3"}, got, "a missing key is 0")
+
+	got, err = eng.IncrBy("limited", 5)
+	require.NoError(t, err)
+	assert.Equal(t, engine.Entry{Key: "limited", Value: "15", ExpiresAt: deadline}, got, "the deadline is kept")
+
+	got, err = eng.IncrBy("max", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "9223372036854775807", got.Value)
+
+	_, err = eng.IncrBy("max", 1)
+	assert.ErrorIs(t, err, engine.ErrOverflow)
+	_, err = eng.IncrBy("text", 1)
+	assert.ErrorIs(t, err, engine.ErrNotInteger)
+	_, err = eng.IncrBy("", 1)
+	assert.ErrorIs(t, err, engine.ErrInvalidEntityID)
+
+	v, err := eng.Get("max")
+	require.NoError(t, err)
+	assert.Equal(t, "9223372036854775807", v, "the failed increment keeps the value")
+}
+
+func TestMemEngine_IncrByFloat(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.Set("text", "val"))
+	require.NoError(t, eng.Set("int", "10"))
+	require.NoError(t, eng.Set("huge", "1.7e308"))
+
+	got, err := eng.IncrByFloat("int", 0.1)
+	require.NoError(t, err)
+	assert.Equal(t, "10.1", got.Value)
+
+	got, err = eng.IncrByFloat("missing", -2.5)
+	require.NoError(t, err)
+	assert.Equal(t, "-2.5", got.Value)
+
+	_, err = eng.IncrByFloat("huge", 1e308)
+	assert.ErrorIs(t, err, engine.ErrOverflow)
+	_, err = eng.IncrByFloat("text", 1)
+	assert.ErrorIs(t, err, engine.ErrNotFloat)
+	_, err = eng.IncrByFloat("int", math.Inf(1))
+	assert.ErrorIs(t, err, engine.ErrNotFloat)
+}
+
+func TestMemEngine_IncrByExpiredKey(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.SetWithDeadline("key", "10", time.Now().Add(10*time.Millisecond)))
+	time.Sleep(20 * time.Millisecond)
+
+	got, err := eng.IncrBy("key", 1)
+	require.NoError(t, err)
+	assert.Equal(t, engine.Entry{Key: "key", Value: "1"}, got, "the expired key is 0 and has no deadline")
+}
+
+func TestMemEngine_CompareAndSet(t *testing.T) {
+	t.Parallel()
+
+	absent := func(current string) bool { return current == "" }
+	equal := func(expected string) func(string) bool {
+		return func(current string) bool { return current == expected }
+	}
+
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.SetWithDeadline("expiring", "val", time.Now().Add(time.Hour)))
+
+	current, set, err := eng.CompareAndSet("lock", "owner_1", time.Time{}, absent)
+	require.NoError(t, err)
+	assert.True(t, set)
+	assert.Empty(t, current)
+
+	current, set, err = eng.CompareAndSet("lock", "owner_2", time.Time{}, absent)
+	require.NoError(t, err)
+	assert.False(t, set)
+	assert.Equal(t, "owner_1", current)
+
+	current, set, err = eng.CompareAndSet("lock", "owner_2", time.Time{}, equal("owner_1"))
+	require.NoError(t, err)
+	assert.True(t, set)
+	assert.Equal(t, "owner_1", current)
+
+	v, err := eng.Get("lock")
+	require.NoError(t, err)
+	assert.Equal(t, "owner_2", v)
+
+	deadline := time.Now().Add(time.Minute)
+	_, set, err = eng.CompareAndSet("expiring", "new", deadline, equal("val"))
+	require.NoError(t, err)
+	assert.True(t, set)
+	got, err := eng.Deadline("expiring")
+	require.NoError(t, err)
+	assert.Equal(t, deadline.UnixNano(), got.UnixNano())
+
+	_, set, err = eng.CompareAndSet("expiring", "newer", time.Time{}, equal("new"))
+	require.NoError(t, err)
+	assert.True(t, set)
+	got, err = eng.Deadline("expiring")
+	require.NoError(t, err)
+	assert.True(t, got.IsZero(), "the zero deadline removes the time to live")
+
+	_, _, err = eng.CompareAndSet("", "val", time.Time{}, absent)
+	assert.ErrorIs(t, err, engine.ErrInvalidEntityID)
+	_, _, err = eng.CompareAndSet("key", "", time.Time{}, absent)
+	assert.ErrorIs(t, err, engine.ErrInvalidEntityData)
+}
+
+func TestMemEngine_CompareAndSetExpiredKey(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewMemEngine(0)
+	require.NoError(t, eng.SetWithDeadline("lock", "owner_1", time.Now().Add(10*time.Millisecond)))
+	time.Sleep(20 * time.Millisecond)
+
+	current, set, err := eng.CompareAndSet("lock", "owner_2", time.Time{}, func(current string) bool { return current == "" })
+	require.NoError(t, err)
+	assert.True(t, set, "the expired key does not exist")
+	assert.Empty(t, current)
+}
@@ -7,4 +7,8 @@ var (
 	ErrNotFound          = errors.New("entity not found")
 	ErrInvalidEntityID   = errors.New("invalid entity id")
 	ErrInvalidEntityData = errors.New("invalid entity data")
+	ErrOutOfMemory       = errors.New("out of memory")
+	ErrNotInteger        = errors.New("value is not an integer or out of range")
+	ErrNotFloat          = errors.New("value is not a valid float")
+	ErrOverflow          = errors.New("increment or decrement would overflow")
 )
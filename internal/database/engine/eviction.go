@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// EvictionPolicy defines how the keys to evict are chosen when the memory is exhausted.
+type EvictionPolicy string
+
+const (
+	// NoEviction rejects the writes that need more memory.
+	NoEviction EvictionPolicy = "noeviction"
+	// AllKeysLRU evicts the least recently used keys.
+	AllKeysLRU EvictionPolicy = "allkeys-lru"
+	// AllKeysLFU evicts the least frequently used keys.
+	AllKeysLFU EvictionPolicy = "allkeys-lfu"
+	// VolatileTTL evicts the keys with the nearest deadline, keys without a deadline are kept.
+	VolatileTTL EvictionPolicy = "volatile-ttl"
+	// Random evicts random keys.
+	Random EvictionPolicy = "random"
+)
+
+// evictionSamples is the number of keys compared to choose the one to evict.
+//
+// The keys are sampled in the map iteration order, so the choice is approximate.
+const evictionSamples = 5
+
+// entryOverhead approximates the memory held by an entry apart from its key and value.
+const entryOverhead = 64
+
+// Valid reports whether the policy is supported.
+func (p EvictionPolicy) Valid() bool {
+	switch p {
+	case NoEviction, AllKeysLRU, AllKeysLFU, VolatileTTL, Random:
+		return true
+	}
+	return false
+}
+
+// memoryBudget defines the max memory and the memory used against it.
+type memoryBudget struct {
+	max  int
+	used atomic.Int64
+}
+
+func entrySize(k, v string) int {
+	return len(k) + len(v) + entryOverhead
+}
+
+// reserve makes room for size more bytes evicting the keys that are not kept.
+func (e *MemEngine) reserve(size int, keep func(key string) bool) error {
+	if e.memory.max == 0 || size <= 0 {
+		return nil
+	}
+	return e.evict(size, keep)
+}
+
+// evict evicts the keys that are not kept until size more bytes fit the max memory,
+// the evicted entries are kept for Evicted.
+//
+// The budget may be shared with the other partitions, but only the keys
+// of this one are evicted.
+func (e *MemEngine) evict(size int, keep func(key string) bool) error {
+	for int(e.memory.used.Load())+size > e.memory.max {
+		victim, found := e.victim(keep)
+		if !found {
+			return ErrOutOfMemory
+		}
+
+		e.evicted = append(e.evicted, Entry{Key: victim, Value: e.m[victim].value, ExpiresAt: e.expires[victim]})
+		e.hasEvicted.Store(true)
+		e.delete(victim)
+	}
+	return nil
+}
+
+// victim chooses the key to evict by the policy, the kept keys are never chosen.
+func (e *MemEngine) victim(keep func(key string) bool) (string, bool) {
+	var (
+		victim string
+		found  bool
+		best   = uint64(math.MaxUint64)
+	)
+
+	sampled := 0
+	switch e.evictionPolicy {
+	case AllKeysLRU, AllKeysLFU, Random:
+		for key, it := range e.m {
+			if keep(key) {
+				continue
+			}
+
+			var score uint64
+			switch e.evictionPolicy {
+			case AllKeysLRU:
+				score = it.access.Load()
+			case AllKeysLFU:
+				score = uint64(it.hits.Load())
+			}
+			if score < best {
+				victim, found, best = key, true, score
+			}
+
+			if sampled++; sampled == evictionSamples || e.evictionPolicy == Random {
+				break
+			}
+		}
+	case VolatileTTL:
+		for key, deadline := range e.expires {
+			if keep(key) {
+				continue
+			}
+
+			if uint64(deadline) < best {
+				victim, found, best = key, true, uint64(deadline)
+			}
+			if sampled++; sampled == evictionSamples {
+				break
+			}
+		}
+	}
+
+	return victim, found
+}
@@ -0,0 +1,228 @@
+package engine_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entrySize is the approximate size of the test entries with 5 byte keys and values.
+const entrySize = 5 + 5 + 64
+
+func TestMemEngine_NoEviction(t *testing.T) {
+	eng := engine.NewMemEngine(0, engine.WithMaxMemory(2*entrySize))
+	require.NoError(t, eng.Set("key_1", "val_1"))
+	require.NoError(t, eng.Set("key_2", "val_2"))
+	assert.Equal(t, 2*entrySize, eng.UsedMemory())
+
+	assert.ErrorIs(t, eng.Set("key_3", "val_3"), engine.ErrOutOfMemory)
+	require.NoError(t, eng.Set("key_1", "new_1"), "a value of the same size fits")
+
+	require.NoError(t, eng.Del("key_2"))
+	require.NoError(t, eng.Set("key_3", "val_3"))
+	assert.Equal(t, 2*entrySize, eng.UsedMemory())
+}
+
+func TestMemEngine_Eviction(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  engine.EvictionPolicy
+		prepare func(t *testing.T, eng *engine.MemEngine)
+		evicted string
+	}{
+		{
+			name:   "LRU evicts the least recently used key",
+			policy: engine.AllKeysLRU,
+			prepare: func(t *testing.T, eng *engine.MemEngine) {
+				require.NoError(t, eng.Set("key_1", "val_1"))
+				require.NoError(t, eng.Set("key_2", "val_2"))
+				_, err := eng.Get("key_1")
+				require.NoError(t, err)
+			},
+			evicted: "key_2",
+		},
+		{
+			name:   "LFU evicts the least frequently used key",
+			policy: engine.AllKeysLFU,
+			prepare: func(t *testing.T, eng *engine.MemEngine) {
+				require.NoError(t, eng.Set("key_1", "val_1"))
+				require.NoError(t, eng.Set("key_2", "val_2"))
+				for range 3 {
+					_, err := eng.Get("key_2")
+					require.NoError(t, err)
+				}
+				_, err := eng.Get("key_1")
+				require.NoError(t, err)
+			},
+			evicted: "key_1",
+		},
+		{
+			name:   "Volatile TTL evicts the key with the nearest deadline",
+			policy: engine.VolatileTTL,
+			prepare: func(t *testing.T, eng *engine.MemEngine) {
+				require.NoError(t, eng.SetWithDeadline("key_1", "val_1", time.Now().Add(time.Hour)))
+				require.NoError(t, eng.SetWithDeadline("key_2", "val_2", time.Now().Add(time.Minute)))
+			},
+			evicted: "key_2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := engine.NewMemEngine(0, engine.WithMaxMemory(2*entrySize), engine.WithEvictionPolicy(tt.policy))
+			tt.prepare(t, eng)
+
+			require.NoError(t, eng.Set("key_3", "val_3"))
+			_, err := eng.Get(tt.evicted)
+			assert.ErrorIs(t, err, engine.ErrNotFound)
+			assert.Len(t, eng.Dump(), 2)
+			assert.Equal(t, 2*entrySize, eng.UsedMemory())
+		})
+	}
+}
+
+func TestMemEngine_VolatileTTLKeepsPersistentKeys(t *testing.T) {
+	eng := engine.NewMemEngine(0, engine.WithMaxMemory(2*entrySize), engine.WithEvictionPolicy(engine.VolatileTTL))
+	require.NoError(t, eng.Set("key_1", "val_1"))
+	require.NoError(t, eng.Set("key_2", "val_2"))
+
+	assert.ErrorIs(t, eng.Set("key_3", "val_3"), engine.ErrOutOfMemory)
+}
+
+func TestMemEngine_RandomEviction(t *testing.T) {
+	eng := engine.NewMemEngine(0, engine.WithMaxMemory(10*entrySize), engine.WithEvictionPolicy(engine.Random))
+	for i := range 100 {
+		require.NoError(t, eng.Set(fmt.Sprintf("key%02d", i), fmt.Sprintf("val%02d", i)))
+	}
+
+	assert.Len(t, eng.Dump(), 10)
+	_, err := eng.Get("key99")
+	assert.NoError(t, err, "the written key is never evicted")
+}
+
+func TestMemEngine_EntryLargerThanMaxMemory(t *testing.T) {
+	eng := engine.NewMemEngine(0, engine.WithMaxMemory(entrySize), engine.WithEvictionPolicy(engine.AllKeysLRU))
+	require.NoError(t, eng.Set("key_1", "val_1"))
+
+	assert.ErrorIs(t, eng.Set("key_2", "a very long value"), engine.ErrOutOfMemory)
+	assert.Len(t, eng.Dump(), 1, "nothing is evicted for the entry that never fits")
+}
+
+func TestMemEngine_MSetNoEviction(t *testing.T) {
+	eng := engine.NewMemEngine(0, engine.WithMaxMemory(2*entrySize))
+	require.NoError(t, eng.Set("key_1", "val_1"))
+
+	err := eng.MSet([]engine.Entry{{Key: "key_2", Value: "val_2"}, {Key: "key_3", Value: "val_3"}})
+	assert.ErrorIs(t, err, engine.ErrOutOfMemory)
+	assert.Len(t, eng.Dump(), 1, "nothing is set if the batch does not fit")
+
+	require.NoError(t, eng.MSet([]engine.Entry{{Key: "key_1", Value: "new_1"}, {Key: "key_2", Value: "val_2"}}))
+	assert.Equal(t, 2*entrySize, eng.UsedMemory())
+}
+
+func TestMemEngine_MSetEviction(t *testing.T) {
+	eng := engine.NewMemEngine(0, engine.WithMaxMemory(3*entrySize), engine.WithEvictionPolicy(engine.AllKeysLRU))
+	require.NoError(t, eng.Set("key_1", "val_1"))
+	require.NoError(t, eng.Set("key_2", "val_2"))
+
+	require.NoError(t, eng.MSet([]engine.Entry{{Key: "key_2", Value: "new_2"}, {Key: "key_3", Value: "val_3"}, {Key: "key_4", Value: "val_4"}}))
+	values, err := eng.MGet([]string{"key_1", "key_2", "key_3", "key_4"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "new_2", "val_3", "val_4"}, values, "the keys of the batch are never evicted")
+}
+
+func TestPartitionedEngine_MaxMemory(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewPartitionedEngine(4, 0, engine.WithMaxMemory(40*entrySize), engine.WithEvictionPolicy(engine.AllKeysLRU))
+	for i := range 100 {
+		require.NoError(t, eng.Set(fmt.Sprintf("key%02d", i), fmt.Sprintf("val%02d", i)))
+	}
+
+	assert.LessOrEqual(t, eng.UsedMemory(), 40*entrySize)
+}
+
+func TestPartitionedEngine_SharedMaxMemory(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewPartitionedEngine(4, 0, engine.WithMaxMemory(4*entrySize), engine.WithEvictionPolicy(engine.AllKeysLRU))
+	require.NoError(t, eng.Set("key", strings.Repeat("v", 2*entrySize)), "an entry larger than a partition share must fit")
+	assert.Equal(t, 4*entrySize, eng.Stats().MaxMemory)
+
+	require.NoError(t, eng.Restore([]engine.Entry{{Key: "key_1", Value: "val_1"}, {Key: "key_2", Value: "val_2"}}))
+	assert.Equal(t, 2*entrySize, eng.UsedMemory(), "the replaced entries must release the shared memory")
+}
+
+func TestMemEngine_Evicted(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+
+	eng := engine.NewMemEngine(0, engine.WithMaxMemory(2*entrySize), engine.WithEvictionPolicy(engine.VolatileTTL))
+	assert.Empty(t, eng.Evicted())
+
+	require.NoError(t, eng.Set("key_1", "val_1"))
+	require.NoError(t, eng.SetWithDeadline("key_2", "val_2", deadline))
+	require.NoError(t, eng.Set("key_3", "val_3"))
+
+	assert.Equal(t, []engine.Entry{{Key: "key_2", Value: "val_2", ExpiresAt: deadline.UnixNano()}}, eng.Evicted())
+	assert.Empty(t, eng.Evicted(), "the evicted entries are taken once")
+
+	assert.ErrorIs(t, eng.Set("key_4", "val_4"), engine.ErrOutOfMemory)
+	assert.Empty(t, eng.Evicted())
+}
+
+func TestMemEngine_RestoreOverMaxMemory(t *testing.T) {
+	entries := []engine.Entry{
+		{Key: "key_1", Value: "val_1"},
+		{Key: "key_2", Value: "val_2"},
+		{Key: "key_3", Value: "val_3"},
+	}
+
+	t.Run("Entries are evicted by policy", func(t *testing.T) {
+		eng := engine.NewMemEngine(0, engine.WithMaxMemory(2*entrySize), engine.WithEvictionPolicy(engine.AllKeysLRU))
+		require.NoError(t, eng.Restore(entries))
+
+		assert.Equal(t, 2, eng.Len())
+		assert.Equal(t, 2*entrySize, eng.UsedMemory())
+		assert.Empty(t, eng.Evicted(), "the restored state is not logged, so are its evictions")
+	})
+
+	t.Run("State is kept if entries do not fit", func(t *testing.T) {
+		eng := engine.NewMemEngine(0, engine.WithMaxMemory(2*entrySize))
+		require.NoError(t, eng.Set("key_4", "val_4"))
+
+		assert.ErrorIs(t, eng.Restore(entries), engine.ErrOutOfMemory)
+		assert.Equal(t, []engine.Entry{{Key: "key_4", Value: "val_4"}}, eng.Dump())
+		assert.Equal(t, entrySize, eng.UsedMemory())
+	})
+}
+
+func TestPartitionedEngine_RestoreOverMaxMemory(t *testing.T) {
+	eng := engine.NewPartitionedEngine(4, 0, engine.WithMaxMemory(8*entrySize))
+	require.NoError(t, eng.Set("key99", "val99"))
+
+	entries := make([]engine.Entry, 0, 20)
+	for i := range 20 {
+		entries = append(entries, engine.Entry{Key: fmt.Sprintf("key%02d", i), Value: fmt.Sprintf("val%02d", i)})
+	}
+	assert.ErrorIs(t, eng.Restore(entries), engine.ErrOutOfMemory)
+	assert.Equal(t, []engine.Entry{{Key: "key99", Value: "val99"}}, eng.Dump(), "no partition is restored if one does not fit")
+}
+
+func TestPartitionedEngine_Evicted(t *testing.T) {
+	eng := engine.NewPartitionedEngine(4, 0, engine.WithMaxMemory(40*entrySize), engine.WithEvictionPolicy(engine.AllKeysLRU))
+	for i := range 100 {
+		require.NoError(t, eng.Set(fmt.Sprintf("key%02d", i), fmt.Sprintf("val%02d", i)))
+	}
+
+	evicted := eng.Evicted()
+	assert.Equal(t, 100, eng.Len()+len(evicted))
+	for _, entry := range evicted {
+		_, err := eng.Get(entry.Key)
+		assert.ErrorIs(t, err, engine.ErrNotFound)
+	}
+	assert.Empty(t, eng.Evicted())
+}
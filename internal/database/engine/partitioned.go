@@ -0,0 +1,342 @@
+package engine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PartitionedEngine defines a key-value data store split into partitions.
+//
+// Every key belongs to one partition, so operations on keys
+// of different partitions do not contend on the same lock.
+type PartitionedEngine struct {
+	partitions []*MemEngine
+	// sweeps counts the DeleteExpired calls to start them from the partitions in turn.
+	sweeps atomic.Uint64
+}
+
+// NewPartitionedEngine creates a new PartitionedEngine with the given number of partitions.
+//
+// The options are applied to every partition, the capacity is split between them evenly.
+// The partitions share the max memory, each of them evicts its own keys once the entries
+// of all of them reach the limit, so a partition with no keys to evict rejects the writes.
+func NewPartitionedEngine(partitionsNumber int, cap int, options ...Option) *PartitionedEngine {
+	if partitionsNumber <= 0 {
+		partitionsNumber = 1
+	}
+
+	partitions := make([]*MemEngine, partitionsNumber)
+	for i := range partitions {
+		partitions[i] = NewMemEngine((cap+partitionsNumber-1)/partitionsNumber, options...)
+		partitions[i].memory = partitions[0].memory
+	}
+
+	return &PartitionedEngine{
+		partitions: partitions,
+	}
+}
+
+// Set sets a new key-value pair.
+func (e *PartitionedEngine) Set(k, v string) error {
+	return e.partition(k).Set(k, v)
+}
+
+// Get finds and returns a value by key.
+func (e *PartitionedEngine) Get(k string) (string, error) {
+	return e.partition(k).Get(k)
+}
+
+// Del deletes the value by key.
+func (e *PartitionedEngine) Del(k string) error {
+	return e.partition(k).Del(k)
+}
+
+// SetWithDeadline sets a new key-value pair that expires at the deadline.
+func (e *PartitionedEngine) SetWithDeadline(k, v string, deadline time.Time) error {
+	return e.partition(k).SetWithDeadline(k, v, deadline)
+}
+
+// Expire sets the deadline of the key, the key is deleted if the deadline has passed.
+func (e *PartitionedEngine) Expire(k string, deadline time.Time) (bool, error) {
+	return e.partition(k).Expire(k, deadline)
+}
+
+// Persist removes the deadline of the key.
+func (e *PartitionedEngine) Persist(k string) (bool, error) {
+	return e.partition(k).Persist(k)
+}
+
+// Deadline returns the time the key expires at, zero time if the key never expires.
+func (e *PartitionedEngine) Deadline(k string) (time.Time, error) {
+	return e.partition(k).Deadline(k)
+}
+
+// CompareAndSet sets the value of the key if check accepts its current value.
+func (e *PartitionedEngine) CompareAndSet(k, v string, deadline time.Time, check func(current string) bool) (string, bool, error) {
+	return e.partition(k).CompareAndSet(k, v, deadline, check)
+}
+
+// IncrBy adds the delta to the integer value of the key, a missing key is 0.
+func (e *PartitionedEngine) IncrBy(k string, delta int64) (Entry, error) {
+	return e.partition(k).IncrBy(k, delta)
+}
+
+// IncrByFloat adds the delta to the float value of the key, a missing key is 0.
+func (e *PartitionedEngine) IncrByFloat(k string, delta float64) (Entry, error) {
+	return e.partition(k).IncrByFloat(k, delta)
+}
+
+// MSet sets the key-value pairs that never expire, either all of them or none.
+//
+// The partitions of the keys are locked together, so the pairs are seen at once.
+func (e *PartitionedEngine) MSet(entries []Entry) error {
+	if err := validateEntries(entries); err != nil {
+		return err
+	}
+
+	partitioned := make([][]Entry, len(e.partitions))
+	for _, entry := range entries {
+		i := e.index(entry.Key)
+		partitioned[i] = append(partitioned[i], entry)
+	}
+
+	locked := e.lock(func(i int) bool { return len(partitioned[i]) != 0 })
+	defer unlock(locked)
+
+	batches := make([]map[string]string, len(e.partitions))
+	reserved := 0
+	for i, p := range e.partitions {
+		if len(partitioned[i]) == 0 {
+			continue
+		}
+
+		batch, size, err := p.reserveAll(partitioned[i], reserved)
+		if err != nil {
+			return err
+		}
+		batches[i] = batch
+		reserved += max(size, 0)
+	}
+	for i, batch := range batches {
+		e.partitions[i].storeAll(batch)
+	}
+	return nil
+}
+
+// MGet returns the values of the keys in order, the value of a missing key is empty
+// as the stored values are never empty.
+func (e *PartitionedEngine) MGet(keys []string) ([]string, error) {
+	if err := validateKeys(keys); err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixNano()
+
+	positions := e.positions(keys)
+	locked := e.rlock(func(i int) bool { return len(positions[i]) != 0 })
+	defer runlock(locked)
+
+	values := make([]string, len(keys))
+	for i, p := range e.partitions {
+		for _, j := range positions[i] {
+			values[j], _ = p.get(keys[j], now)
+		}
+	}
+	return values, nil
+}
+
+// MDel deletes the values by keys.
+func (e *PartitionedEngine) MDel(keys []string) error {
+	if err := validateKeys(keys); err != nil {
+		return err
+	}
+
+	positions := e.positions(keys)
+	locked := e.lock(func(i int) bool { return len(positions[i]) != 0 })
+	defer unlock(locked)
+
+	for i, p := range e.partitions {
+		for _, j := range positions[i] {
+			p.delete(keys[j])
+		}
+	}
+	return nil
+}
+
+// DeleteExpired checks up to limit keys with a deadline and deletes the expired ones.
+//
+// The limit is split between the partitions evenly, so the share of the expired keys among
+// the checked ones means the same as with a single partition. The remainder goes to
+// the partitions in turn, so all of them are swept even if the limit is lower than their number.
+func (e *PartitionedEngine) DeleteExpired(limit int) int {
+	n := len(e.partitions)
+	start := int(e.sweeps.Add(1) % uint64(n))
+
+	deleted := 0
+	for i := range n {
+		share := limit / n
+		if i < limit%n {
+			share++
+		}
+		if share == 0 {
+			break
+		}
+		deleted += e.partitions[(start+i)%n].DeleteExpired(share)
+	}
+	return deleted
+}
+
+// Dump returns a copy of all key-value pairs that are not expired.
+func (e *PartitionedEngine) Dump() []Entry {
+	var entries []Entry
+	for _, p := range e.partitions {
+		entries = append(entries, p.Dump()...)
+	}
+	return entries
+}
+
+// Restore replaces all key-value pairs with the entries, either in all partitions or in none.
+//
+// The entries over the max memory are evicted by the policy from the partitions restored last,
+// ErrOutOfMemory is returned if they can't be evicted.
+func (e *PartitionedEngine) Restore(entries []Entry) error {
+	now := time.Now().UnixNano()
+
+	partitioned := make([][]Entry, len(e.partitions))
+	for _, entry := range entries {
+		i := e.index(entry.Key)
+		partitioned[i] = append(partitioned[i], entry)
+	}
+
+	locked := e.lock(func(int) bool { return true })
+	defer unlock(locked)
+
+	// All the partitions are emptied first, so the replaced entries hold none of the shared memory.
+	replaced := make([]memState, len(e.partitions))
+	for i, p := range e.partitions {
+		replaced[i] = p.detach()
+	}
+	for i, p := range e.partitions {
+		if err := p.fill(partitioned[i], now); err != nil {
+			for j, p := range e.partitions {
+				p.reset(replaced[j])
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Evicted returns the entries evicted from all partitions since the last call.
+func (e *PartitionedEngine) Evicted() []Entry {
+	var evicted []Entry
+	for _, p := range e.partitions {
+		evicted = append(evicted, p.Evicted()...)
+	}
+	return evicted
+}
+
+// UsedMemory returns the approximate number of bytes held by the entries.
+func (e *PartitionedEngine) UsedMemory() int {
+	used := 0
+	for _, p := range e.partitions {
+		used += p.UsedMemory()
+	}
+	return used
+}
+
+// Len returns the number of the keys, including the expired ones that are not removed yet.
+func (e *PartitionedEngine) Len() int {
+	n := 0
+	for _, p := range e.partitions {
+		n += p.Len()
+	}
+	return n
+}
+
+// Stats returns the current state of the engine summed over the partitions, they share the max memory.
+func (e *PartitionedEngine) Stats() Stats {
+	var stats Stats
+	for _, p := range e.partitions {
+		ps := p.Stats()
+		stats.Keys += ps.Keys
+		stats.ExpiringKeys += ps.ExpiringKeys
+		stats.UsedMemory += ps.UsedMemory
+		stats.MaxMemory = ps.MaxMemory
+		stats.EvictionPolicy = ps.EvictionPolicy
+	}
+	return stats
+}
+
+// PartitionsNumber returns the number of partitions.
+func (e *PartitionedEngine) PartitionsNumber() int {
+	return len(e.partitions)
+}
+
+func (e *PartitionedEngine) partition(k string) *MemEngine {
+	return e.partitions[e.index(k)]
+}
+
+func (e *PartitionedEngine) index(k string) int {
+	return int(hash(k) % uint32(len(e.partitions)))
+}
+
+// hash returns the 32-bit FNV-1a hash of the key.
+func hash(k string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	h := uint32(offset32)
+	for i := 0; i < len(k); i++ {
+		h ^= uint32(k[i])
+		h *= prime32
+	}
+	return h
+}
+
+// positions returns the positions of the keys by their partitions.
+func (e *PartitionedEngine) positions(keys []string) [][]int {
+	positions := make([][]int, len(e.partitions))
+	for j, k := range keys {
+		i := e.index(k)
+		positions[i] = append(positions[i], j)
+	}
+	return positions
+}
+
+// lock locks the chosen partitions in order, so the batches never deadlock.
+func (e *PartitionedEngine) lock(chosen func(i int) bool) []*MemEngine {
+	var locked []*MemEngine
+	for i, p := range e.partitions {
+		if chosen(i) {
+			p.mtx.Lock()
+			locked = append(locked, p)
+		}
+	}
+	return locked
+}
+
+// rlock locks the chosen partitions for reading in order.
+func (e *PartitionedEngine) rlock(chosen func(i int) bool) []*MemEngine {
+	var locked []*MemEngine
+	for i, p := range e.partitions {
+		if chosen(i) {
+			p.mtx.RLock()
+			locked = append(locked, p)
+		}
+	}
+	return locked
+}
+
+func unlock(partitions []*MemEngine) {
+	for _, p := range partitions {
+		p.mtx.Unlock()
+	}
+}
+
+func runlock(partitions []*MemEngine) {
+	for _, p := range partitions {
+		p.mtx.RUnlock()
+	}
+}
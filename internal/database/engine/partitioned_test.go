@@ -0,0 +1,297 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPartitionedEngine(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		partitionsNumber int
+		want             int
+	}{
+		"negative partitions number": {
+			partitionsNumber: -1,
+			want:             1,
+		},
+		"zero partitions number": {
+			partitionsNumber: 0,
+			want:             1,
+		},
+		"valid partitions number": {
+			partitionsNumber: 8,
+			want:             8,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			eng := engine.NewPartitionedEngine(test.partitionsNumber, 0)
+			assert.Equal(t, test.want, eng.PartitionsNumber())
+		})
+	}
+}
+
+func TestPartitionedEngine(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewPartitionedEngine(8, 256)
+
+	assert.ErrorIs(t, eng.Set("", "val"), engine.ErrInvalidEntityID)
+	assert.ErrorIs(t, eng.Set("key", ""), engine.ErrInvalidEntityData)
+	_, err := eng.Get("")
+	assert.ErrorIs(t, err, engine.ErrInvalidEntityID)
+	assert.ErrorIs(t, eng.Del(""), engine.ErrInvalidEntityID)
+
+	for i := range 100 {
+		require.NoError(t, eng.Set(fmt.Sprintf("key_%d", i), fmt.Sprintf("val_%d", i)))
+	}
+	for i := range 100 {
+		got, err := eng.Get(fmt.Sprintf("key_%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("val_%d", i), got)
+	}
+
+	require.NoError(t, eng.Del("key_1"))
+	_, err = eng.Get("key_1")
+	assert.ErrorIs(t, err, engine.ErrNotFound)
+}
+
+func TestPartitionedEngine_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	const workers = 16
+
+	eng := engine.NewPartitionedEngine(4, 0)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := range workers {
+		go func() {
+			defer wg.Done()
+
+			for i := range 100 {
+				key := fmt.Sprintf("key_%d_%d", w, i)
+				assert.NoError(t, eng.Set(key, "val"))
+				_, err := eng.Get(key)
+				assert.NoError(t, err)
+				assert.NoError(t, eng.Del(key))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPartitionedEngine_ConcurrentIncrBy(t *testing.T) {
+	t.Parallel()
+
+	const workers = 16
+
+	eng := engine.NewPartitionedEngine(4, 0)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+
+			for range 100 {
+				_, err := eng.IncrBy("counter", 1)
+				assert.NoError(t, err)
+				_, err = eng.IncrByFloat("float_counter", 0.5)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, err := eng.Get("counter")
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(workers*100), v)
+	v, err = eng.Get("float_counter")
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(workers*50), v)
+}
+
+func TestPartitionedEngine_ConcurrentCompareAndSet(t *testing.T) {
+	t.Parallel()
+
+	const workers = 16
+
+	eng := engine.NewPartitionedEngine(4, 0)
+	require.NoError(t, eng.Set("counter", "0"))
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+
+			for swapped := 0; swapped < 100; {
+				v, err := eng.Get("counter")
+				if !assert.NoError(t, err) {
+					return
+				}
+				n, _ := strconv.Atoi(v)
+
+				_, set, err := eng.CompareAndSet("counter", strconv.Itoa(n+1), time.Time{}, func(current string) bool {
+					return current == v
+				})
+				if !assert.NoError(t, err) {
+					return
+				}
+				if set {
+					swapped++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, err := eng.Get("counter")
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(workers*100), v, "every swap is applied exactly once")
+}
+
+func TestPartitionedEngine_Batch(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewPartitionedEngine(4, 0)
+
+	var (
+		entries []engine.Entry
+		keys    []string
+		want    []string
+	)
+	for i := range 20 {
+		entries = append(entries, engine.Entry{Key: fmt.Sprintf("key_%d", i), Value: fmt.Sprintf("val_%d", i)})
+		keys = append(keys, fmt.Sprintf("key_%d", i))
+		want = append(want, fmt.Sprintf("val_%d", i))
+	}
+	require.NoError(t, eng.MSet(entries))
+
+	got, err := eng.MGet(append(keys, "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, append(want, ""), got)
+
+	require.NoError(t, eng.MDel(keys[:10]))
+	assert.Equal(t, 10, eng.Len())
+	got, err = eng.MGet(keys[5:15])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "", "", "", "", "val_10", "val_11", "val_12", "val_13", "val_14"}, got)
+}
+
+func TestPartitionedEngine_MSetIsAtomic(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewPartitionedEngine(4, 0)
+	keys := []string{"key_1", "key_2", "key_3", "key_4", "key_5", "key_6"}
+	require.NoError(t, eng.MSet(batch(keys, "0")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 1; ctx.Err() == nil; i++ {
+			assert.NoError(t, eng.MSet(batch(keys, strconv.Itoa(i))))
+		}
+	}()
+
+	for range 1000 {
+		values, err := eng.MGet(keys)
+		require.NoError(t, err)
+		for _, v := range values {
+			require.Equal(t, values[0], v, "MGET never sees a partial MSET")
+		}
+	}
+	cancel()
+	wg.Wait()
+}
+
+// batch returns the entries of the keys with the same value.
+func batch(keys []string, value string) []engine.Entry {
+	entries := make([]engine.Entry, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, engine.Entry{Key: k, Value: value})
+	}
+	return entries
+}
+
+func TestPartitionedEngine_DumpAndRestore(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewPartitionedEngine(4, 0)
+
+	var want []engine.Entry
+	for i := range 20 {
+		entry := engine.Entry{Key: fmt.Sprintf("key_%d", i), Value: fmt.Sprintf("val_%d", i)}
+		require.NoError(t, eng.Set(entry.Key, entry.Value))
+		want = append(want, entry)
+	}
+	assert.ElementsMatch(t, want, eng.Dump())
+
+	restored := engine.NewPartitionedEngine(4, 0)
+	require.NoError(t, restored.Restore(want))
+	assert.Equal(t, len(want), restored.Len())
+	for _, entry := range want {
+		got, err := restored.Get(entry.Key)
+		require.NoError(t, err)
+		assert.Equal(t, entry.Value, got)
+	}
+}
+
+func TestPartitionedEngine_DeleteExpired(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewPartitionedEngine(4, 0)
+	for i := range 40 {
+		require.NoError(t, eng.SetWithDeadline(fmt.Sprintf("key_%d", i), "val", time.Now().Add(10*time.Millisecond)))
+	}
+	require.NoError(t, eng.Set("key", "val"))
+
+	time.Sleep(20 * time.Millisecond)
+
+	// The limit is shared by the partitions, so the sweep checks no more keys than a single engine.
+	deleted := eng.DeleteExpired(8)
+	assert.LessOrEqual(t, deleted, 8)
+
+	// The limit lower than the number of partitions still reaches all of them in turn.
+	for range 4 * 40 {
+		n := eng.DeleteExpired(1)
+		assert.LessOrEqual(t, n, 1)
+		deleted += n
+	}
+	assert.Equal(t, 40, deleted)
+	assert.Equal(t, []engine.Entry{{Key: "key", Value: "val"}}, eng.Dump())
+}
+
+func TestPartitionedEngine_Stats(t *testing.T) {
+	t.Parallel()
+
+	eng := engine.NewPartitionedEngine(4, 0, engine.WithMaxMemory(1<<20), engine.WithEvictionPolicy(engine.AllKeysLRU))
+	for i := range 10 {
+		require.NoError(t, eng.Set(fmt.Sprintf("key_%d", i), "val"))
+	}
+	require.NoError(t, eng.SetWithDeadline("expiring", "val", time.Now().Add(time.Minute)))
+
+	stats := eng.Stats()
+	assert.Equal(t, 11, stats.Keys)
+	assert.Equal(t, 1, stats.ExpiringKeys)
+	assert.Positive(t, stats.UsedMemory)
+	assert.Equal(t, 1<<20, stats.MaxMemory)
+	assert.Equal(t, engine.AllKeysLRU, stats.EvictionPolicy)
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/alukart32/go-fast-key/internal/auth"
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+)
+
+// ErrorCode defines the stable code of the error the clients may rely on,
+// unlike the error message.
+type ErrorCode string
+
+const (
+	// GenericCode is the code of the errors without a more specific code.
+	GenericCode        ErrorCode = "ERR"
+	SyntaxCode         ErrorCode = "SYNTAX"
+	UnknownCommandCode ErrorCode = "UNKNOWNCMD"
+	InvalidCode        ErrorCode = "INVALID"
+	NotFoundCode       ErrorCode = "NOTFOUND"
+	OutOfMemoryCode    ErrorCode = "OOM"
+	ReadOnlyCode       ErrorCode = "READONLY"
+	DisabledCode       ErrorCode = "DISABLED"
+	IOCode             ErrorCode = "IOERR"
+	NoAuthCode         ErrorCode = "NOAUTH"
+	WrongPassCode      ErrorCode = "WRONGPASS"
+	NoPermCode         ErrorCode = "NOPERM"
+	ExecAbortCode      ErrorCode = "EXECABORT"
+	OverflowCode       ErrorCode = "OVERFLOW"
+)
+
+// errorCodes defines the codes of the sentinel errors, the first matching one is used.
+var errorCodes = []struct {
+	err  error
+	code ErrorCode
+}{
+	{err: compute.ErrEmptyRequest, code: SyntaxCode},
+	{err: compute.ErrInvalidArgsNumber, code: SyntaxCode},
+	{err: compute.ErrInvalidOption, code: SyntaxCode},
+	{err: compute.ErrUnterminatedQuote, code: SyntaxCode},
+	{err: compute.ErrInvalidEscape, code: SyntaxCode},
+	{err: compute.ErrInvalidRawLength, code: SyntaxCode},
+	{err: compute.ErrMissingSeparator, code: SyntaxCode},
+	{err: compute.ErrUnknownCommand, code: UnknownCommandCode},
+
+	{err: engine.ErrInvalidEntityID, code: InvalidCode},
+	{err: engine.ErrInvalidEntityData, code: InvalidCode},
+	{err: engine.ErrNotFound, code: NotFoundCode},
+	{err: engine.ErrOutOfMemory, code: OutOfMemoryCode},
+	{err: engine.ErrNotInteger, code: InvalidCode},
+	{err: engine.ErrNotFloat, code: InvalidCode},
+	{err: engine.ErrOverflow, code: OverflowCode},
+
+	{err: ErrInvalidExpireTime, code: InvalidCode},
+	{err: ErrUnknownInfoSection, code: InvalidCode},
+	{err: ErrReadOnly, code: ReadOnlyCode},
+	{err: ErrWALDisabled, code: DisabledCode},
+	{err: ErrAuthDisabled, code: DisabledCode},
+	{err: ErrSnapshotsDisabled, code: DisabledCode},
+	{err: ErrNotLogged, code: IOCode},
+	{err: ErrSnapshotFailed, code: IOCode},
+	{err: ErrExecAborted, code: ExecAbortCode},
+	{err: ErrWatchedKeyChanged, code: ExecAbortCode},
+	{err: ErrNoAuth, code: NoAuthCode},
+
+	{err: auth.ErrInvalidCredentials, code: WrongPassCode},
+	{err: auth.ErrTooManyAttempts, code: WrongPassCode},
+	{err: auth.ErrNoPermission, code: NoPermCode},
+	{err: auth.ErrInvalidRule, code: InvalidCode},
+	{err: auth.ErrUserNotFound, code: NotFoundCode},
+}
+
+// ErrorCodeOf returns the code of the error, GenericCode for the errors without a code.
+func ErrorCodeOf(err error) ErrorCode {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return GenericCode
+}
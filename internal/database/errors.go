@@ -3,5 +3,29 @@ package database
 import "errors"
 
 var (
-	ErrStandBy = errors.New("stand-by")
+	ErrStandBy       = errors.New("stand-by")
+	ErrNotLogged     = errors.New("mutation is not logged")
+	ErrInvalidRecord = errors.New("invalid wal record")
+	ErrWALDisabled   = errors.New("wal is disabled")
+	ErrReadOnly      = errors.New("read-only replica")
+
+	ErrInvalidExpireTime = errors.New("invalid expire time")
+
+	ErrNoAuth       = errors.New("authentication required")
+	ErrAuthDisabled = errors.New("authentication is disabled")
+
+	ErrNestedMulti         = errors.New("MULTI calls can not be nested")
+	ErrExecWithoutMulti    = errors.New("EXEC without MULTI")
+	ErrDiscardWithoutMulti = errors.New("DISCARD without MULTI")
+	ErrWatchInMulti        = errors.New("WATCH inside MULTI is not allowed")
+	ErrNotInTransaction    = errors.New("command is not allowed in transaction")
+	ErrExecAborted         = errors.New("transaction discarded because of previous errors")
+	ErrWatchedKeyChanged   = errors.New("transaction aborted: watched key changed")
+
+	ErrSnapshotsDisabled = errors.New("snapshots are disabled")
+	ErrSnapshotFailed    = errors.New("snapshot is failed")
+
+	ErrUnknownInfoSection = errors.New("unknown info section")
+
+	ErrInvalidResponse = errors.New("invalid response")
 )
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+)
+
+// The replication roles reported by INFO.
+const (
+	masterRole = "master"
+	slaveRole  = "slave"
+)
+
+// ListenerStats defines the state of the network listener reported by INFO.
+type ListenerStats struct {
+	Address             string
+	Protocol            string
+	Connections         int
+	RejectedConnections uint64
+	BytesRead           uint64
+	BytesWritten        uint64
+}
+
+// Listener describes the network listener of the clients.
+type Listener interface {
+	Stats() ListenerStats
+}
+
+// ReplicationStats defines the state of the replication link reported by INFO.
+type ReplicationStats struct {
+	// Role is either master or slave.
+	Role          string
+	MasterAddress string
+	// Slaves is the number of the slaves connected to the master.
+	Slaves int
+	// Connected and LastSync describe the link of the slave to the master.
+	Connected bool
+	LastSync  time.Time
+}
+
+// Replication describes the replication link of the database.
+type Replication interface {
+	Stats() ReplicationStats
+}
+
+// ServerInfo defines the server process reported by INFO.
+type ServerInfo struct {
+	Version   string
+	StartedAt time.Time
+	// Settings summarize the server config.
+	Settings []Setting
+}
+
+// Setting defines the config setting reported by INFO.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// infoSections defines the INFO sections in their order.
+var infoSections = []struct {
+	name   string
+	title  string
+	fields func(db *Database) ([]Setting, error)
+}{
+	{name: "server", title: "Server", fields: (*Database).serverInfo},
+	{name: "clients", title: "Clients", fields: (*Database).clientsInfo},
+	{name: "memory", title: "Memory", fields: (*Database).memoryInfo},
+	{name: "stats", title: "Stats", fields: (*Database).statsInfo},
+	{name: "replication", title: "Replication", fields: (*Database).replicationInfo},
+	{name: "wal", title: "WAL", fields: (*Database).walInfo},
+	{name: "keyspace", title: "Keyspace", fields: (*Database).keyspaceInfo},
+}
+
+// doInfo returns the fields of the requested section or of all the sections, one field per line.
+func (db *Database) doInfo(q compute.Query) (Response, error) {
+	section := "all"
+	if args := q.Arguments(); len(args) != 0 {
+		section = strings.ToLower(args[0])
+	}
+
+	var sections []string
+	for _, s := range infoSections {
+		if section != "all" && section != s.name {
+			continue
+		}
+
+		fields, err := s.fields(db)
+		if err != nil {
+			return Response{}, err
+		}
+
+		lines := []string{"# " + s.title}
+		for _, f := range fields {
+			lines = append(lines, f.Name+":"+f.Value)
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if len(sections) == 0 {
+		return Response{}, ErrUnknownInfoSection
+	}
+	return Value(strings.Join(sections, "\n\n")), nil
+}
+
+func (db *Database) serverInfo() ([]Setting, error) {
+	version := db.info.Version
+	if version == "" {
+		version = "unknown"
+	}
+
+	fields := []Setting{
+		{Name: "version", Value: version},
+		{Name: "uptime_in_seconds", Value: strconv.FormatInt(int64(time.Since(db.info.StartedAt).Seconds()), 10)},
+	}
+	return append(fields, db.info.Settings...), nil
+}
+
+func (db *Database) clientsInfo() ([]Setting, error) {
+	var (
+		connected int
+		listeners []Setting
+	)
+	for i, l := range db.listeners {
+		stats := l.Stats()
+		connected += stats.Connections
+		listeners = append(listeners, Setting{
+			Name:  "listener" + strconv.Itoa(i),
+			Value: fmt.Sprintf("address=%s,protocol=%s,connections=%d", stats.Address, stats.Protocol, stats.Connections),
+		})
+	}
+
+	return append([]Setting{{Name: "connected_clients", Value: strconv.Itoa(connected)}}, listeners...), nil
+}
+
+func (db *Database) memoryInfo() ([]Setting, error) {
+	stats := db.e.Stats()
+	return []Setting{
+		{Name: "used_memory", Value: strconv.Itoa(stats.UsedMemory)},
+		{Name: "maxmemory", Value: strconv.Itoa(stats.MaxMemory)},
+		{Name: "maxmemory_policy", Value: string(stats.EvictionPolicy)},
+	}, nil
+}
+
+func (db *Database) statsInfo() ([]Setting, error) {
+	var rejected, read, written uint64
+	for _, l := range db.listeners {
+		stats := l.Stats()
+		rejected += stats.RejectedConnections
+		read += stats.BytesRead
+		written += stats.BytesWritten
+	}
+
+	return []Setting{
+		{Name: "total_commands_processed", Value: strconv.FormatUint(db.processed.Load(), 10)},
+		{Name: "rejected_connections", Value: strconv.FormatUint(rejected, 10)},
+		{Name: "total_net_input_bytes", Value: strconv.FormatUint(read, 10)},
+		{Name: "total_net_output_bytes", Value: strconv.FormatUint(written, 10)},
+	}, nil
+}
+
+func (db *Database) replicationInfo() ([]Setting, error) {
+	if db.replication == nil {
+		return []Setting{{Name: "role", Value: "standalone"}}, nil
+	}
+
+	stats := db.replication.Stats()
+	fields := []Setting{
+		{Name: "role", Value: stats.Role},
+		{Name: "master_address", Value: stats.MasterAddress},
+	}
+	switch stats.Role {
+	case masterRole:
+		fields = append(fields, Setting{Name: "connected_slaves", Value: strconv.Itoa(stats.Slaves)})
+	case slaveRole:
+		status := "down"
+		if stats.Connected {
+			status = "up"
+		}
+		fields = append(fields,
+			Setting{Name: "master_link_status", Value: status},
+			Setting{Name: "master_last_sync_seconds_ago", Value: secondsAgo(stats.LastSync)},
+		)
+	}
+	return append(fields, Setting{Name: "lsn", Value: strconv.FormatUint(db.LSN(), 10)}), nil
+}
+
+func (db *Database) walInfo() ([]Setting, error) {
+	if db.w == nil {
+		return []Setting{{Name: "wal_enabled", Value: "0"}}, nil
+	}
+
+	stats, err := db.w.Stats()
+	if err != nil {
+		return nil, fmt.Errorf("wal stats: %w", err)
+	}
+
+	status := "ok"
+	if stats.LastFlushErr != nil {
+		status = "err"
+	}
+	return []Setting{
+		{Name: "wal_enabled", Value: "1"},
+		{Name: "lsn", Value: strconv.FormatUint(stats.LSN, 10)},
+		{Name: "segments", Value: strconv.Itoa(stats.Segments)},
+		{Name: "pending_records", Value: strconv.Itoa(stats.PendingRecords)},
+		{Name: "flush_policy", Value: string(stats.FlushPolicy)},
+		{Name: "last_flush_seconds_ago", Value: secondsAgo(stats.LastFlush)},
+		{Name: "last_flush_status", Value: status},
+	}, nil
+}
+
+func (db *Database) keyspaceInfo() ([]Setting, error) {
+	stats := db.e.Stats()
+	return []Setting{
+		{Name: "keys", Value: strconv.Itoa(stats.Keys)},
+		{Name: "expires", Value: strconv.Itoa(stats.ExpiringKeys)},
+	}, nil
+}
+
+// secondsAgo returns the whole seconds passed since t, or -1 if t is not set.
+func secondsAgo(t time.Time) string {
+	if t.IsZero() {
+		return "-1"
+	}
+	return strconv.FormatInt(int64(time.Since(t).Seconds()), 10)
+}
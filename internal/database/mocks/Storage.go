@@ -2,13 +2,81 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	engine "github.com/alukart32/go-fast-key/internal/database/engine"
+	time "time"
 
-// Storage is an autogenerated mock type for the Storage type
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Storage is an autogenerated mock type for the Engine type
 type Storage struct {
 	mock.Mock
 }
 
+// CompareAndSet provides a mock function with given fields: k, v, deadline, check
+func (_m *Storage) CompareAndSet(k string, v string, deadline time.Time, check func(string) bool) (string, bool, error) {
+	ret := _m.Called(k, v, deadline, check)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompareAndSet")
+	}
+
+	var r0 string
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string, string, time.Time, func(string) bool) (string, bool, error)); ok {
+		return rf(k, v, deadline, check)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, time.Time, func(string) bool) string); ok {
+		r0 = rf(k, v, deadline, check)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, time.Time, func(string) bool) bool); ok {
+		r1 = rf(k, v, deadline, check)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(string, string, time.Time, func(string) bool) error); ok {
+		r2 = rf(k, v, deadline, check)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Deadline provides a mock function with given fields: k
+func (_m *Storage) Deadline(k string) (time.Time, error) {
+	ret := _m.Called(k)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Deadline")
+	}
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (time.Time, error)); ok {
+		return rf(k)
+	}
+	if rf, ok := ret.Get(0).(func(string) time.Time); ok {
+		r0 = rf(k)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(k)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Del provides a mock function with given fields: k
 func (_m *Storage) Del(k string) error {
 	ret := _m.Called(k)
@@ -27,6 +95,92 @@ func (_m *Storage) Del(k string) error {
 	return r0
 }
 
+// DeleteExpired provides a mock function with given fields: limit
+func (_m *Storage) DeleteExpired(limit int) int {
+	ret := _m.Called(limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpired")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(int) int); ok {
+		r0 = rf(limit)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// Dump provides a mock function with no fields
+func (_m *Storage) Dump() []engine.Entry {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Dump")
+	}
+
+	var r0 []engine.Entry
+	if rf, ok := ret.Get(0).(func() []engine.Entry); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]engine.Entry)
+		}
+	}
+
+	return r0
+}
+
+// Evicted provides a mock function with no fields
+func (_m *Storage) Evicted() []engine.Entry {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Evicted")
+	}
+
+	var r0 []engine.Entry
+	if rf, ok := ret.Get(0).(func() []engine.Entry); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]engine.Entry)
+		}
+	}
+
+	return r0
+}
+
+// Expire provides a mock function with given fields: k, deadline
+func (_m *Storage) Expire(k string, deadline time.Time) (bool, error) {
+	ret := _m.Called(k, deadline)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Expire")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time) (bool, error)); ok {
+		return rf(k, deadline)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time) bool); ok {
+		r0 = rf(k, deadline)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time) error); ok {
+		r1 = rf(k, deadline)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: k
 func (_m *Storage) Get(k string) (string, error) {
 	ret := _m.Called(k)
@@ -55,6 +209,174 @@ func (_m *Storage) Get(k string) (string, error) {
 	return r0, r1
 }
 
+// IncrBy provides a mock function with given fields: k, delta
+func (_m *Storage) IncrBy(k string, delta int64) (engine.Entry, error) {
+	ret := _m.Called(k, delta)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrBy")
+	}
+
+	var r0 engine.Entry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int64) (engine.Entry, error)); ok {
+		return rf(k, delta)
+	}
+	if rf, ok := ret.Get(0).(func(string, int64) engine.Entry); ok {
+		r0 = rf(k, delta)
+	} else {
+		r0 = ret.Get(0).(engine.Entry)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int64) error); ok {
+		r1 = rf(k, delta)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrByFloat provides a mock function with given fields: k, delta
+func (_m *Storage) IncrByFloat(k string, delta float64) (engine.Entry, error) {
+	ret := _m.Called(k, delta)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrByFloat")
+	}
+
+	var r0 engine.Entry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, float64) (engine.Entry, error)); ok {
+		return rf(k, delta)
+	}
+	if rf, ok := ret.Get(0).(func(string, float64) engine.Entry); ok {
+		r0 = rf(k, delta)
+	} else {
+		r0 = ret.Get(0).(engine.Entry)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, float64) error); ok {
+		r1 = rf(k, delta)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MDel provides a mock function with given fields: keys
+func (_m *Storage) MDel(keys []string) error {
+	ret := _m.Called(keys)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MDel")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string) error); ok {
+		r0 = rf(keys)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MGet provides a mock function with given fields: keys
+func (_m *Storage) MGet(keys []string) ([]string, error) {
+	ret := _m.Called(keys)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MGet")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]string) ([]string, error)); ok {
+		return rf(keys)
+	}
+	if rf, ok := ret.Get(0).(func([]string) []string); ok {
+		r0 = rf(keys)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(keys)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MSet provides a mock function with given fields: entries
+func (_m *Storage) MSet(entries []engine.Entry) error {
+	ret := _m.Called(entries)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MSet")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]engine.Entry) error); ok {
+		r0 = rf(entries)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Persist provides a mock function with given fields: k
+func (_m *Storage) Persist(k string) (bool, error) {
+	ret := _m.Called(k)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Persist")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (bool, error)); ok {
+		return rf(k)
+	}
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(k)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(k)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: entries
+func (_m *Storage) Restore(entries []engine.Entry) error {
+	ret := _m.Called(entries)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restore")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]engine.Entry) error); ok {
+		r0 = rf(entries)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Set provides a mock function with given fields: k, v
 func (_m *Storage) Set(k string, v string) error {
 	ret := _m.Called(k, v)
@@ -73,6 +395,42 @@ func (_m *Storage) Set(k string, v string) error {
 	return r0
 }
 
+// SetWithDeadline provides a mock function with given fields: k, v, deadline
+func (_m *Storage) SetWithDeadline(k string, v string, deadline time.Time) error {
+	ret := _m.Called(k, v, deadline)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetWithDeadline")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, time.Time) error); ok {
+		r0 = rf(k, v, deadline)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Stats provides a mock function with no fields
+func (_m *Storage) Stats() engine.Stats {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Stats")
+	}
+
+	var r0 engine.Stats
+	if rf, ok := ret.Get(0).(func() engine.Stats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(engine.Stats)
+	}
+
+	return r0
+}
+
 // NewStorage creates a new instance of Storage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewStorage(t interface {
@@ -0,0 +1,8 @@
+package replication
+
+import "errors"
+
+var (
+	ErrNotAuthenticated = errors.New("replication connection is not authenticated")
+	ErrInvalidSecret    = errors.New("invalid replication secret")
+)
@@ -0,0 +1,151 @@
+package replication
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
+	"go.uber.org/zap"
+)
+
+// SegmentReader describes the source of the replicated log segments.
+type SegmentReader interface {
+	ReadSegment(pos wal.SegmentPosition) (wal.SegmentChunk, error)
+}
+
+// SnapshotReader describes the source of the snapshots the lagging slaves are resynchronized from.
+type SnapshotReader interface {
+	Latest() (snapshot.Snapshot, error)
+}
+
+// slaveUser is the user of the authenticated slave sessions.
+const slaveUser = "replication"
+
+// Master defines the replication master that serves its log segments to slaves.
+//
+// A slave connection is served once it sends the replication secret.
+type Master struct {
+	server    *network.TCPServer
+	segments  SegmentReader
+	snapshots SnapshotReader
+	secret    [sha256.Size]byte
+
+	l *zap.Logger
+}
+
+// NewMaster creates a new Master.
+func NewMaster(server *network.TCPServer, segments SegmentReader, secret string, logger *zap.Logger, options ...MasterOption) (*Master, error) {
+	if server == nil {
+		return nil, errors.New("server is nil")
+	}
+	if segments == nil {
+		return nil, errors.New("segment reader is nil")
+	}
+	if secret == "" {
+		return nil, errors.New("secret is empty")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+
+	master := &Master{
+		server:   server,
+		segments: segments,
+		secret:   sha256.Sum256([]byte(secret)),
+		l:        logger,
+	}
+	for _, option := range options {
+		option(master)
+	}
+	return master, nil
+}
+
+// Stats returns the state of the master, the slaves are the connections to its server.
+func (m *Master) Stats() Stats {
+	return Stats{
+		Role:          MasterRole,
+		MasterAddress: m.server.Address(),
+		Slaves:        m.server.Connections(),
+	}
+}
+
+// Serve handles the slaves requests until ctx is done.
+func (m *Master) Serve(ctx context.Context) {
+	m.server.HandleQueries(ctx, m.handle)
+}
+
+// Close stops listening of the master that is not serving yet.
+func (m *Master) Close() error {
+	return m.server.Close()
+}
+
+func (m *Master) handle(_ context.Context, s *session.Session, data []byte) []byte {
+	var resp response
+
+	req, err := decode[request](data)
+	if err == nil {
+		err = m.authenticate(s, req.Secret)
+	}
+	if err != nil {
+		m.l.Warn("invalid replication request", zap.String("remote_addr", s.RemoteAddr()), zap.Error(err))
+		resp.Error = err.Error()
+	} else {
+		var chunk wal.SegmentChunk
+		chunk, err = m.segments.ReadSegment(wal.SegmentPosition{LSN: req.LSN, FirstLSN: req.FirstLSN, Offset: req.Offset})
+		resp.FirstLSN, resp.Data, resp.Offset, resp.Last = chunk.FirstLSN, chunk.Data, chunk.Offset, chunk.Last
+		if errors.Is(err, wal.ErrSegmentCompacted) && m.snapshots != nil {
+			resp.Snapshot, err = m.resync(s, req.LSN)
+		}
+		if err != nil {
+			m.l.Warn("fail to read segment", zap.Uint64("lsn", req.LSN), zap.Error(err))
+			resp.Error = err.Error()
+		}
+	}
+
+	encoded, err := encode(resp)
+	if err != nil {
+		m.l.Error("fail to encode replication response", zap.Error(err))
+		return nil
+	}
+	return encoded
+}
+
+// resync returns the latest snapshot for the slave that is behind the compacted log,
+// the slave continues from the log records following the snapshot.
+func (m *Master) resync(s *session.Session, lsn uint64) (*snapshot.Snapshot, error) {
+	snap, err := m.snapshots.Latest()
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot: %w", err)
+	}
+
+	m.l.Info("resync slave from snapshot",
+		zap.String("remote_addr", s.RemoteAddr()),
+		zap.Uint64("lsn", lsn),
+		zap.Uint64("snapshot_lsn", snap.LSN),
+	)
+	return &snap, nil
+}
+
+// authenticate authenticates the slave session by the secret, the session stays authenticated.
+func (m *Master) authenticate(s *session.Session, secret string) error {
+	if s.Authenticated() {
+		return nil
+	}
+	if secret == "" {
+		return ErrNotAuthenticated
+	}
+
+	// The hashes have the same length, so the comparison time does not depend on the secret.
+	sum := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare(sum[:], m.secret[:]) != 1 {
+		return ErrInvalidSecret
+	}
+	s.Authenticate(slaveUser)
+	return nil
+}
@@ -0,0 +1,12 @@
+package replication
+
+// MasterOption defines an optional Master setting.
+type MasterOption func(*Master)
+
+// WithSnapshots makes the master resynchronize the slaves that are behind
+// the compacted log from the latest snapshot.
+func WithSnapshots(snapshots SnapshotReader) MasterOption {
+	return func(m *Master) {
+		m.snapshots = snapshots
+	}
+}
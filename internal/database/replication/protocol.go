@@ -0,0 +1,47 @@
+package replication
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+)
+
+// request asks the master for the log records following LSN.
+//
+// FirstLSN and Offset are taken from the previous response, the master continues
+// reading its segment from them.
+type request struct {
+	LSN      uint64
+	FirstLSN uint64
+	Offset   int64
+	// Secret authenticates the connection, it is sent with the first request only.
+	Secret string
+}
+
+// response holds the frames of the master segment with the records following the requested LSN.
+//
+// Data is empty if the slave has caught up with the master.
+type response struct {
+	FirstLSN uint64
+	Data     []byte
+	Offset   int64
+	Last     bool
+	// Snapshot is sent instead of the frames if the requested records are compacted.
+	Snapshot *snapshot.Snapshot
+	Error    string
+}
+
+func encode[T request | response](msg T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decode[T request | response](data []byte) (T, error) {
+	var msg T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&msg)
+	return msg, err
+}
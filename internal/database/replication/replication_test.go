@@ -0,0 +1,459 @@
+package replication_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/alukart32/go-fast-key/internal/database/replication"
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewMaster(t *testing.T) {
+	t.Parallel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop())
+	require.NoError(t, err)
+
+	segments, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		server   *network.TCPServer
+		segments replication.SegmentReader
+		secret   string
+		logger   *zap.Logger
+		wantErr  error
+	}{
+		"create master without server": {
+			segments: segments,
+			secret:   "secret",
+			logger:   zap.NewNop(),
+			wantErr:  errors.New("server is nil"),
+		},
+		"create master without segment reader": {
+			server:  server,
+			secret:  "secret",
+			logger:  zap.NewNop(),
+			wantErr: errors.New("segment reader is nil"),
+		},
+		"create master without secret": {
+			server:   server,
+			segments: segments,
+			logger:   zap.NewNop(),
+			wantErr:  errors.New("secret is empty"),
+		},
+		"create master without logger": {
+			server:   server,
+			segments: segments,
+			secret:   "secret",
+			wantErr:  errors.New("logger is nil"),
+		},
+		"create master": {
+			server:   server,
+			segments: segments,
+			secret:   "secret",
+			logger:   zap.NewNop(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			master, err := replication.NewMaster(test.server, test.segments, test.secret, test.logger)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantErr != nil {
+				assert.Nil(t, master)
+			} else {
+				assert.NotNil(t, master)
+			}
+		})
+	}
+}
+
+func TestNewSlave(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		address string
+		secret  string
+		logger  *zap.Logger
+		wantErr error
+	}{
+		"create slave without master address": {
+			secret:  "secret",
+			logger:  zap.NewNop(),
+			wantErr: errors.New("master address is empty"),
+		},
+		"create slave without secret": {
+			address: "localhost:3223",
+			logger:  zap.NewNop(),
+			wantErr: errors.New("secret is empty"),
+		},
+		"create slave without logger": {
+			address: "localhost:3223",
+			secret:  "secret",
+			wantErr: errors.New("logger is nil"),
+		},
+		"create slave": {
+			address: "localhost:3223",
+			secret:  "secret",
+			logger:  zap.NewNop(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			slave, err := replication.NewSlave(test.address, time.Second, test.secret, test.logger)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantErr != nil {
+				assert.Nil(t, slave)
+			} else {
+				assert.NotNil(t, slave)
+			}
+		})
+	}
+}
+
+func TestReplication(t *testing.T) {
+	t.Parallel()
+
+	masterWAL, err := wal.NewWAL(t.TempDir(), zap.NewNop(), wal.WithMaxSegmentSize(64))
+	require.NoError(t, err)
+	require.NoError(t, masterWAL.Recover(0, func(wal.Record) error { return nil }))
+	defer masterWAL.Close()
+
+	for i := range 10 {
+		logged := masterWAL.Append(wal.Record{
+			CommandID: compute.SetCommand,
+			Arguments: []string{"key", string(rune('a' + i))},
+		})
+		require.NoError(t, logged.Get())
+	}
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop())
+	require.NoError(t, err)
+	master, err := replication.NewMaster(server, masterWAL, "secret", zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go master.Serve(ctx)
+
+	slaveWAL, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+	replica := &replica{w: slaveWAL}
+	require.NoError(t, slaveWAL.Recover(0, replica.apply))
+	defer slaveWAL.Close()
+
+	slave, err := replication.NewSlave(server.Address(), 10*time.Millisecond, "secret", zap.NewNop())
+	require.NoError(t, err)
+	go slave.Run(ctx, replica)
+
+	require.Eventually(t, func() bool {
+		return replica.LSN() == 10
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "j", replica.value())
+
+	logged := masterWAL.Append(wal.Record{CommandID: compute.DelCommand, Arguments: []string{"key"}})
+	require.NoError(t, logged.Get())
+
+	require.Eventually(t, func() bool {
+		return replica.LSN() == 11
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, replica.value())
+
+	require.Eventually(t, func() bool {
+		return slave.Stats().Connected
+	}, time.Second, 10*time.Millisecond)
+	slaveStats := slave.Stats()
+	assert.Equal(t, replication.SlaveRole, slaveStats.Role)
+	assert.Equal(t, server.Address(), slaveStats.MasterAddress)
+	assert.False(t, slaveStats.LastSync.IsZero())
+	assert.Equal(t, replication.Stats{
+		Role:          replication.MasterRole,
+		MasterAddress: server.Address(),
+		Slaves:        1,
+	}, master.Stats())
+}
+
+func TestReplication_ResyncFromSnapshot(t *testing.T) {
+	t.Parallel()
+
+	masterWAL, err := wal.NewWAL(t.TempDir(), zap.NewNop(), wal.WithMaxSegmentSize(64))
+	require.NoError(t, err)
+	require.NoError(t, masterWAL.Recover(0, func(wal.Record) error { return nil }))
+	defer masterWAL.Close()
+
+	for i := range 10 {
+		logged := masterWAL.Append(wal.Record{
+			CommandID: compute.SetCommand,
+			Arguments: []string{"key", string(rune('a' + i))},
+		})
+		require.NoError(t, logged.Get())
+	}
+
+	snapshots, err := snapshot.NewStore(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, snapshots.Save(snapshot.Snapshot{
+		LSN:     10,
+		Entries: []engine.Entry{{Key: "key", Value: "j"}},
+	}))
+	require.NoError(t, masterWAL.Compact(10))
+	_, err = masterWAL.ReadSegment(wal.SegmentPosition{})
+	require.ErrorIs(t, err, wal.ErrSegmentCompacted)
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop())
+	require.NoError(t, err)
+	master, err := replication.NewMaster(server, masterWAL, "secret", zap.NewNop(), replication.WithSnapshots(snapshots))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go master.Serve(ctx)
+
+	slaveWAL, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+	replica := &replica{w: slaveWAL}
+	require.NoError(t, slaveWAL.Recover(0, replica.apply))
+	defer slaveWAL.Close()
+
+	slave, err := replication.NewSlave(server.Address(), 10*time.Millisecond, "secret", zap.NewNop())
+	require.NoError(t, err)
+	go slave.Run(ctx, replica)
+
+	require.Eventually(t, func() bool {
+		return replica.LSN() == 10
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "j", replica.value())
+
+	logged := masterWAL.Append(wal.Record{CommandID: compute.DelCommand, Arguments: []string{"key"}})
+	require.NoError(t, logged.Get())
+
+	require.Eventually(t, func() bool {
+		return replica.LSN() == 11
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, replica.value())
+	assert.Equal(t, 1, replica.restored(), "the slave must continue from the log after the snapshot")
+}
+
+func TestReplication_InvalidSecret(t *testing.T) {
+	t.Parallel()
+
+	masterWAL, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, masterWAL.Recover(0, func(wal.Record) error { return nil }))
+	defer masterWAL.Close()
+
+	logged := masterWAL.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key", "val"}})
+	require.NoError(t, logged.Get())
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop())
+	require.NoError(t, err)
+	master, err := replication.NewMaster(server, masterWAL, "secret", zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go master.Serve(ctx)
+
+	slaveWAL, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+	replica := &replica{w: slaveWAL}
+	require.NoError(t, slaveWAL.Recover(0, replica.apply))
+	defer slaveWAL.Close()
+
+	slave, err := replication.NewSlave(server.Address(), 10*time.Millisecond, "wrong secret", zap.NewNop())
+	require.NoError(t, err)
+	go slave.Run(ctx, replica)
+
+	assert.Never(t, func() bool {
+		return replica.LSN() != 0
+	}, 200*time.Millisecond, 10*time.Millisecond)
+	assert.False(t, slave.Stats().Connected)
+}
+
+func TestReplication_TLS(t *testing.T) {
+	t.Parallel()
+
+	certFile, keyFile := generateTestCert(t)
+	serverConfig, err := network.NewServerTLSConfig(certFile, keyFile, certFile, true)
+	require.NoError(t, err)
+
+	masterWAL, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, masterWAL.Recover(0, func(wal.Record) error { return nil }))
+	defer masterWAL.Close()
+
+	logged := masterWAL.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key", "val"}})
+	require.NoError(t, logged.Get())
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(), network.WithServerTLSConfig(serverConfig))
+	require.NoError(t, err)
+	master, err := replication.NewMaster(server, masterWAL, "secret", zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go master.Serve(ctx)
+
+	tests := map[string]struct {
+		options  []replication.SlaveOption
+		wantSync bool
+	}{
+		"slave without tls": {},
+		"slave without client certificate": {
+			options: []replication.SlaveOption{replication.WithSlaveTLSConfig(clientTLSConfig(t, certFile, "", ""))},
+		},
+		"slave with client certificate": {
+			options:  []replication.SlaveOption{replication.WithSlaveTLSConfig(clientTLSConfig(t, certFile, certFile, keyFile))},
+			wantSync: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			slaveWAL, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+			require.NoError(t, err)
+			replica := &replica{w: slaveWAL}
+			require.NoError(t, slaveWAL.Recover(0, replica.apply))
+			defer slaveWAL.Close()
+
+			slave, err := replication.NewSlave(server.Address(), 10*time.Millisecond, "secret", zap.NewNop(), test.options...)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go slave.Run(ctx, replica)
+
+			if test.wantSync {
+				require.Eventually(t, func() bool {
+					return replica.LSN() == 1
+				}, time.Second, 10*time.Millisecond)
+				assert.Equal(t, "val", replica.value())
+			} else {
+				assert.Never(t, func() bool {
+					return replica.LSN() != 0
+				}, 200*time.Millisecond, 10*time.Millisecond)
+			}
+		})
+	}
+}
+
+func clientTLSConfig(t *testing.T, caFile, certFile, keyFile string) *tls.Config {
+	t.Helper()
+
+	config, err := network.NewClientTLSConfig(caFile, certFile, keyFile, "")
+	require.NoError(t, err)
+	return config
+}
+
+// generateTestCert writes a self-signed certificate of localhost that is valid for both servers and clients.
+func generateTestCert(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "replication.crt"), filepath.Join(dir, "replication.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+// replica applies the replicated records to a single value.
+type replica struct {
+	mtx sync.Mutex
+	val string
+	// snapshots counts the applied snapshots.
+	snapshots int
+	w         *wal.WAL
+}
+
+func (r *replica) LSN() uint64 {
+	return r.w.LSN()
+}
+
+func (r *replica) ApplySegment(firstLSN uint64, data []byte) error {
+	return r.w.ApplySegment(firstLSN, data, r.apply)
+}
+
+func (r *replica) ApplySnapshot(snap snapshot.Snapshot) error {
+	r.mtx.Lock()
+	r.val = ""
+	for _, entry := range snap.Entries {
+		r.val = entry.Value
+	}
+	r.snapshots++
+	r.mtx.Unlock()
+
+	return r.w.Reset(snap.LSN)
+}
+
+func (r *replica) apply(rec wal.Record) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	switch rec.CommandID {
+	case compute.SetCommand:
+		r.val = rec.Arguments[1]
+	case compute.DelCommand:
+		r.val = ""
+	}
+	return nil
+}
+
+func (r *replica) value() string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.val
+}
+
+func (r *replica) restored() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.snapshots
+}
@@ -0,0 +1,229 @@
+package replication
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+	"github.com/alukart32/go-fast-key/internal/network"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSyncInterval   = time.Second
+	defaultRequestTimeout = 10 * time.Second
+)
+
+// Replica describes the local database that applies the replicated log.
+type Replica interface {
+	LSN() uint64
+	ApplySegment(firstLSN uint64, data []byte) error
+	ApplySnapshot(snap snapshot.Snapshot) error
+}
+
+// Slave defines the replication slave that polls the master for the new log segments.
+type Slave struct {
+	masterAddress  string
+	secret         string
+	syncInterval   time.Duration
+	requestTimeout time.Duration
+	tlsConfig      *tls.Config
+
+	// connected and lastSync describe the link to the master.
+	connected atomic.Bool
+	lastSync  atomic.Int64
+
+	l *zap.Logger
+}
+
+// NewSlave creates a new Slave of the master at masterAddress that authenticates by the secret.
+func NewSlave(masterAddress string, syncInterval time.Duration, secret string, logger *zap.Logger, options ...SlaveOption) (*Slave, error) {
+	if masterAddress == "" {
+		return nil, errors.New("master address is empty")
+	}
+	if secret == "" {
+		return nil, errors.New("secret is empty")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+	if syncInterval <= 0 {
+		syncInterval = defaultSyncInterval
+	}
+
+	slave := &Slave{
+		masterAddress:  masterAddress,
+		secret:         secret,
+		syncInterval:   syncInterval,
+		requestTimeout: defaultRequestTimeout,
+		l:              logger,
+	}
+	for _, option := range options {
+		option(slave)
+	}
+	return slave, nil
+}
+
+// Run synchronizes the replica with the master every sync interval until ctx is done.
+func (s *Slave) Run(ctx context.Context, replica Replica) {
+	var conn *connection
+	defer func() {
+		if conn != nil {
+			conn.close()
+		}
+	}()
+
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		if conn == nil {
+			c, err := s.dial(ctx)
+			if err != nil {
+				s.l.Warn("fail to connect to master", zap.String("address", s.masterAddress), zap.Error(err))
+			}
+			conn = c
+		}
+
+		if conn != nil {
+			if err := s.sync(conn, replica); err != nil {
+				s.l.Warn("fail to sync with master", zap.Error(err))
+				conn.close()
+				conn = nil
+			} else {
+				s.lastSync.Store(time.Now().UnixNano())
+			}
+		}
+		s.connected.Store(conn != nil)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stats returns the state of the link to the master.
+func (s *Slave) Stats() Stats {
+	stats := Stats{
+		Role:          SlaveRole,
+		MasterAddress: s.masterAddress,
+		Connected:     s.connected.Load(),
+	}
+	if lastSync := s.lastSync.Load(); lastSync != 0 {
+		stats.LastSync = time.Unix(0, lastSync)
+	}
+	return stats
+}
+
+// sync requests the new frames until the replica catches up with the master last segment.
+//
+// The replica that is behind the compacted master log is restored from the master snapshot first.
+func (s *Slave) sync(conn *connection, replica Replica) error {
+	for {
+		lsn := replica.LSN()
+		req := request{LSN: lsn, FirstLSN: conn.firstLSN, Offset: conn.offset}
+		if !conn.authenticated {
+			req.Secret = s.secret
+		}
+		resp, err := conn.send(req, s.requestTimeout)
+		if err != nil {
+			return err
+		}
+		if resp.Error != "" {
+			return fmt.Errorf("master: %s", resp.Error)
+		}
+		conn.authenticated = true
+
+		if resp.Snapshot != nil {
+			if err := replica.ApplySnapshot(*resp.Snapshot); err != nil {
+				return fmt.Errorf("apply snapshot: %w", err)
+			}
+			s.l.Info("replica is resynchronized from master snapshot", zap.Uint64("lsn", resp.Snapshot.LSN))
+			conn.firstLSN, conn.offset = 0, 0
+		} else {
+			if len(resp.Data) != 0 {
+				if err := replica.ApplySegment(resp.FirstLSN, resp.Data); err != nil {
+					return fmt.Errorf("apply segment: %w", err)
+				}
+			}
+			conn.firstLSN, conn.offset = resp.FirstLSN, resp.Offset
+		}
+		if resp.Last || replica.LSN() == lsn {
+			return nil
+		}
+	}
+}
+
+func (s *Slave) dial(ctx context.Context) (*connection, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.tlsConfig != nil {
+		// The server name is taken from the master address unless the config sets it.
+		dialer := &tls.Dialer{Config: s.tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", s.masterAddress)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", s.masterAddress)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &connection{
+		conn: conn,
+		// The segments size is limited by the master log settings.
+		reader: network.NewFrameReader(conn, 0),
+	}, nil
+}
+
+// connection defines the slave connection to the master.
+type connection struct {
+	conn   net.Conn
+	reader *network.FrameReader
+	// authenticated is set once the master accepts the secret.
+	authenticated bool
+	// firstLSN and offset locate the frame following the replicated ones in the master log.
+	firstLSN uint64
+	offset   int64
+}
+
+func (c *connection) send(req request, timeout time.Duration) (response, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return response{}, fmt.Errorf("set deadline: %w", err)
+	}
+
+	data, err := encode(req)
+	if err != nil {
+		return response{}, fmt.Errorf("encode request: %w", err)
+	}
+	if err := network.WriteFrame(c.conn, network.RequestFrame, data); err != nil {
+		return response{}, fmt.Errorf("send request: %w", err)
+	}
+
+	frameType, payload, err := c.reader.Read()
+	if err != nil {
+		return response{}, fmt.Errorf("read response: %w", err)
+	}
+	if frameType != network.ResponseFrame {
+		return response{}, fmt.Errorf("master: %s", payload)
+	}
+
+	resp, err := decode[response](payload)
+	if err != nil {
+		return response{}, fmt.Errorf("decode response: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *connection) close() {
+	_ = c.conn.Close()
+}
@@ -0,0 +1,13 @@
+package replication
+
+import "crypto/tls"
+
+// SlaveOption defines an optional Slave setting.
+type SlaveOption func(*Slave)
+
+// WithSlaveTLSConfig makes the slave connect to the master over TLS with the config.
+func WithSlaveTLSConfig(config *tls.Config) SlaveOption {
+	return func(s *Slave) {
+		s.tlsConfig = config
+	}
+}
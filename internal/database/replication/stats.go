@@ -0,0 +1,22 @@
+package replication
+
+import "time"
+
+// Role defines the replication role of the server.
+type Role string
+
+const (
+	MasterRole Role = "master"
+	SlaveRole  Role = "slave"
+)
+
+// Stats defines the state of the replication.
+type Stats struct {
+	Role          Role
+	MasterAddress string
+	// Slaves is the number of the slaves connected to the master.
+	Slaves int
+	// Connected and LastSync describe the link of the slave to the master.
+	Connected bool
+	LastSync  time.Time
+}
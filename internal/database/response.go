@@ -0,0 +1,107 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Status defines the kind of the response.
+type Status byte
+
+const (
+	// OKStatus is the success of the command that has no result.
+	OKStatus Status = iota + 1
+	// QueuedStatus is the command queued by the transaction.
+	QueuedStatus
+	// ValueStatus is the value of the command, for example the value of the key.
+	ValueStatus
+	// IntegerStatus is the integer result of the command, for example the time to live.
+	IntegerStatus
+	// NilStatus is the missing value, for example the value of the missing key.
+	NilStatus
+	// ArrayStatus is the results of the command with many results, for example MGET or EXEC.
+	ArrayStatus
+	// ErrorStatus is the failure of the command.
+	ErrorStatus
+)
+
+// Response defines the typed result of the query.
+type Response struct {
+	Status Status
+	// Value is the value of ValueStatus.
+	Value string
+	// Integer is the value of IntegerStatus.
+	Integer int64
+	// Elements are the results of ArrayStatus.
+	Elements []Response
+	// Code and Err are the failure of ErrorStatus.
+	Code ErrorCode
+	Err  error
+}
+
+// OK returns the success response.
+func OK() Response {
+	return Response{Status: OKStatus}
+}
+
+// Queued returns the response of the command queued by the transaction.
+func Queued() Response {
+	return Response{Status: QueuedStatus}
+}
+
+// Value returns the value response.
+func Value(v string) Response {
+	return Response{Status: ValueStatus, Value: v}
+}
+
+// Integer returns the integer response.
+func Integer(n int64) Response {
+	return Response{Status: IntegerStatus, Integer: n}
+}
+
+// Nil returns the missing value response.
+func Nil() Response {
+	return Response{Status: NilStatus}
+}
+
+// Array returns the response of many results.
+func Array(elements ...Response) Response {
+	return Response{Status: ArrayStatus, Elements: elements}
+}
+
+// ErrorResponse returns the failure response with the code of err.
+func ErrorResponse(err error) Response {
+	return Response{Status: ErrorStatus, Code: ErrorCodeOf(err), Err: err}
+}
+
+// String renders the response for humans: the values as is, one array element per line.
+func (r Response) String() string {
+	switch r.Status {
+	case OKStatus:
+		return "OK"
+	case QueuedStatus:
+		return "QUEUED"
+	case ValueStatus:
+		return r.Value
+	case IntegerStatus:
+		return "(integer) " + strconv.FormatInt(r.Integer, 10)
+	case NilStatus:
+		return "(nil)"
+	case ArrayStatus:
+		if len(r.Elements) == 0 {
+			return "(empty array)"
+		}
+
+		lines := make([]string, 0, len(r.Elements))
+		for i, e := range r.Elements {
+			prefix := strconv.Itoa(i+1) + ") "
+			element := strings.ReplaceAll(e.String(), "\n", "\n"+strings.Repeat(" ", len(prefix)))
+			lines = append(lines, prefix+element)
+		}
+		return strings.Join(lines, "\n")
+	case ErrorStatus:
+		return fmt.Sprintf("(error) %s %v", r.Code, r.Err)
+	}
+	return fmt.Sprintf("(unknown status %d)", r.Status)
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The FastKey protocol response layout:
+//
+//	status  byte
+//	payload by status:
+//	  ValueStatus    uvarint length, value
+//	  IntegerStatus  varint
+//	  ArrayStatus    uvarint count, elements
+//	  ErrorStatus    uvarint length, code, uvarint length, message
+//	  other          none
+
+// EncodeResponse encodes the response for the FastKey protocol.
+func EncodeResponse(r Response) []byte {
+	return appendResponse(nil, r)
+}
+
+func appendResponse(data []byte, r Response) []byte {
+	data = append(data, byte(r.Status))
+	switch r.Status {
+	case ValueStatus:
+		data = appendString(data, r.Value)
+	case IntegerStatus:
+		data = binary.AppendVarint(data, r.Integer)
+	case ArrayStatus:
+		data = binary.AppendUvarint(data, uint64(len(r.Elements)))
+		for _, e := range r.Elements {
+			data = appendResponse(data, e)
+		}
+	case ErrorStatus:
+		var message string
+		if r.Err != nil {
+			message = r.Err.Error()
+		}
+		data = appendString(data, string(r.Code))
+		data = appendString(data, message)
+	}
+	return data
+}
+
+func appendString(data []byte, s string) []byte {
+	data = binary.AppendUvarint(data, uint64(len(s)))
+	return append(data, s...)
+}
+
+// DecodeResponse decodes the FastKey protocol response, the error of the response
+// keeps only its message.
+func DecodeResponse(data []byte) (Response, error) {
+	d := responseDecoder{data: data}
+	r := d.response()
+	if d.err == nil && d.offset != len(d.data) {
+		d.err = ErrInvalidResponse
+	}
+	if d.err != nil {
+		return Response{}, d.err
+	}
+	return r, nil
+}
+
+type responseDecoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (d *responseDecoder) response() Response {
+	if d.err != nil {
+		return Response{}
+	}
+	if d.offset == len(d.data) {
+		d.err = ErrInvalidResponse
+		return Response{}
+	}
+
+	r := Response{Status: Status(d.data[d.offset])}
+	d.offset++
+	switch r.Status {
+	case OKStatus, QueuedStatus, NilStatus:
+	case ValueStatus:
+		r.Value = d.string()
+	case IntegerStatus:
+		v, n := binary.Varint(d.data[d.offset:])
+		if n <= 0 {
+			d.err = ErrInvalidResponse
+			return Response{}
+		}
+		r.Integer = v
+		d.offset += n
+	case ArrayStatus:
+		count := d.uvarint()
+		// Every element takes at least one byte.
+		if count > uint64(len(d.data)-d.offset) {
+			d.err = ErrInvalidResponse
+			return Response{}
+		}
+		if count > 0 {
+			r.Elements = make([]Response, 0, count)
+		}
+		for i := uint64(0); i < count && d.err == nil; i++ {
+			r.Elements = append(r.Elements, d.response())
+		}
+	case ErrorStatus:
+		r.Code = ErrorCode(d.string())
+		r.Err = errors.New(d.string())
+	default:
+		d.err = ErrInvalidResponse
+	}
+	return r
+}
+
+func (d *responseDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data[d.offset:])
+	if n <= 0 {
+		d.err = ErrInvalidResponse
+		return 0
+	}
+	d.offset += n
+	return v
+}
+
+func (d *responseDecoder) string() string {
+	size := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if uint64(len(d.data)-d.offset) < size {
+		d.err = ErrInvalidResponse
+		return ""
+	}
+
+	s := string(d.data[d.offset : d.offset+int(size)])
+	d.offset += int(size)
+	return s
+}
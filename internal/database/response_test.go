@@ -0,0 +1,122 @@
+package database_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/auth"
+	"github.com/alukart32/go-fast-key/internal/database"
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponse_String(t *testing.T) {
+	tests := []struct {
+		name string
+		resp database.Response
+		want string
+	}{
+		{name: "OK", resp: database.OK(), want: "OK"},
+		{name: "Queued", resp: database.Queued(), want: "QUEUED"},
+		{name: "Value", resp: database.Value("val"), want: "val"},
+		{name: "Integer", resp: database.Integer(-2), want: "(integer) -2"},
+		{name: "Nil", resp: database.Nil(), want: "(nil)"},
+		{name: "Empty array", resp: database.Array(), want: "(empty array)"},
+		{
+			name: "Array",
+			resp: database.Array(database.Value("val"), database.Nil(), database.Integer(10)),
+			want: "1) val\n2) (nil)\n3) (integer) 10",
+		},
+		{
+			name: "Nested array",
+			resp: database.Array(database.OK(), database.Array(database.Value("a"), database.Value("b"))),
+			want: "1) OK\n2) 1) a\n   2) b",
+		},
+		{
+			name: "Error",
+			resp: database.ErrorResponse(engine.ErrOutOfMemory),
+			want: "(error) OOM " + engine.ErrOutOfMemory.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.resp.String())
+		})
+	}
+}
+
+func TestErrorCodeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want database.ErrorCode
+	}{
+		{err: compute.ErrInvalidArgsNumber, want: database.SyntaxCode},
+		{err: &compute.SyntaxError{Offset: 3, Err: compute.ErrUnterminatedQuote}, want: database.SyntaxCode},
+		{err: compute.ErrUnknownCommand, want: database.UnknownCommandCode},
+		{err: engine.ErrOutOfMemory, want: database.OutOfMemoryCode},
+		{err: engine.ErrOverflow, want: database.OverflowCode},
+		{err: database.ErrReadOnly, want: database.ReadOnlyCode},
+		{err: fmt.Errorf("%w: user svc-a", auth.ErrNoPermission), want: database.NoPermCode},
+		{err: database.ErrWatchedKeyChanged, want: database.ExecAbortCode},
+		{err: errors.New("storage error"), want: database.GenericCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.want, database.ErrorCodeOf(tt.err))
+		})
+	}
+}
+
+func TestResponse_EncodeDecode(t *testing.T) {
+	responses := []database.Response{
+		database.OK(),
+		database.Queued(),
+		database.Value(""),
+		database.Value("value\nwith\x00bytes"),
+		database.Integer(-1),
+		database.Integer(1 << 40),
+		database.Nil(),
+		database.Array(),
+		database.Array(database.Value("val"), database.Nil(), database.Array(database.Integer(1))),
+		database.ErrorResponse(errors.New("storage error")),
+	}
+	for _, resp := range responses {
+		t.Run(resp.String(), func(t *testing.T) {
+			got, err := database.DecodeResponse(database.EncodeResponse(resp))
+			require.NoError(t, err)
+			assert.Equal(t, resp, got)
+		})
+	}
+
+	t.Run("Error keeps the code and the message", func(t *testing.T) {
+		got, err := database.DecodeResponse(database.EncodeResponse(database.ErrorResponse(engine.ErrNotFound)))
+		require.NoError(t, err)
+		assert.Equal(t, database.NotFoundCode, got.Code)
+		assert.EqualError(t, got.Err, engine.ErrNotFound.Error())
+	})
+}
+
+func TestDecodeResponse_Invalid(t *testing.T) {
+	valid := database.EncodeResponse(database.Array(database.Value("val"), database.Integer(10)))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "Empty", data: nil},
+		{name: "Unknown status", data: []byte{0xff}},
+		{name: "Truncated", data: valid[:len(valid)-1]},
+		{name: "Trailing bytes", data: append(append([]byte{}, valid...), 0)},
+		{name: "Too long value", data: []byte{byte(database.ValueStatus), 10, 'v'}},
+		{name: "Too many elements", data: []byte{byte(database.ArrayStatus), 100, byte(database.OKStatus)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := database.DecodeResponse(tt.data)
+			assert.ErrorIs(t, err, database.ErrInvalidResponse)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package snapshot
+
+import "errors"
+
+var (
+	ErrNotFound           = errors.New("snapshot not found")
+	ErrCorrupted          = errors.New("corrupted snapshot")
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+)
@@ -0,0 +1,150 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+)
+
+// The snapshot file layout:
+//
+//	magic    [4]byte
+//	version  uint16
+//	lsn      uint64
+//	count    uvarint
+//	entries  count * (key, value, expires_at), key and value are a uvarint length and bytes,
+//	         expires_at is a uvarint Unix time in nanoseconds, zero if the entry never expires
+//	checksum uint32, CRC-32 of all the preceding bytes
+const (
+	magic          = "FKSS"
+	currentVersion = 1
+	headerSize     = len(magic) + 2 + 8
+	checksumSize   = 4
+)
+
+// Snapshot defines the engine state at the moment the record with LSN was logged.
+type Snapshot struct {
+	LSN     uint64
+	Entries []engine.Entry
+}
+
+// encode writes the snapshot to w in the current format version.
+func encode(w io.Writer, s Snapshot) error {
+	checksum := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, checksum))
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = binary.LittleEndian.AppendUint16(header, currentVersion)
+	header = binary.LittleEndian.AppendUint64(header, s.LSN)
+	header = binary.AppendUvarint(header, uint64(len(s.Entries)))
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+
+	var buf []byte
+	for _, entry := range s.Entries {
+		buf = binary.AppendUvarint(buf[:0], uint64(len(entry.Key)))
+		buf = append(buf, entry.Key...)
+		buf = binary.AppendUvarint(buf, uint64(len(entry.Value)))
+		buf = append(buf, entry.Value...)
+		buf = binary.AppendUvarint(buf, uint64(entry.ExpiresAt))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := w.Write(binary.LittleEndian.AppendUint32(nil, checksum.Sum32()))
+	return err
+}
+
+// decode decodes the snapshot verifying its checksum.
+func decode(data []byte) (Snapshot, error) {
+	if len(data) < headerSize+checksumSize || string(data[:len(magic)]) != magic {
+		return Snapshot{}, ErrCorrupted
+	}
+
+	body := data[:len(data)-checksumSize]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return Snapshot{}, ErrCorrupted
+	}
+
+	version := binary.LittleEndian.Uint16(body[len(magic):])
+	if version != currentVersion {
+		return Snapshot{}, ErrUnsupportedVersion
+	}
+
+	d := decoder{data: body, offset: len(magic) + 2}
+	s := Snapshot{LSN: d.uint64()}
+
+	count := d.uvarint()
+	s.Entries = make([]engine.Entry, 0, min(count, uint64(len(body))))
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		s.Entries = append(s.Entries, engine.Entry{
+			Key:       d.string(),
+			Value:     d.string(),
+			ExpiresAt: int64(d.uvarint()),
+		})
+	}
+
+	if d.err != nil || d.offset != len(body) {
+		return Snapshot{}, ErrCorrupted
+	}
+	return s, nil
+}
+
+type decoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (d *decoder) uint64() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data)-d.offset < 8 {
+		d.err = ErrCorrupted
+		return 0
+	}
+
+	v := binary.LittleEndian.Uint64(d.data[d.offset:])
+	d.offset += 8
+	return v
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data[d.offset:])
+	if n <= 0 {
+		d.err = ErrCorrupted
+		return 0
+	}
+	d.offset += n
+	return v
+}
+
+func (d *decoder) string() string {
+	size := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if uint64(len(d.data)-d.offset) < size {
+		d.err = ErrCorrupted
+		return ""
+	}
+
+	s := string(d.data[d.offset : d.offset+int(size)])
+	d.offset += int(size)
+	return s
+}
@@ -0,0 +1,65 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, encode(&buf, Snapshot{
+		LSN:     7,
+		Entries: []engine.Entry{{Key: "key", Value: "val", ExpiresAt: 1700000000000000000}},
+	}))
+	valid := buf.Bytes()
+
+	withVersion := func(version uint16) []byte {
+		data := bytes.Clone(valid)
+		binary.LittleEndian.PutUint16(data[len(magic):], version)
+		body := data[:len(data)-checksumSize]
+		binary.LittleEndian.PutUint32(data[len(body):], crc32.ChecksumIEEE(body))
+		return data
+	}
+
+	tests := map[string]struct {
+		data    []byte
+		wantErr error
+	}{
+		"valid snapshot": {
+			data: valid,
+		},
+		"empty data": {
+			data:    nil,
+			wantErr: ErrCorrupted,
+		},
+		"invalid magic": {
+			data:    append([]byte("XXXX"), valid[len(magic):]...),
+			wantErr: ErrCorrupted,
+		},
+		"truncated data": {
+			data:    valid[:len(valid)-1],
+			wantErr: ErrCorrupted,
+		},
+		"unsupported version": {
+			data:    withVersion(currentVersion + 1),
+			wantErr: ErrUnsupportedVersion,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := decode(test.data)
+			assert.Equal(t, test.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	snapshotPrefix = "snapshot_"
+	snapshotSuffix = ".snap"
+	tmpSuffix      = ".tmp"
+)
+
+// retainedNumber is the number of kept snapshots, the older ones
+// are the fallback if the latest snapshot is damaged.
+const retainedNumber = 2
+
+// Store defines the directory of the engine snapshots.
+type Store struct {
+	dir string
+	l   *zap.Logger
+}
+
+// NewStore creates a new Store that keeps snapshots in dir.
+func NewStore(dir string, logger *zap.Logger) (*Store, error) {
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+	if dir == "" {
+		return nil, errors.New("data directory is empty")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+
+	return &Store{
+		dir: dir,
+		l:   logger,
+	}, nil
+}
+
+// Save durably writes the snapshot and removes the ones that are no longer retained.
+func (s *Store) Save(snap Snapshot) error {
+	path := s.path(snap.LSN)
+	tmpPath := path + tmpSuffix
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+
+	if err := encode(file, snap); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+
+	s.l.Info("snapshot is saved", zap.Uint64("lsn", snap.LSN), zap.Int("entries", len(snap.Entries)))
+	s.prune()
+	return nil
+}
+
+// Latest loads the latest valid snapshot.
+//
+// Damaged snapshots are skipped, ErrNotFound is returned if there is no valid one.
+func (s *Store) Latest() (Snapshot, error) {
+	lsns, err := s.list()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	for _, lsn := range slices.Backward(lsns) {
+		data, err := os.ReadFile(s.path(lsn))
+		if err != nil {
+			s.l.Warn("fail to read snapshot", zap.Uint64("lsn", lsn), zap.Error(err))
+			continue
+		}
+
+		snap, err := decode(data)
+		if err != nil {
+			s.l.Warn("skip invalid snapshot", zap.Uint64("lsn", lsn), zap.Error(err))
+			continue
+		}
+		return snap, nil
+	}
+
+	return Snapshot{}, ErrNotFound
+}
+
+// CompactionLSN returns the LSN the log can be compacted up to.
+//
+// It is the LSN of the oldest retained snapshot, so the log still covers
+// the fallback to it. Zero is returned until enough snapshots are taken.
+func (s *Store) CompactionLSN() uint64 {
+	lsns, err := s.list()
+	if err != nil || len(lsns) < retainedNumber {
+		return 0
+	}
+	return lsns[len(lsns)-retainedNumber]
+}
+
+func (s *Store) prune() {
+	lsns, err := s.list()
+	if err != nil {
+		s.l.Warn("fail to list snapshots", zap.Error(err))
+		return
+	}
+
+	for len(lsns) > retainedNumber {
+		if err := os.Remove(s.path(lsns[0])); err != nil {
+			s.l.Warn("fail to remove snapshot", zap.Uint64("lsn", lsns[0]), zap.Error(err))
+		}
+		lsns = lsns[1:]
+	}
+}
+
+// list returns the LSNs of the snapshots in ascending order.
+func (s *Store) list() ([]uint64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot directory: %w", err)
+	}
+
+	var lsns []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+			continue
+		}
+
+		lsn, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		lsns = append(lsns, lsn)
+	}
+
+	slices.Sort(lsns)
+	return lsns, nil
+}
+
+func (s *Store) path(lsn uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%020d%s", snapshotPrefix, lsn, snapshotSuffix))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open snapshot directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync snapshot directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package snapshot_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/database/engine"
+	"github.com/alukart32/go-fast-key/internal/database/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewStore(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		dir        string
+		logger     *zap.Logger
+		wantErr    error
+		wantNilObj bool
+	}{
+		"create store without logger": {
+			dir:        t.TempDir(),
+			wantErr:    errors.New("logger is nil"),
+			wantNilObj: true,
+		},
+		"create store without directory": {
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("data directory is empty"),
+			wantNilObj: true,
+		},
+		"create store": {
+			dir:    filepath.Join(t.TempDir(), "snapshots"),
+			logger: zap.NewNop(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := snapshot.NewStore(test.dir, test.logger)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantNilObj {
+				assert.Nil(t, store)
+			} else {
+				assert.NotNil(t, store)
+			}
+		})
+	}
+}
+
+func TestStore_SaveAndLatest(t *testing.T) {
+	t.Parallel()
+
+	store, err := snapshot.NewStore(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+
+	_, err = store.Latest()
+	require.ErrorIs(t, err, snapshot.ErrNotFound)
+
+	want := snapshot.Snapshot{
+		LSN: 42,
+		Entries: []engine.Entry{
+			{Key: "key_1", Value: "val_1"},
+			{Key: "key_2", Value: "val with spaces\nand lines", ExpiresAt: 1700000000000000000},
+		},
+	}
+	require.NoError(t, store.Save(want))
+
+	got, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestStore_LatestSkipsDamagedSnapshot(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := snapshot.NewStore(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	older := snapshot.Snapshot{LSN: 1, Entries: []engine.Entry{{Key: "key", Value: "old"}}}
+	require.NoError(t, store.Save(older))
+	require.NoError(t, store.Save(snapshot.Snapshot{LSN: 2, Entries: []engine.Entry{{Key: "key", Value: "new"}}}))
+
+	path := filepath.Join(dir, "snapshot_00000000000000000002.snap")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)/2] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	got, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, older, got)
+}
+
+func TestStore_Retention(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := snapshot.NewStore(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, store.Save(snapshot.Snapshot{LSN: 10}))
+	assert.Zero(t, store.CompactionLSN(), "the only snapshot has no fallback")
+
+	require.NoError(t, store.Save(snapshot.Snapshot{LSN: 20}))
+	assert.EqualValues(t, 10, store.CompactionLSN())
+
+	require.NoError(t, store.Save(snapshot.Snapshot{LSN: 30}))
+	assert.EqualValues(t, 20, store.CompactionLSN())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	got, err := store.Latest()
+	require.NoError(t, err)
+	assert.EqualValues(t, 30, got.LSN)
+}
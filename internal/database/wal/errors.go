@@ -0,0 +1,12 @@
+package wal
+
+import "errors"
+
+var (
+	ErrNotRecovered       = errors.New("wal is not recovered")
+	ErrClosed             = errors.New("wal is closed")
+	ErrCorruptedRecord    = errors.New("corrupted wal record")
+	ErrLSNGap             = errors.New("wal lsn gap")
+	ErrSegmentCompacted   = errors.New("wal segment is compacted")
+	ErrInvalidFlushPolicy = errors.New("invalid flush policy")
+)
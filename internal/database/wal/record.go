@@ -0,0 +1,197 @@
+package wal
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+)
+
+// frameHeaderSize is the size of the payload length and its checksum.
+const frameHeaderSize = 8
+
+// Record defines a single logged mutation.
+type Record struct {
+	LSN       uint64
+	CommandID compute.CommandID
+	Arguments []string
+}
+
+// encodeFrame encodes records into a single checksummed frame.
+//
+// The frame is the unit of atomicity: on recovery either all of its records
+// are applied or none of them.
+func encodeFrame(records []Record) []byte {
+	payload := binary.AppendUvarint(nil, uint64(len(records)))
+	for _, r := range records {
+		payload = binary.AppendUvarint(payload, r.LSN)
+		payload = binary.AppendUvarint(payload, uint64(r.CommandID))
+		payload = binary.AppendUvarint(payload, uint64(len(r.Arguments)))
+		for _, arg := range r.Arguments {
+			payload = binary.AppendUvarint(payload, uint64(len(arg)))
+			payload = append(payload, arg...)
+		}
+	}
+
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+	return append(frame, payload...)
+}
+
+// decodeFrames decodes the frames from data.
+//
+// It returns the decoded records and the size of the valid prefix of data.
+// ErrCorruptedRecord is returned if data has a torn or damaged tail.
+func decodeFrames(data []byte) ([]Record, int, error) {
+	var records []Record
+	size, err := eachFrame(data, func(_ int, decoded []Record) {
+		records = append(records, decoded...)
+	})
+	return records, size, err
+}
+
+// framesAfter returns the complete frames of data that hold the records logged after lsn
+// and their records, the frames before them are left out.
+//
+// ErrCorruptedRecord is returned if data has a torn or damaged tail.
+func framesAfter(data []byte, lsn uint64) ([]byte, []Record, error) {
+	frames, _, records, err := framesBetween(data, lsn, math.MaxUint64)
+	return frames, records, err
+}
+
+// framesBetween returns the complete frames of data that hold the records logged after from
+// up to to and their records, the frames around them are left out. It also returns the offset
+// that follows the frames with the records up to to.
+//
+// ErrCorruptedRecord is returned if data has a torn or damaged tail.
+func framesBetween(data []byte, from, to uint64) ([]byte, int, []Record, error) {
+	start, end := -1, -1
+	var records []Record
+	size, err := eachFrame(data, func(offset int, decoded []Record) {
+		if end >= 0 || len(decoded) == 0 || decoded[len(decoded)-1].LSN <= from {
+			return
+		}
+		if decoded[len(decoded)-1].LSN > to {
+			end = offset
+			return
+		}
+		if start < 0 {
+			start = offset
+		}
+		records = append(records, decoded...)
+	})
+	if end < 0 {
+		end = size
+	}
+	if start < 0 {
+		return nil, end, nil, err
+	}
+	return data[start:end], end, records, err
+}
+
+// eachFrame calls fn with the offset and the records of every frame of data in order.
+//
+// It returns the size of the valid prefix of data.
+// ErrCorruptedRecord is returned if data has a torn or damaged tail.
+func eachFrame(data []byte, fn func(offset int, records []Record)) (int, error) {
+	offset := 0
+	for offset < len(data) {
+		decoded, size, err := decodeFrame(data[offset:])
+		if err != nil {
+			return offset, err
+		}
+
+		fn(offset, decoded)
+		offset += size
+	}
+
+	return offset, nil
+}
+
+// decodeFrame decodes the frame data starts with, it returns the frame records and size.
+//
+// ErrCorruptedRecord is returned if the frame is torn or damaged.
+func decodeFrame(data []byte) ([]Record, int, error) {
+	if len(data) < frameHeaderSize {
+		return nil, 0, ErrCorruptedRecord
+	}
+
+	size := int(binary.LittleEndian.Uint32(data[0:4]))
+	checksum := binary.LittleEndian.Uint32(data[4:8])
+	if len(data)-frameHeaderSize < size {
+		return nil, 0, ErrCorruptedRecord
+	}
+
+	payload := data[frameHeaderSize : frameHeaderSize+size]
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, 0, ErrCorruptedRecord
+	}
+
+	decoded, err := decodePayload(payload)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decoded, frameHeaderSize + size, nil
+}
+
+func decodePayload(payload []byte) ([]Record, error) {
+	d := decoder{data: payload}
+
+	count := d.uvarint()
+	records := make([]Record, 0, min(count, uint64(len(payload))))
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		r := Record{
+			LSN:       d.uvarint(),
+			CommandID: compute.CommandID(d.uvarint()),
+		}
+
+		argsNumber := d.uvarint()
+		r.Arguments = make([]string, 0, min(argsNumber, uint64(len(payload))))
+		for j := uint64(0); j < argsNumber && d.err == nil; j++ {
+			r.Arguments = append(r.Arguments, d.string())
+		}
+		records = append(records, r)
+	}
+
+	if d.err != nil || d.offset != len(d.data) {
+		return nil, ErrCorruptedRecord
+	}
+	return records, nil
+}
+
+type decoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data[d.offset:])
+	if n <= 0 {
+		d.err = ErrCorruptedRecord
+		return 0
+	}
+	d.offset += n
+	return v
+}
+
+func (d *decoder) string() string {
+	size := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if uint64(len(d.data)-d.offset) < size {
+		d.err = ErrCorruptedRecord
+		return ""
+	}
+
+	s := string(d.data[d.offset : d.offset+int(size)])
+	d.offset += int(size)
+	return s
+}
@@ -0,0 +1,157 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	segmentPrefix = "wal_"
+	segmentSuffix = ".log"
+)
+
+// segment defines a log file that holds records starting from firstLSN.
+type segment struct {
+	file     *os.File
+	firstLSN uint64
+	size     int
+}
+
+// openSegment opens the segment for appending, creating it if necessary.
+func openSegment(dir string, firstLSN uint64) (*segment, error) {
+	file, err := os.OpenFile(segmentPath(dir, firstLSN), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open segment: %w", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("stat segment: %w", err)
+	}
+
+	return &segment{
+		file:     file,
+		firstLSN: firstLSN,
+		size:     int(stat.Size()),
+	}, nil
+}
+
+// write appends data to the segment.
+//
+// A partial write is rolled back so that the segment never ends with a torn frame.
+func (s *segment) write(data []byte) error {
+	n, err := s.file.Write(data)
+	if err != nil {
+		if n > 0 {
+			_ = s.file.Truncate(int64(s.size))
+		}
+		return fmt.Errorf("write segment: %w", err)
+	}
+
+	s.size += n
+	return nil
+}
+
+// truncate removes the data written after size.
+func (s *segment) truncate(size int) error {
+	if err := s.file.Truncate(int64(size)); err != nil {
+		return fmt.Errorf("truncate segment: %w", err)
+	}
+
+	s.size = size
+	return nil
+}
+
+func (s *segment) sync() error {
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("sync segment: %w", err)
+	}
+	return nil
+}
+
+func (s *segment) close() error {
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return fmt.Errorf("sync segment: %w", err)
+	}
+	return s.file.Close()
+}
+
+// appendSegment durably appends data to the segment, creating it if necessary.
+func appendSegment(dir string, firstLSN uint64, data []byte) error {
+	segment, err := openSegment(dir, firstLSN)
+	if err != nil {
+		return err
+	}
+
+	if err := segment.write(data); err != nil {
+		_ = segment.file.Close()
+		return err
+	}
+	return segment.close()
+}
+
+// readSegment reads the segment data that follows offset.
+func readSegment(dir string, firstLSN uint64, offset int64) ([]byte, error) {
+	file, err := os.Open(segmentPath(dir, firstLSN))
+	if err != nil {
+		return nil, fmt.Errorf("open segment: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat segment: %w", err)
+	}
+	if offset > stat.Size() {
+		return nil, fmt.Errorf("offset %d is beyond segment size %d", offset, stat.Size())
+	}
+
+	data := make([]byte, stat.Size()-offset)
+	// The active segment may grow while it is read, the frames written later are read next time.
+	n, err := file.ReadAt(data, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read segment: %w", err)
+	}
+	return data[:n], nil
+}
+
+func segmentName(firstLSN uint64) string {
+	return fmt.Sprintf("%s%020d%s", segmentPrefix, firstLSN, segmentSuffix)
+}
+
+func segmentPath(dir string, firstLSN uint64) string {
+	return filepath.Join(dir, segmentName(firstLSN))
+}
+
+// listSegments returns the first LSNs of the segments in dir in ascending order.
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read wal directory: %w", err)
+	}
+
+	var segments []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+
+		lsn, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, lsn)
+	}
+
+	slices.Sort(segments)
+	return segments, nil
+}
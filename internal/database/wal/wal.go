@@ -0,0 +1,598 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/pkg/concurrency"
+	"go.uber.org/zap"
+)
+
+// FlushPolicy defines when the logged records are synced to the disk.
+type FlushPolicy string
+
+const (
+	// FlushPolicyAlways syncs the segment after each appended frame.
+	FlushPolicyAlways FlushPolicy = "always"
+	// FlushPolicyBatch syncs the segment once per flushed batch.
+	FlushPolicyBatch FlushPolicy = "batch"
+	// FlushPolicyNone leaves syncing to the operating system.
+	FlushPolicyNone FlushPolicy = "none"
+)
+
+const (
+	defaultMaxSegmentSize       = 10 << 20
+	defaultFlushingBatchSize    = 100
+	defaultFlushingBatchTimeout = 10 * time.Millisecond
+)
+
+// pendingFrame defines the appended records that wait for the flush,
+// they are assigned LSNs once they are written.
+type pendingFrame struct {
+	records []Record
+	promise concurrency.Promise[error]
+}
+
+// WAL defines a segmented write-ahead log.
+//
+// Appended records are collected into batches that are flushed by
+// the background goroutine when the batch is full or its timeout expires.
+type WAL struct {
+	mtx       sync.Mutex
+	batch     []pendingFrame
+	batchSize int
+	// lsn is the LSN of the last written record.
+	lsn       uint64
+	recovered bool
+	closed    bool
+	// lastFlush and lastFlushErr describe the last flushed batch.
+	lastFlush    time.Time
+	lastFlushErr error
+
+	// segment is accessed by the flushing goroutine only.
+	segment *segment
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+
+	dir                  string
+	maxSegmentSize       int
+	flushPolicy          FlushPolicy
+	flushingBatchSize    int
+	flushingBatchTimeout time.Duration
+	// observeFlush is called with the duration of every batch flush.
+	observeFlush func(time.Duration)
+
+	l *zap.Logger
+}
+
+// NewWAL creates a new WAL that keeps segments in dir.
+func NewWAL(dir string, logger *zap.Logger, options ...Option) (*WAL, error) {
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+	if dir == "" {
+		return nil, errors.New("data directory is empty")
+	}
+
+	w := &WAL{
+		flushCh:              make(chan struct{}, 1),
+		stopCh:               make(chan struct{}),
+		dir:                  dir,
+		maxSegmentSize:       defaultMaxSegmentSize,
+		flushPolicy:          FlushPolicyBatch,
+		flushingBatchSize:    defaultFlushingBatchSize,
+		flushingBatchTimeout: defaultFlushingBatchTimeout,
+		l:                    logger,
+	}
+
+	for _, option := range options {
+		option(w)
+	}
+
+	switch w.flushPolicy {
+	case FlushPolicyAlways, FlushPolicyBatch, FlushPolicyNone:
+	default:
+		return nil, ErrInvalidFlushPolicy
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+
+	return w, nil
+}
+
+// Recover replays the records logged after lsn and starts flushing appended records.
+//
+// Segments that hold only the records up to lsn are skipped. A torn tail of
+// the last segment is truncated, damage anywhere else is reported as an error.
+func (w *WAL) Recover(lsn uint64, apply func(Record) error) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if w.recovered {
+		return errors.New("wal is already recovered")
+	}
+
+	segments, err := listSegments(w.dir)
+	if err != nil {
+		return err
+	}
+
+	w.lsn = lsn
+	replayed := 0
+	for i, firstLSN := range segments {
+		if i+1 < len(segments) && segments[i+1] <= lsn+1 {
+			continue
+		}
+		if firstLSN > w.lsn+1 {
+			return fmt.Errorf("%w: expected lsn %d, got segment %s", ErrLSNGap, w.lsn+1, segmentName(firstLSN))
+		}
+
+		path := segmentPath(w.dir, firstLSN)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read segment: %w", err)
+		}
+
+		records, size, err := decodeFrames(data)
+		if err != nil {
+			if i != len(segments)-1 {
+				return fmt.Errorf("segment %s: %w", segmentName(firstLSN), err)
+			}
+
+			w.l.Warn("truncate torn wal tail",
+				zap.String("segment", segmentName(firstLSN)),
+				zap.Int("offset", size),
+			)
+			if err := os.Truncate(path, int64(size)); err != nil {
+				return fmt.Errorf("truncate segment: %w", err)
+			}
+		}
+
+		for _, r := range records {
+			if r.LSN <= w.lsn {
+				continue
+			}
+			if err := apply(r); err != nil {
+				return fmt.Errorf("apply record %d: %w", r.LSN, err)
+			}
+			w.lsn = r.LSN
+		}
+		replayed++
+	}
+
+	w.recovered = true
+	w.l.Debug("wal is recovered", zap.Int("segments", replayed), zap.Uint64("lsn", w.lsn))
+
+	w.wg.Add(1)
+	go w.flushLoop()
+
+	return nil
+}
+
+// Append logs the records, they are assigned consecutive LSNs once they are written.
+//
+// The records are written as one frame, so they are recovered all together or not at all.
+// The returned future is resolved once the batch with the records is flushed,
+// the records of a failed batch take no LSNs.
+func (w *WAL) Append(records ...Record) concurrency.Future[error] {
+	promise := concurrency.NewPromise[error]()
+	if len(records) == 0 {
+		promise.Set(nil)
+		return promise.Future()
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if !w.recovered {
+		promise.Set(ErrNotRecovered)
+		return promise.Future()
+	}
+	if w.closed {
+		promise.Set(ErrClosed)
+		return promise.Future()
+	}
+
+	w.batch = append(w.batch, pendingFrame{
+		records: slices.Clone(records),
+		promise: promise,
+	})
+	w.batchSize += len(records)
+
+	if w.batchSize >= w.flushingBatchSize {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return promise.Future()
+}
+
+// LSN returns the LSN of the last written record.
+func (w *WAL) LSN() uint64 {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return w.lsn
+}
+
+// Stats defines the state of the write-ahead log.
+type Stats struct {
+	LSN            uint64
+	Segments       int
+	PendingRecords int
+	FlushPolicy    FlushPolicy
+	LastFlush      time.Time
+	LastFlushErr   error
+}
+
+// Stats returns the current state of the log.
+func (w *WAL) Stats() (Stats, error) {
+	segments, err := listSegments(w.dir)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return Stats{
+		LSN:            w.lsn,
+		Segments:       len(segments),
+		PendingRecords: w.batchSize,
+		FlushPolicy:    w.flushPolicy,
+		LastFlush:      w.lastFlush,
+		LastFlushErr:   w.lastFlushErr,
+	}, nil
+}
+
+// Compact removes the segments that hold only the records up to lsn.
+func (w *WAL) Compact(lsn uint64) error {
+	segments, err := listSegments(w.dir)
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	for i := 0; i+1 < len(segments) && segments[i+1] <= lsn+1; i++ {
+		if err := os.Remove(segmentPath(w.dir, segments[i])); err != nil {
+			return fmt.Errorf("remove segment: %w", err)
+		}
+		removed++
+	}
+
+	w.l.Debug("wal is compacted", zap.Int("removed_segments", removed), zap.Uint64("lsn", lsn))
+	return nil
+}
+
+// SegmentPosition defines the position in the log that follows the record of LSN.
+type SegmentPosition struct {
+	LSN uint64
+	// FirstLSN and Offset locate the frame following the record, they are taken
+	// from the previous chunk so that the next read continues from that frame.
+	FirstLSN uint64
+	Offset   int64
+}
+
+// SegmentChunk holds the frames read from the segment of FirstLSN.
+type SegmentChunk struct {
+	FirstLSN uint64
+	Data     []byte
+	// Offset is the offset of the frame following Data in the segment.
+	Offset int64
+	// Last reports whether the segment is the last one.
+	Last bool
+}
+
+// ReadSegment returns the chunk of the segment that holds the records logged after pos.LSN,
+// the frames with the earlier records are left out. Only the records up to the current LSN
+// are read, the later frames are removed if they fail to be synced.
+//
+// The segment is read from pos.Offset if the frame there continues pos.LSN, otherwise
+// it is read from the start. The chunk is empty if there are no records after pos.LSN yet.
+// ErrSegmentCompacted is returned if the records following pos.LSN are already compacted.
+func (w *WAL) ReadSegment(pos SegmentPosition) (SegmentChunk, error) {
+	// The LSN is taken first, so the frames up to it are complete when the segment is read.
+	synced := w.LSN()
+
+	segments, err := listSegments(w.dir)
+	if err != nil {
+		return SegmentChunk{}, err
+	}
+	if len(segments) == 0 {
+		return SegmentChunk{Last: true}, nil
+	}
+	if segments[0] > pos.LSN+1 {
+		return SegmentChunk{}, ErrSegmentCompacted
+	}
+
+	i := 0
+	for i+1 < len(segments) && segments[i+1] <= pos.LSN+1 {
+		i++
+	}
+
+	offset := int64(0)
+	if segments[i] == pos.FirstLSN {
+		offset = pos.Offset
+	}
+	for ; i < len(segments); i++ {
+		frames, end, err := w.readFrames(segments[i], offset, pos.LSN, synced)
+		if err != nil {
+			return SegmentChunk{}, err
+		}
+
+		last := i == len(segments)-1
+		if last || len(frames) != 0 {
+			return SegmentChunk{FirstLSN: segments[i], Data: frames, Offset: end, Last: last}, nil
+		}
+		offset = 0
+	}
+
+	return SegmentChunk{Last: true}, nil
+}
+
+// readFrames reads the complete frames of the segment that hold the records logged after lsn
+// up to synced, and returns them with the offset that follows them in the segment.
+func (w *WAL) readFrames(firstLSN uint64, offset int64, lsn, synced uint64) ([]byte, int64, error) {
+	if offset > 0 {
+		data, err := readSegment(w.dir, firstLSN, offset)
+		if err == nil && continues(data, lsn) {
+			frames, end, _, _ := framesBetween(data, lsn, synced)
+			return frames, offset + int64(end), nil
+		}
+	}
+
+	data, err := readSegment(w.dir, firstLSN, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	// The active segment may end with a frame that is being written.
+	frames, end, _, _ := framesBetween(data, lsn, synced)
+	return frames, int64(end), nil
+}
+
+// continues reports whether data is empty or starts with the frame that holds
+// the records up to the one following lsn.
+func continues(data []byte, lsn uint64) bool {
+	if len(data) == 0 {
+		return true
+	}
+
+	records, _, err := decodeFrame(data)
+	return err == nil && len(records) != 0 && records[0].LSN <= lsn+1
+}
+
+// ApplySegment appends the frames replicated from the master segment to the segment
+// of the same first LSN and replays their records. The frames with the records up to
+// the current LSN are skipped.
+//
+// It must not be mixed with Append, the replicated log is owned by the master.
+func (w *WAL) ApplySegment(firstLSN uint64, data []byte, apply func(Record) error) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if !w.recovered {
+		return ErrNotRecovered
+	}
+
+	frames, records, err := framesAfter(data, w.lsn)
+	if err != nil {
+		return fmt.Errorf("segment %s: %w", segmentName(firstLSN), err)
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+	if err := appendSegment(w.dir, firstLSN, frames); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if err := apply(r); err != nil {
+			return fmt.Errorf("apply record %d: %w", r.LSN, err)
+		}
+		w.lsn = r.LSN
+	}
+	return nil
+}
+
+// Reset removes all the segments, the replicated log continues after lsn.
+//
+// It is used once the replica is restored from the master snapshot with lsn,
+// so it must not be mixed with Append as ApplySegment.
+func (w *WAL) Reset(lsn uint64) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if !w.recovered {
+		return ErrNotRecovered
+	}
+
+	segments, err := listSegments(w.dir)
+	if err != nil {
+		return err
+	}
+	for _, firstLSN := range segments {
+		if err := os.Remove(segmentPath(w.dir, firstLSN)); err != nil {
+			return fmt.Errorf("remove segment: %w", err)
+		}
+	}
+
+	w.lsn = lsn
+	w.l.Debug("wal is reset", zap.Int("removed_segments", len(segments)), zap.Uint64("lsn", lsn))
+	return nil
+}
+
+// Close flushes the pending records, then syncs and closes the active segment.
+func (w *WAL) Close() error {
+	w.mtx.Lock()
+	if w.closed {
+		w.mtx.Unlock()
+		return nil
+	}
+	w.closed = true
+	recovered := w.recovered
+	w.mtx.Unlock()
+
+	if !recovered {
+		return nil
+	}
+
+	close(w.stopCh)
+	w.wg.Wait()
+
+	if w.segment == nil {
+		return nil
+	}
+
+	err := w.segment.close()
+	w.segment = nil
+	return err
+}
+
+func (w *WAL) flushLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.flushingBatchTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			w.flush()
+			return
+		case <-w.flushCh:
+			w.flush()
+			ticker.Reset(w.flushingBatchTimeout)
+		case <-ticker.C:
+			w.flush()
+		}
+	}
+}
+
+// flush writes the pending batch and resolves its futures.
+func (w *WAL) flush() {
+	w.mtx.Lock()
+	batch := w.batch
+	w.batch = nil
+	w.batchSize = 0
+	lsn := w.lsn
+	w.mtx.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	if w.observeFlush != nil {
+		start := time.Now()
+		defer func() { w.observeFlush(time.Since(start)) }()
+	}
+
+	var flushErr error
+	for len(batch) > 0 {
+		n, written, err := w.write(batch, lsn)
+		if err != nil {
+			w.l.Error("fail to flush wal batch", zap.Error(err))
+			flushErr = err
+		} else {
+			lsn = written
+			w.mtx.Lock()
+			w.lsn = lsn
+			w.mtx.Unlock()
+		}
+
+		for _, frame := range batch[:n] {
+			frame.promise.Set(err)
+		}
+		batch = batch[n:]
+	}
+
+	w.mtx.Lock()
+	w.lastFlush, w.lastFlushErr = time.Now(), flushErr
+	w.mtx.Unlock()
+}
+
+// write writes the leading frames of the batch that fit into one segment,
+// their records are assigned the LSNs following lsn.
+//
+// It returns the number of the handled frames and the LSN of the last written record.
+// Nothing is left in the segment if the write fails, so the next frames take the same LSNs.
+func (w *WAL) write(batch []pendingFrame, lsn uint64) (int, uint64, error) {
+	first := encodeFrame(assignLSNs(batch[0].records, lsn))
+	if err := w.rotate(lsn+1, len(first)); err != nil {
+		return len(batch), lsn, err
+	}
+
+	n, data := 1, first
+	last := lsn + uint64(len(batch[0].records))
+	if w.flushPolicy != FlushPolicyAlways {
+		for ; n < len(batch); n++ {
+			frame := encodeFrame(assignLSNs(batch[n].records, last))
+			if w.segment.size+len(data)+len(frame) > w.maxSegmentSize {
+				break
+			}
+			data = append(data, frame...)
+			last += uint64(len(batch[n].records))
+		}
+	}
+
+	size := w.segment.size
+	if err := w.segment.write(data); err != nil {
+		return n, lsn, err
+	}
+	if w.flushPolicy != FlushPolicyNone {
+		if err := w.segment.sync(); err != nil {
+			if err := w.segment.truncate(size); err != nil {
+				w.l.Error("fail to remove unsynced wal frames", zap.Error(err))
+			}
+			return n, lsn, err
+		}
+	}
+	return n, last, nil
+}
+
+// assignLSNs returns the copies of the records with the consecutive LSNs following lsn.
+func assignLSNs(records []Record, lsn uint64) []Record {
+	assigned := make([]Record, len(records))
+	for i, r := range records {
+		r.LSN = lsn + uint64(i) + 1
+		assigned[i] = r
+	}
+	return assigned
+}
+
+// rotate opens a new segment if there is no active one or the frame does not fit into it.
+func (w *WAL) rotate(firstLSN uint64, frameSize int) error {
+	if w.segment != nil {
+		if w.segment.size == 0 || w.segment.size+frameSize <= w.maxSegmentSize {
+			return nil
+		}
+		if err := w.segment.close(); err != nil {
+			w.l.Warn("fail to close segment", zap.Error(err))
+		}
+		w.segment = nil
+	}
+
+	segment, err := openSegment(w.dir, firstLSN)
+	if err != nil {
+		return err
+	}
+	w.segment = segment
+	return nil
+}
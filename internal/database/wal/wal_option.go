@@ -0,0 +1,47 @@
+package wal
+
+import "time"
+
+// Option defines an optional WAL setting.
+type Option func(*WAL)
+
+// WithMaxSegmentSize sets the size after which a new segment is started.
+func WithMaxSegmentSize(size uint) Option {
+	return func(w *WAL) {
+		if size != 0 {
+			w.maxSegmentSize = int(size)
+		}
+	}
+}
+
+// WithFlushPolicy sets the policy of syncing records to the disk.
+func WithFlushPolicy(policy FlushPolicy) Option {
+	return func(w *WAL) {
+		w.flushPolicy = policy
+	}
+}
+
+// WithFlushingBatchSize sets the number of records that triggers the batch flush.
+func WithFlushingBatchSize(size uint) Option {
+	return func(w *WAL) {
+		if size != 0 {
+			w.flushingBatchSize = int(size)
+		}
+	}
+}
+
+// WithFlushingBatchTimeout sets the maximum time a record waits for the batch flush.
+func WithFlushingBatchTimeout(timeout time.Duration) Option {
+	return func(w *WAL) {
+		if timeout > 0 {
+			w.flushingBatchTimeout = timeout
+		}
+	}
+}
+
+// WithFlushObserver sets the function called with the duration of every batch flush.
+func WithFlushObserver(observe func(time.Duration)) Option {
+	return func(w *WAL) {
+		w.observeFlush = observe
+	}
+}
@@ -0,0 +1,571 @@
+package wal_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+	"github.com/alukart32/go-fast-key/internal/database/wal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewWAL(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		dir        string
+		logger     *zap.Logger
+		options    []wal.Option
+		wantErr    error
+		wantNilObj bool
+	}{
+		"create wal without logger": {
+			dir:        t.TempDir(),
+			wantErr:    errors.New("logger is nil"),
+			wantNilObj: true,
+		},
+		"create wal without directory": {
+			logger:     zap.NewNop(),
+			wantErr:    errors.New("data directory is empty"),
+			wantNilObj: true,
+		},
+		"create wal with invalid flush policy": {
+			dir:        t.TempDir(),
+			logger:     zap.NewNop(),
+			options:    []wal.Option{wal.WithFlushPolicy("sometimes")},
+			wantErr:    wal.ErrInvalidFlushPolicy,
+			wantNilObj: true,
+		},
+		"create wal with options": {
+			dir:    filepath.Join(t.TempDir(), "wal"),
+			logger: zap.NewNop(),
+			options: []wal.Option{
+				wal.WithFlushPolicy(wal.FlushPolicyNone),
+				wal.WithMaxSegmentSize(1 << 10),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			w, err := wal.NewWAL(test.dir, test.logger, test.options...)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantNilObj {
+				assert.Nil(t, w)
+			} else {
+				assert.NotNil(t, w)
+			}
+		})
+	}
+}
+
+func TestWAL_AppendWithoutRecover(t *testing.T) {
+	t.Parallel()
+
+	w, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+
+	err = w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key", "val"}}).Get()
+	assert.ErrorIs(t, err, wal.ErrNotRecovered)
+}
+
+func TestWAL_Recover(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	records := []wal.Record{
+		{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key_1", "val_1"}},
+		{LSN: 2, CommandID: compute.SetCommand, Arguments: []string{"key_2", "val with spaces"}},
+		{LSN: 3, CommandID: compute.DelCommand, Arguments: []string{"key_1"}},
+	}
+
+	w, err := wal.NewWAL(dir, zap.NewNop(), wal.WithMaxSegmentSize(32))
+	require.NoError(t, err)
+	require.NoError(t, w.Recover(0, func(wal.Record) error { return nil }))
+
+	require.NoError(t, w.Append(wal.Record{CommandID: records[0].CommandID, Arguments: records[0].Arguments}).Get())
+	require.NoError(t, w.Append(
+		wal.Record{CommandID: records[1].CommandID, Arguments: records[1].Arguments},
+		wal.Record{CommandID: records[2].CommandID, Arguments: records[2].Arguments},
+	).Get())
+	assert.EqualValues(t, 3, w.LSN())
+	require.NoError(t, w.Close())
+
+	segments, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, segments, 2, "small segments must be rotated")
+
+	w, err = wal.NewWAL(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	var recovered []wal.Record
+	require.NoError(t, w.Recover(0, func(r wal.Record) error {
+		recovered = append(recovered, r)
+		return nil
+	}))
+	assert.Equal(t, records, recovered)
+	assert.EqualValues(t, 3, w.LSN())
+
+	require.NoError(t, w.Append(wal.Record{CommandID: compute.DelCommand, Arguments: []string{"key_2"}}).Get())
+	assert.EqualValues(t, 4, w.LSN())
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_RecoverTornTail(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	w, err := wal.NewWAL(dir, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, w.Recover(0, func(wal.Record) error { return nil }))
+	require.NoError(t, w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key_1", "val_1"}}).Get())
+	require.NoError(t, w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key_2", "val_2"}}).Get())
+	require.NoError(t, w.Close())
+
+	segments, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+
+	path := filepath.Join(dir, segments[0].Name())
+	stat, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, stat.Size()-3))
+
+	w, err = wal.NewWAL(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	var recovered []wal.Record
+	require.NoError(t, w.Recover(0, func(r wal.Record) error {
+		recovered = append(recovered, r)
+		return nil
+	}))
+	require.Len(t, recovered, 1)
+	assert.Equal(t, []string{"key_1", "val_1"}, recovered[0].Arguments)
+	assert.EqualValues(t, 1, w.LSN())
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_FailedBatchTakesNoLSNs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	w, err := wal.NewWAL(dir, zap.NewNop(), wal.WithMaxSegmentSize(32))
+	require.NoError(t, err)
+	require.NoError(t, w.Recover(0, func(wal.Record) error { return nil }))
+	require.NoError(t, w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key_1", "val_1"}}).Get())
+
+	// The next segment can not be opened while a directory takes its name.
+	blocked := filepath.Join(dir, fmt.Sprintf("wal_%020d.log", 2))
+	require.NoError(t, os.Mkdir(blocked, 0o755))
+	assert.Error(t, w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key_2", "val_2"}}).Get())
+	assert.EqualValues(t, 1, w.LSN())
+
+	require.NoError(t, os.Remove(blocked))
+	require.NoError(t, w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key_3", "val_3"}}).Get())
+	assert.EqualValues(t, 2, w.LSN())
+	require.NoError(t, w.Close())
+
+	w, err = wal.NewWAL(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	var recovered []wal.Record
+	require.NoError(t, w.Recover(0, func(r wal.Record) error {
+		recovered = append(recovered, r)
+		return nil
+	}))
+	assert.Equal(t, []wal.Record{
+		{LSN: 1, CommandID: compute.SetCommand, Arguments: []string{"key_1", "val_1"}},
+		{LSN: 2, CommandID: compute.SetCommand, Arguments: []string{"key_3", "val_3"}},
+	}, recovered)
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_RecoverApplyError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	w, err := wal.NewWAL(dir, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, w.Recover(0, func(wal.Record) error { return nil }))
+	require.NoError(t, w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key", "val"}}).Get())
+	require.NoError(t, w.Close())
+
+	applyErr := errors.New("apply error")
+	w, err = wal.NewWAL(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	err = w.Recover(0, func(wal.Record) error { return applyErr })
+	assert.ErrorIs(t, err, applyErr)
+}
+
+func TestWAL_AppendBatches(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		options []wal.Option
+	}{
+		"flush by batch size": {
+			options: []wal.Option{
+				wal.WithFlushingBatchSize(10),
+				wal.WithFlushingBatchTimeout(time.Hour),
+			},
+		},
+		"flush by batch timeout": {
+			options: []wal.Option{
+				wal.WithFlushingBatchSize(1000),
+				wal.WithFlushingBatchTimeout(5 * time.Millisecond),
+			},
+		},
+		"flush with always policy": {
+			options: []wal.Option{
+				wal.WithFlushPolicy(wal.FlushPolicyAlways),
+				wal.WithFlushingBatchSize(10),
+			},
+		},
+		"flush with none policy": {
+			options: []wal.Option{
+				wal.WithFlushPolicy(wal.FlushPolicyNone),
+				wal.WithFlushingBatchSize(10),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			const writers = 50
+
+			dir := t.TempDir()
+			w, err := wal.NewWAL(dir, zap.NewNop(), test.options...)
+			require.NoError(t, err)
+			require.NoError(t, w.Recover(0, func(wal.Record) error { return nil }))
+
+			var wg sync.WaitGroup
+			wg.Add(writers)
+			for i := range writers {
+				go func() {
+					defer wg.Done()
+
+					err := w.Append(wal.Record{
+						CommandID: compute.SetCommand,
+						Arguments: []string{fmt.Sprintf("key_%d", i), "val"},
+					}).Get()
+					assert.NoError(t, err)
+				}()
+			}
+			wg.Wait()
+			require.NoError(t, w.Close())
+
+			w, err = wal.NewWAL(dir, zap.NewNop())
+			require.NoError(t, err)
+
+			var lsn uint64
+			require.NoError(t, w.Recover(0, func(r wal.Record) error {
+				lsn++
+				assert.Equal(t, lsn, r.LSN)
+				return nil
+			}))
+			assert.EqualValues(t, writers, lsn)
+			require.NoError(t, w.Close())
+		})
+	}
+}
+
+func TestWAL_CloseFlushesPendingRecords(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	w, err := wal.NewWAL(dir, zap.NewNop(),
+		wal.WithFlushingBatchSize(1000),
+		wal.WithFlushingBatchTimeout(time.Hour),
+	)
+	require.NoError(t, err)
+	require.NoError(t, w.Recover(0, func(wal.Record) error { return nil }))
+
+	future := w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key", "val"}})
+	require.NoError(t, w.Close())
+	require.NoError(t, future.Get())
+
+	err = w.Append(wal.Record{CommandID: compute.DelCommand, Arguments: []string{"key"}}).Get()
+	assert.ErrorIs(t, err, wal.ErrClosed)
+}
+
+func TestWAL_FlushObserver(t *testing.T) {
+	t.Parallel()
+
+	var flushes atomic.Int32
+	w, err := wal.NewWAL(t.TempDir(), zap.NewNop(),
+		wal.WithFlushingBatchTimeout(time.Millisecond),
+		wal.WithFlushObserver(func(d time.Duration) {
+			assert.Positive(t, d)
+			flushes.Add(1)
+		}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, w.Recover(0, func(wal.Record) error { return nil }))
+
+	require.NoError(t, w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key", "val"}}).Get())
+	require.NoError(t, w.Close())
+	assert.Equal(t, int32(1), flushes.Load(), "empty batches are not observed")
+}
+
+func TestWAL_Stats(t *testing.T) {
+	t.Parallel()
+
+	w, err := wal.NewWAL(t.TempDir(), zap.NewNop(), wal.WithFlushingBatchTimeout(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, w.Recover(0, func(wal.Record) error { return nil }))
+
+	stats, err := w.Stats()
+	require.NoError(t, err)
+	assert.Zero(t, stats.PendingRecords)
+	assert.True(t, stats.LastFlush.IsZero())
+
+	future := w.Append(wal.Record{CommandID: compute.SetCommand, Arguments: []string{"key", "val"}})
+	stats, err = w.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.PendingRecords)
+
+	require.NoError(t, w.Close())
+	require.NoError(t, future.Get())
+
+	stats, err = w.Stats()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats.LSN)
+	assert.Equal(t, 1, stats.Segments)
+	assert.Zero(t, stats.PendingRecords)
+	assert.False(t, stats.LastFlush.IsZero())
+	assert.NoError(t, stats.LastFlushErr)
+}
+
+func TestWAL_RecoverAfterCompaction(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	w, err := wal.NewWAL(dir, zap.NewNop(), wal.WithMaxSegmentSize(32))
+	require.NoError(t, err)
+	require.NoError(t, w.Recover(0, func(wal.Record) error { return nil }))
+	for i := range 5 {
+		err := w.Append(wal.Record{
+			CommandID: compute.SetCommand,
+			Arguments: []string{fmt.Sprintf("key_%d", i), "val"},
+		}).Get()
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Compact(3))
+	require.NoError(t, w.Close())
+
+	segments, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, segments, 2, "segments with records up to lsn 3 must be removed")
+
+	w, err = wal.NewWAL(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	var lsns []uint64
+	require.NoError(t, w.Recover(3, func(r wal.Record) error {
+		lsns = append(lsns, r.LSN)
+		return nil
+	}))
+	assert.Equal(t, []uint64{4, 5}, lsns)
+	require.NoError(t, w.Close())
+
+	w, err = wal.NewWAL(dir, zap.NewNop())
+	require.NoError(t, err)
+
+	err = w.Recover(1, func(wal.Record) error { return nil })
+	assert.ErrorIs(t, err, wal.ErrLSNGap)
+}
+
+func TestWAL_RecoverFromSnapshotLSN(t *testing.T) {
+	t.Parallel()
+
+	w, err := wal.NewWAL(t.TempDir(), zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, w.Recover(10, func(wal.Record) error { return nil }))
+
+	require.NoError(t, w.Append(wal.Record{CommandID: compute.DelCommand, Arguments: []string{"key"}}).Get())
+	assert.EqualValues(t, 11, w.LSN(), "lsn must continue from the snapshot lsn")
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_ReplicateSegments(t *testing.T) {
+	t.Parallel()
+
+	masterDir, slaveDir := t.TempDir(), t.TempDir()
+
+	master, err := wal.NewWAL(masterDir, zap.NewNop(), wal.WithMaxSegmentSize(64))
+	require.NoError(t, err)
+	require.NoError(t, master.Recover(0, func(wal.Record) error { return nil }))
+	defer master.Close()
+
+	slave, err := wal.NewWAL(slaveDir, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, slave.Recover(0, func(wal.Record) error { return nil }))
+
+	chunk, err := master.ReadSegment(wal.SegmentPosition{})
+	require.NoError(t, err)
+	assert.Zero(t, chunk.FirstLSN)
+	assert.Empty(t, chunk.Data)
+	assert.True(t, chunk.Last, "empty master log has nothing to replicate")
+
+	for i := range 10 {
+		err := master.Append(wal.Record{
+			CommandID: compute.SetCommand,
+			Arguments: []string{fmt.Sprintf("key_%d", i), "val"},
+		}).Get()
+		require.NoError(t, err)
+	}
+
+	var (
+		applied []uint64
+		pos     wal.SegmentPosition
+	)
+	for {
+		chunk, err := master.ReadSegment(pos)
+		require.NoError(t, err)
+		require.NoError(t, slave.ApplySegment(chunk.FirstLSN, chunk.Data, func(r wal.Record) error {
+			applied = append(applied, r.LSN)
+			return nil
+		}))
+
+		// The frames already applied are skipped, the segment is not appended twice.
+		require.NoError(t, slave.ApplySegment(chunk.FirstLSN, chunk.Data, func(r wal.Record) error {
+			applied = append(applied, r.LSN)
+			return nil
+		}))
+		pos = wal.SegmentPosition{LSN: slave.LSN(), FirstLSN: chunk.FirstLSN, Offset: chunk.Offset}
+		if chunk.Last {
+			break
+		}
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, applied)
+	assert.EqualValues(t, 10, slave.LSN())
+
+	chunk, err = master.ReadSegment(pos)
+	require.NoError(t, err)
+	assert.Empty(t, chunk.Data, "caught up slave must get no frames")
+	assert.Equal(t, pos.Offset, chunk.Offset)
+	assert.True(t, chunk.Last)
+
+	require.NoError(t, master.Append(wal.Record{
+		CommandID: compute.SetCommand,
+		Arguments: []string{"key_10", "val"},
+	}).Get())
+
+	// The segment is read from its start if the offset does not continue the slave lsn.
+	for _, offset := range []int64{0, 1, pos.Offset + 1, 1 << 20} {
+		stale, err := master.ReadSegment(wal.SegmentPosition{LSN: slave.LSN(), FirstLSN: pos.FirstLSN, Offset: offset})
+		require.NoError(t, err)
+		assert.NotEmpty(t, stale.Data, "offset %d", offset)
+	}
+
+	chunk, err = master.ReadSegment(pos)
+	require.NoError(t, err)
+	applied = nil
+	require.NoError(t, slave.ApplySegment(chunk.FirstLSN, chunk.Data, func(r wal.Record) error {
+		applied = append(applied, r.LSN)
+		return nil
+	}))
+	assert.Equal(t, []uint64{11}, applied, "only the frames after the slave lsn must be sent")
+	require.NoError(t, slave.Close())
+
+	masterSegments, err := os.ReadDir(masterDir)
+	require.NoError(t, err)
+	slaveSegments, err := os.ReadDir(slaveDir)
+	require.NoError(t, err)
+	require.Equal(t, len(masterSegments), len(slaveSegments))
+	for i := range masterSegments {
+		assert.Equal(t, masterSegments[i].Name(), slaveSegments[i].Name())
+
+		want, err := os.ReadFile(filepath.Join(masterDir, masterSegments[i].Name()))
+		require.NoError(t, err)
+		got, err := os.ReadFile(filepath.Join(slaveDir, slaveSegments[i].Name()))
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	slave, err = wal.NewWAL(slaveDir, zap.NewNop())
+	require.NoError(t, err)
+	var recovered int
+	require.NoError(t, slave.Recover(0, func(wal.Record) error {
+		recovered++
+		return nil
+	}))
+	assert.Equal(t, 11, recovered, "replicated segments must be recoverable")
+	require.NoError(t, slave.Close())
+
+	require.NoError(t, master.Compact(master.LSN()))
+	_, err = master.ReadSegment(wal.SegmentPosition{})
+	assert.ErrorIs(t, err, wal.ErrSegmentCompacted)
+}
+
+func TestWAL_Reset(t *testing.T) {
+	t.Parallel()
+
+	master, err := wal.NewWAL(t.TempDir(), zap.NewNop(), wal.WithMaxSegmentSize(64))
+	require.NoError(t, err)
+	require.NoError(t, master.Recover(0, func(wal.Record) error { return nil }))
+	defer master.Close()
+
+	for i := range 12 {
+		err := master.Append(wal.Record{
+			CommandID: compute.SetCommand,
+			Arguments: []string{fmt.Sprintf("key_%d", i), "val"},
+		}).Get()
+		require.NoError(t, err)
+	}
+
+	slaveDir := t.TempDir()
+	slave, err := wal.NewWAL(slaveDir, zap.NewNop())
+	require.NoError(t, err)
+	assert.ErrorIs(t, slave.Reset(10), wal.ErrNotRecovered)
+	require.NoError(t, slave.Recover(0, func(wal.Record) error { return nil }))
+
+	chunk, err := master.ReadSegment(wal.SegmentPosition{})
+	require.NoError(t, err)
+	require.NoError(t, slave.ApplySegment(chunk.FirstLSN, chunk.Data, func(wal.Record) error { return nil }))
+
+	require.NoError(t, slave.Reset(10))
+	assert.EqualValues(t, 10, slave.LSN())
+	segments, err := os.ReadDir(slaveDir)
+	require.NoError(t, err)
+	assert.Empty(t, segments)
+
+	var applied []uint64
+	for {
+		chunk, err := master.ReadSegment(wal.SegmentPosition{LSN: slave.LSN()})
+		require.NoError(t, err)
+		require.NoError(t, slave.ApplySegment(chunk.FirstLSN, chunk.Data, func(r wal.Record) error {
+			applied = append(applied, r.LSN)
+			return nil
+		}))
+		if chunk.Last {
+			break
+		}
+	}
+	assert.Equal(t, []uint64{11, 12}, applied)
+	require.NoError(t, slave.Close())
+
+	slave, err = wal.NewWAL(slaveDir, zap.NewNop())
+	require.NoError(t, err)
+	var recovered []uint64
+	require.NoError(t, slave.Recover(10, func(r wal.Record) error {
+		recovered = append(recovered, r.LSN)
+		return nil
+	}))
+	assert.Equal(t, []uint64{11, 12}, recovered, "the reset log must be recoverable after the snapshot")
+	require.NoError(t, slave.Close())
+}
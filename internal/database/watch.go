@@ -0,0 +1,73 @@
+package database
+
+import (
+	"sync"
+
+	"github.com/alukart32/go-fast-key/internal/session"
+)
+
+// watchRegistry tracks the transactions watching the keys.
+type watchRegistry struct {
+	mtx  sync.Mutex
+	keys map[string]map[*session.Transaction]struct{}
+}
+
+func newWatchRegistry() *watchRegistry {
+	return &watchRegistry{
+		keys: make(map[string]map[*session.Transaction]struct{}),
+	}
+}
+
+// Watch starts watching the keys by the transaction.
+func (r *watchRegistry) Watch(t *session.Transaction, keys ...string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, k := range keys {
+		watchers, found := r.keys[k]
+		if !found {
+			watchers = make(map[*session.Transaction]struct{})
+			r.keys[k] = watchers
+		}
+		watchers[t] = struct{}{}
+	}
+	t.Watch(r, keys...)
+}
+
+// Unwatch stops watching all the keys of the transaction.
+func (r *watchRegistry) Unwatch(t *session.Transaction) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, k := range t.Watched() {
+		watchers := r.keys[k]
+		delete(watchers, t)
+		if len(watchers) == 0 {
+			delete(r.keys, k)
+		}
+	}
+}
+
+// TouchAll invalidates all the watching transactions, the whole state is replaced.
+func (r *watchRegistry) TouchAll() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, watchers := range r.keys {
+		for t := range watchers {
+			t.Invalidate()
+		}
+	}
+}
+
+// Touch invalidates the transactions watching the modified keys.
+func (r *watchRegistry) Touch(keys ...string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, k := range keys {
+		for t := range r.keys[k] {
+			t.Invalidate()
+		}
+	}
+}
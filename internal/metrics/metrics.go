@@ -0,0 +1,362 @@
+// Package metrics implements the metrics exposed in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Kind defines the Prometheus type of the metric.
+type Kind string
+
+const (
+	CounterKind   Kind = "counter"
+	GaugeKind     Kind = "gauge"
+	HistogramKind Kind = "histogram"
+)
+
+// DefaultBuckets are the upper bounds of the latency histograms in seconds.
+var DefaultBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// Labels defines the label names and values of a metric.
+type Labels map[string]string
+
+// metric writes the samples of a metric of the family.
+type metric interface {
+	write(w *bufio.Writer, name string)
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    Kind
+	metrics []metric
+}
+
+// Registry collects the metrics and writes them in the text format.
+type Registry struct {
+	mtx      sync.Mutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// register adds the metric to the family of the name, the family is created by the first metric.
+func (r *Registry) register(name, help string, kind Kind, m metric) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	f, found := r.families[name]
+	if !found {
+		f = &family{name: name, help: help, kind: kind}
+		r.families[name] = f
+	}
+	if f.kind != kind {
+		panic(fmt.Sprintf("metric %v is registered as %v", name, f.kind))
+	}
+	f.metrics = append(f.metrics, m)
+}
+
+// Counter registers a counter with the labels.
+func (r *Registry) Counter(name, help string, labels Labels) *Counter {
+	c := &Counter{labels: formatLabels(labels)}
+	r.register(name, help, CounterKind, c)
+	return c
+}
+
+// CounterFunc registers a counter whose value is read from f on every scrape.
+func (r *Registry) CounterFunc(name, help string, labels Labels, f func() float64) {
+	r.register(name, help, CounterKind, &valueFunc{labels: formatLabels(labels), f: f})
+}
+
+// GaugeFunc registers a gauge whose value is read from f on every scrape.
+func (r *Registry) GaugeFunc(name, help string, labels Labels, f func() float64) {
+	r.register(name, help, GaugeKind, &valueFunc{labels: formatLabels(labels), f: f})
+}
+
+// Histogram registers a histogram with the upper bounds of its buckets.
+func (r *Registry) Histogram(name, help string, labels Labels, buckets []float64) *Histogram {
+	h := newHistogram(formatLabels(labels), buckets)
+	r.register(name, help, HistogramKind, h)
+	return h
+}
+
+// CounterVec registers the counters that differ by the value of the label.
+func (r *Registry) CounterVec(name, help, label string) *CounterVec {
+	v := &CounterVec{label: label, counters: make(map[string]*Counter)}
+	r.register(name, help, CounterKind, v)
+	return v
+}
+
+// HistogramVec registers the histograms that differ by the value of the label.
+func (r *Registry) HistogramVec(name, help, label string, buckets []float64) *HistogramVec {
+	v := &HistogramVec{label: label, buckets: buckets, histograms: make(map[string]*Histogram)}
+	r.register(name, help, HistogramKind, v)
+	return v
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format, the families are sorted by name.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mtx.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, &family{name: f.name, help: f.help, kind: f.kind, metrics: slices.Clone(f.metrics)})
+	}
+	r.mtx.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, f := range families {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.name, f.kind)
+		for _, m := range f.metrics {
+			m.write(bw, f.name)
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler serves the metrics to the Prometheus scrapes.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	labels string
+	v      atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+func (c *Counter) write(w *bufio.Writer, name string) {
+	writeSample(w, name, c.labels, float64(c.v.Load()))
+}
+
+type valueFunc struct {
+	labels string
+	f      func() float64
+}
+
+func (v *valueFunc) write(w *bufio.Writer, name string) {
+	writeSample(w, name, v.labels, v.f())
+}
+
+// Histogram counts the observed values by buckets.
+type Histogram struct {
+	labels  string
+	bounds  []float64
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sumBits atomic.Uint64
+}
+
+func newHistogram(labels string, buckets []float64) *Histogram {
+	bounds := slices.Clone(buckets)
+	slices.Sort(bounds)
+	return &Histogram{
+		labels: labels,
+		bounds: bounds,
+		counts: make([]atomic.Uint64, len(bounds)),
+	}
+}
+
+// Observe adds the value to the histogram.
+func (h *Histogram) Observe(v float64) {
+	if i, _ := slices.BinarySearch(h.bounds, v); i < len(h.bounds) {
+		h.counts[i].Add(1)
+	}
+	h.count.Add(1)
+
+	for {
+		old := h.sumBits.Load()
+		if h.sumBits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Count returns the number of the observed values.
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+func (h *Histogram) write(w *bufio.Writer, name string) {
+	// The count is read first, so the cumulative buckets never exceed it.
+	count := h.count.Load()
+	sum := math.Float64frombits(h.sumBits.Load())
+
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i].Load()
+		writeSample(w, name+"_bucket", joinLabels(h.labels, formatLabel("le", formatFloat(bound))), float64(min(cumulative, count)))
+	}
+	writeSample(w, name+"_bucket", joinLabels(h.labels, formatLabel("le", "+Inf")), float64(count))
+	writeSample(w, name+"_sum", h.labels, sum)
+	writeSample(w, name+"_count", h.labels, float64(count))
+}
+
+// CounterVec is a set of counters that differ by the value of the label.
+type CounterVec struct {
+	label    string
+	mtx      sync.RWMutex
+	counters map[string]*Counter
+}
+
+// With returns the counter of the label value.
+func (v *CounterVec) With(value string) *Counter {
+	v.mtx.RLock()
+	c, found := v.counters[value]
+	v.mtx.RUnlock()
+	if found {
+		return c
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if c, found = v.counters[value]; !found {
+		c = &Counter{labels: formatLabel(v.label, value)}
+		v.counters[value] = c
+	}
+	return c
+}
+
+func (v *CounterVec) write(w *bufio.Writer, name string) {
+	v.mtx.RLock()
+	defer v.mtx.RUnlock()
+	for _, value := range sortedKeys(v.counters) {
+		v.counters[value].write(w, name)
+	}
+}
+
+// HistogramVec is a set of histograms that differ by the value of the label.
+type HistogramVec struct {
+	label      string
+	buckets    []float64
+	mtx        sync.RWMutex
+	histograms map[string]*Histogram
+}
+
+// With returns the histogram of the label value.
+func (v *HistogramVec) With(value string) *Histogram {
+	v.mtx.RLock()
+	h, found := v.histograms[value]
+	v.mtx.RUnlock()
+	if found {
+		return h
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if h, found = v.histograms[value]; !found {
+		h = newHistogram(formatLabel(v.label, value), v.buckets)
+		v.histograms[value] = h
+	}
+	return h
+}
+
+func (v *HistogramVec) write(w *bufio.Writer, name string) {
+	v.mtx.RLock()
+	defer v.mtx.RUnlock()
+	for _, value := range sortedKeys(v.histograms) {
+		v.histograms[value].write(w, name)
+	}
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteByte('{')
+		w.WriteString(labels)
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatLabels(labels Labels) string {
+	parts := make([]string, 0, len(labels))
+	for _, name := range sortedKeys(labels) {
+		parts = append(parts, formatLabel(name, labels[name]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatLabel(name, value string) string {
+	return name + `="` + labelValueEscaper.Replace(value) + `"`
+}
+
+func joinLabels(labels, label string) string {
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,92 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+
+	commands := registry.CounterVec("app_commands_total", "Number of the commands.", "command")
+	commands.With("SET").Add(2)
+	commands.With("GET").Inc()
+
+	latency := registry.Histogram("app_latency_seconds", "Latency.", metrics.Labels{"path": `a"b`}, []float64{1, 0.1})
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(2)
+
+	registry.GaugeFunc("app_connections", "Open connections.", metrics.Labels{"listener": "a"}, func() float64 { return 1 })
+	registry.GaugeFunc("app_connections", "Open connections.", metrics.Labels{"listener": "b"}, func() float64 { return 2 })
+	registry.Counter("app_errors_total", "Errors\nby line.", nil)
+
+	var buf bytes.Buffer
+	n, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	assert.Equal(t, `# HELP app_commands_total Number of the commands.
+# TYPE app_commands_total counter
+app_commands_total{command="GET"} 1
+app_commands_total{command="SET"} 2
+# HELP app_connections Open connections.
+# TYPE app_connections gauge
+app_connections{listener="a"} 1
+app_connections{listener="b"} 2
+# HELP app_errors_total Errors\nby line.
+# TYPE app_errors_total counter
+app_errors_total 0
+# HELP app_latency_seconds Latency.
+# TYPE app_latency_seconds histogram
+app_latency_seconds_bucket{path="a\"b",le="0.1"} 1
+app_latency_seconds_bucket{path="a\"b",le="1"} 2
+app_latency_seconds_bucket{path="a\"b",le="+Inf"} 3
+app_latency_seconds_sum{path="a\"b"} 2.55
+app_latency_seconds_count{path="a\"b"} 3
+`, buf.String())
+}
+
+func TestRegistry_KindConflict(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	registry.Counter("app_total", "Total.", nil)
+	assert.Panics(t, func() {
+		registry.GaugeFunc("app_total", "Total.", nil, func() float64 { return 0 })
+	})
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	registry.Counter("app_total", "Total.", nil).Inc()
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "# HELP app_total Total.\n# TYPE app_total counter\napp_total 1\n", recorder.Body.String())
+}
+
+func TestHistogramVec(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	durations := registry.HistogramVec("app_duration_seconds", "Duration.", "command", metrics.DefaultBuckets)
+
+	durations.With("GET").Observe(0.001)
+	durations.With("GET").Observe(0.002)
+	durations.With("SET").Observe(0.001)
+
+	assert.Equal(t, uint64(2), durations.With("GET").Count())
+	assert.Equal(t, uint64(1), durations.With("SET").Count())
+}
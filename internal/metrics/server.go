@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server serves the metrics of the registry over HTTP.
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+	logger   *zap.Logger
+}
+
+// NewServer listens on the address and serves the registry on the path.
+func NewServer(address, path string, registry *Registry, logger *zap.Logger) (*Server, error) {
+	if registry == nil {
+		return nil, errors.New("registry is nil")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is nil")
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("fail to listen: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, registry.Handler())
+
+	return &Server{
+		listener: listener,
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		logger: logger,
+	}, nil
+}
+
+// Serve serves the scrapes until ctx is done.
+func (s *Server) Serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := s.server.Shutdown(shutdownCtx); err != nil {
+			s.logger.Warn("fail to shut down metrics server", zap.Error(err))
+		}
+	}()
+
+	if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Error("fail to serve metrics", zap.Error(err))
+	}
+}
+
+// Close stops listening of the server that is not serving yet.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) Address() string {
+	return s.listener.Addr().String()
+}
@@ -0,0 +1,57 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewServer(t *testing.T) {
+	t.Parallel()
+
+	_, err := metrics.NewServer("localhost:0", "/metrics", nil, zap.NewNop())
+	assert.EqualError(t, err, "registry is nil")
+
+	_, err = metrics.NewServer("localhost:0", "/metrics", metrics.NewRegistry(), nil)
+	assert.EqualError(t, err, "logger is nil")
+}
+
+func TestServer_Serve(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	registry.Counter("app_total", "Total.", nil).Add(3)
+
+	server, err := metrics.NewServer("localhost:0", "/metrics", registry, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Serve(ctx)
+	}()
+
+	response, err := http.Get("http://" + server.Address() + "/metrics")
+	require.NoError(t, err)
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, string(body), "app_total 3\n")
+
+	response, err = http.Get("http://" + server.Address() + "/other")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,116 @@
+package network
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FrameType defines the kind of the frame payload.
+type FrameType byte
+
+const (
+	// RequestFrame holds a request of the client.
+	RequestFrame FrameType = iota + 1
+	// ResponseFrame holds a response of the server.
+	ResponseFrame
+	// ErrorFrame holds the reason the server refused to handle the request.
+	ErrorFrame
+)
+
+// The frame layout:
+//
+//	type    byte
+//	length  uint32, big-endian length of the payload
+//	payload [length]byte
+const frameHeaderSize = 1 + 4
+
+var (
+	ErrFrameTooLarge = errors.New("frame is too large")
+	ErrInvalidFrame  = errors.New("invalid frame")
+)
+
+// WriteFrame writes the payload as one frame of type t.
+func WriteFrame(w io.Writer, t FrameType, payload []byte) error {
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(payload))
+	frame[0] = byte(t)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	frame = append(frame, payload...)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// FrameReader reads the frames from the stream reusing its buffer.
+type FrameReader struct {
+	r       *bufio.Reader
+	buf     []byte
+	maxSize int
+}
+
+// NewFrameReader creates a new FrameReader that accepts payloads up to maxSize bytes,
+// zero maxSize means no limit.
+func NewFrameReader(r io.Reader, maxSize int) *FrameReader {
+	return &FrameReader{
+		r:       bufio.NewReader(r),
+		maxSize: maxSize,
+	}
+}
+
+// Read reads the next frame, its payload is valid until the next call.
+//
+// The payload of the too large frame is skipped, so the stream stays at the next frame.
+// io.EOF is returned only if the stream ends between the frames.
+func (fr *FrameReader) Read() (FrameType, []byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(fr.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, fmt.Errorf("%w: truncated header", ErrInvalidFrame)
+		}
+		return 0, nil, err
+	}
+
+	t := FrameType(header[0])
+	if t < RequestFrame || t > ErrorFrame {
+		return 0, nil, fmt.Errorf("%w: unknown type %d", ErrInvalidFrame, t)
+	}
+
+	size := int(binary.BigEndian.Uint32(header[1:]))
+	if fr.maxSize > 0 && size > fr.maxSize {
+		if _, err := io.CopyN(io.Discard, fr.r, int64(size)); err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, nil, fmt.Errorf("%w: truncated payload", ErrInvalidFrame)
+			}
+			return 0, nil, err
+		}
+		return 0, nil, ErrFrameTooLarge
+	}
+
+	if cap(fr.buf) < size {
+		fr.buf = make([]byte, size)
+	}
+	payload := fr.buf[:size]
+	if _, err := io.ReadFull(fr.r, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, fmt.Errorf("%w: truncated payload", ErrInvalidFrame)
+		}
+		return 0, nil, err
+	}
+	return t, payload, nil
+}
+
+// Buffered reports whether a complete frame is already buffered, so it is read without blocking.
+func (fr *FrameReader) Buffered() bool {
+	n := fr.r.Buffered()
+	if n < frameHeaderSize {
+		return false
+	}
+
+	header, err := fr.r.Peek(frameHeaderSize)
+	if err != nil {
+		return false
+	}
+	return n >= frameHeaderSize+int(binary.BigEndian.Uint32(header[1:]))
+}
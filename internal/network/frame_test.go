@@ -0,0 +1,101 @@
+package network_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameReader(t *testing.T) {
+	t.Parallel()
+
+	frame := func(t network.FrameType, payload string) []byte {
+		var buf bytes.Buffer
+		_ = network.WriteFrame(&buf, t, []byte(payload))
+		return buf.Bytes()
+	}
+
+	tests := map[string]struct {
+		data    []byte
+		maxSize int
+
+		wantType    network.FrameType
+		wantPayload string
+		wantErr     error
+	}{
+		"request frame": {
+			data:        frame(network.RequestFrame, "SET key value"),
+			wantType:    network.RequestFrame,
+			wantPayload: "SET key value",
+		},
+		"payload with spaces and new lines": {
+			data:        frame(network.ResponseFrame, " a b \n c\r\n"),
+			wantType:    network.ResponseFrame,
+			wantPayload: " a b \n c\r\n",
+		},
+		"empty payload": {
+			data:     frame(network.ResponseFrame, ""),
+			wantType: network.ResponseFrame,
+		},
+		"payload of max size": {
+			data:        frame(network.RequestFrame, "12345678"),
+			maxSize:     8,
+			wantType:    network.RequestFrame,
+			wantPayload: "12345678",
+		},
+		"too large payload": {
+			data:    frame(network.RequestFrame, "123456789"),
+			maxSize: 8,
+			wantErr: network.ErrFrameTooLarge,
+		},
+		"unknown frame type": {
+			data:    frame(network.FrameType(42), "GET key"),
+			wantErr: network.ErrInvalidFrame,
+		},
+		"truncated header": {
+			data:    frame(network.RequestFrame, "GET key")[:3],
+			wantErr: network.ErrInvalidFrame,
+		},
+		"truncated payload": {
+			data:    frame(network.RequestFrame, "GET key")[:8],
+			wantErr: network.ErrInvalidFrame,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			frameType, payload, err := network.NewFrameReader(bytes.NewReader(test.data), test.maxSize).Read()
+			if test.wantErr != nil {
+				assert.True(t, errors.Is(err, test.wantErr), "Read() error = %v, wantErr %v", err, test.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.wantType, frameType)
+			assert.Equal(t, test.wantPayload, string(payload))
+		})
+	}
+}
+
+func TestFrameReader_SkipsTooLargeFrame(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, network.WriteFrame(&buf, network.ResponseFrame, bytes.Repeat([]byte("a"), 100)))
+	require.NoError(t, network.WriteFrame(&buf, network.ResponseFrame, []byte("next")))
+
+	reader := network.NewFrameReader(&buf, 8)
+	_, _, err := reader.Read()
+	assert.ErrorIs(t, err, network.ErrFrameTooLarge)
+
+	frameType, payload, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, network.ResponseFrame, frameType)
+	assert.Equal(t, "next", string(payload))
+}
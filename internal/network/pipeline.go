@@ -0,0 +1,78 @@
+package network
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Pipeline queues the requests of the client to send them with a single write.
+type Pipeline struct {
+	client   *TCPClient
+	requests [][]byte
+}
+
+// Pipeline returns a new empty pipeline of the client.
+func (c *TCPClient) Pipeline() *Pipeline {
+	return &Pipeline{client: c}
+}
+
+// Queue adds the request to the pipeline.
+func (p *Pipeline) Queue(request []byte) {
+	p.requests = append(p.requests, request)
+}
+
+// Len returns the number of the queued requests.
+func (p *Pipeline) Len() int {
+	return len(p.requests)
+}
+
+// Exec sends the queued requests and returns their responses in the same order.
+//
+// The responses are read while the requests are written, so the server is never
+// blocked by the responses of a large pipeline. The pipeline is empty after Exec.
+func (p *Pipeline) Exec() ([][]byte, error) {
+	requests := p.requests
+	p.requests = nil
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	for _, request := range requests {
+		if len(request) > p.client.bufferSize {
+			return nil, ErrFrameTooLarge
+		}
+		if err := WriteFrame(&buf, RequestFrame, request); err != nil {
+			return nil, err
+		}
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := p.client.conn.Write(buf.Bytes())
+		written <- err
+	}()
+
+	responses := make([][]byte, 0, len(requests))
+	for range requests {
+		frameType, response, err := p.client.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		switch frameType {
+		case ResponseFrame:
+			responses = append(responses, bytes.Clone(response))
+		case ErrorFrame:
+			return nil, fmt.Errorf("server error: %s", response)
+		default:
+			return nil, ErrInvalidFrame
+		}
+	}
+
+	if err := <-written; err != nil {
+		return nil, err
+	}
+
+	return responses, nil
+}
@@ -0,0 +1,68 @@
+package network_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop())
+	require.NoError(t, err)
+
+	go server.HandleQueries(ctx, func(ctx context.Context, _ *session.Session, data []byte) []byte {
+		return append([]byte("echo:"), data...)
+	})
+
+	client, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+	defer client.Close()
+
+	tests := map[string]struct {
+		requests int
+	}{
+		"empty pipeline": {
+			requests: 0,
+		},
+		"single request": {
+			requests: 1,
+		},
+		"many requests": {
+			requests: 10000,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pipeline := client.Pipeline()
+			for i := range test.requests {
+				pipeline.Queue([]byte("GET key_" + strconv.Itoa(i)))
+			}
+			require.Equal(t, test.requests, pipeline.Len())
+
+			responses, err := pipeline.Exec()
+			require.NoError(t, err)
+			require.Len(t, responses, test.requests)
+			for i, response := range responses {
+				assert.Equal(t, "echo:GET key_"+strconv.Itoa(i), string(response))
+			}
+			assert.Zero(t, pipeline.Len())
+		})
+	}
+
+	// The connection is still usable for the single requests.
+	response, err := client.Send([]byte("PING"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo:PING", string(response))
+}
@@ -0,0 +1,230 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alukart32/go-fast-key/internal/session"
+)
+
+// RESPKind defines the type of a RESP reply.
+type RESPKind byte
+
+const (
+	RESPSimpleString RESPKind = iota + 1
+	RESPError
+	RESPInteger
+	RESPBulkString
+	RESPNull
+	RESPArray
+	RESPMap
+	RESPNullArray
+)
+
+// RESPValue defines a reply of the RESP protocol.
+type RESPValue struct {
+	Kind  RESPKind
+	Str   string
+	Int   int64
+	Array []RESPValue
+}
+
+// RESPHandler handles the command arguments of the client session received over the RESP protocol.
+type RESPHandler = func(ctx context.Context, sess *session.Session, args []string) RESPValue
+
+// SimpleString returns the RESP simple string reply.
+func SimpleString(s string) RESPValue {
+	return RESPValue{Kind: RESPSimpleString, Str: s}
+}
+
+// ErrorValue returns the RESP error reply, the message starts with the error code.
+func ErrorValue(msg string) RESPValue {
+	return RESPValue{Kind: RESPError, Str: msg}
+}
+
+// Integer returns the RESP integer reply.
+func Integer(v int64) RESPValue {
+	return RESPValue{Kind: RESPInteger, Int: v}
+}
+
+// BulkString returns the RESP bulk string reply.
+func BulkString(s string) RESPValue {
+	return RESPValue{Kind: RESPBulkString, Str: s}
+}
+
+// Null returns the RESP null reply, it differs from the error reply.
+func Null() RESPValue {
+	return RESPValue{Kind: RESPNull}
+}
+
+// NullArray returns the RESP null array reply, RESP3 clients get it as the null reply.
+func NullArray() RESPValue {
+	return RESPValue{Kind: RESPNullArray}
+}
+
+// Array returns the RESP array reply.
+func Array(values ...RESPValue) RESPValue {
+	return RESPValue{Kind: RESPArray, Array: values}
+}
+
+// Map returns the RESP3 map reply of the key-value pairs, RESP2 clients get it as a flat array.
+func Map(pairs ...RESPValue) RESPValue {
+	return RESPValue{Kind: RESPMap, Array: pairs}
+}
+
+var ErrInvalidRESP = errors.New("invalid resp request")
+
+// respReader reads the RESP commands: arrays of bulk strings or inline commands.
+type respReader struct {
+	r       *bufio.Reader
+	maxSize int
+}
+
+func newRESPReader(r io.Reader, maxSize int) *respReader {
+	return &respReader{
+		r:       bufio.NewReader(r),
+		maxSize: maxSize,
+	}
+}
+
+// read reads the next command, io.EOF is returned only if the stream ends between the commands.
+func (rr *respReader) read() ([]string, error) {
+	line, err := rr.line()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 0 || count > rr.maxSize {
+		return nil, fmt.Errorf("%w: array length %q", ErrInvalidRESP, line[1:])
+	}
+
+	args := make([]string, 0, count)
+	size := 0
+	for range count {
+		line, err := rr.line()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("%w: bulk string expected", ErrInvalidRESP)
+		}
+
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("%w: bulk string length %q", ErrInvalidRESP, line[1:])
+		}
+		// The length is compared with the rest of the limit before it is added, so the size can not overflow.
+		if length > rr.maxSize-size {
+			return nil, ErrFrameTooLarge
+		}
+		size += length
+
+		arg := make([]byte, length+2)
+		if _, err := io.ReadFull(rr.r, arg); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if arg[length] != '\r' || arg[length+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string is not terminated", ErrInvalidRESP)
+		}
+		args = append(args, string(arg[:length]))
+	}
+	return args, nil
+}
+
+// buffered reports whether the next command is already being received.
+func (rr *respReader) buffered() bool {
+	return rr.r.Buffered() != 0
+}
+
+// line reads a CRLF terminated line without the terminator.
+func (rr *respReader) line() (string, error) {
+	var line []byte
+	for {
+		chunk, err := rr.r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			if len(line) != 0 {
+				return "", unexpectedEOF(err)
+			}
+			return "", err
+		}
+		if len(line) > rr.maxSize {
+			return "", ErrFrameTooLarge
+		}
+	}
+
+	line = line[:len(line)-1]
+	if n := len(line); n != 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return string(line), nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated request", ErrInvalidRESP)
+	}
+	return err
+}
+
+// appendRESP appends the encoded value in the protocol version.
+func appendRESP(buf []byte, v RESPValue, version int) []byte {
+	switch v.Kind {
+	case RESPSimpleString:
+		buf = append(buf, '+')
+		buf = append(buf, respLine(v.Str)...)
+		return append(buf, "\r\n"...)
+	case RESPError:
+		buf = append(buf, '-')
+		buf = append(buf, respLine(v.Str)...)
+		return append(buf, "\r\n"...)
+	case RESPInteger:
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, v.Int, 10)
+		return append(buf, "\r\n"...)
+	case RESPBulkString:
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(v.Str)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, v.Str...)
+		return append(buf, "\r\n"...)
+	case RESPArray, RESPMap:
+		if v.Kind == RESPMap && version >= 3 {
+			buf = append(buf, '%')
+			buf = strconv.AppendInt(buf, int64(len(v.Array)/2), 10)
+		} else {
+			buf = append(buf, '*')
+			buf = strconv.AppendInt(buf, int64(len(v.Array)), 10)
+		}
+		buf = append(buf, "\r\n"...)
+		for _, item := range v.Array {
+			buf = appendRESP(buf, item, version)
+		}
+		return buf
+	default:
+		if version >= 3 {
+			return append(buf, "_\r\n"...)
+		}
+		if v.Kind == RESPNullArray {
+			return append(buf, "*-1\r\n"...)
+		}
+		return append(buf, "$-1\r\n"...)
+	}
+}
+
+// respLine replaces the line breaks that are not allowed in simple strings and errors.
+func respLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,217 @@
+package network_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTCPServer_HandleRESP(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(), network.WithServerProtocol(network.RESPProtocol))
+	require.NoError(t, err)
+	assert.Equal(t, network.RESPProtocol, server.Protocol())
+
+	go server.HandleRESP(ctx, func(_ context.Context, _ *session.Session, args []string) network.RESPValue {
+		switch args[0] {
+		case "GET":
+			if args[1] == "missing" {
+				return network.Null()
+			}
+			return network.BulkString("value\r\nwith lines")
+		case "TTL":
+			return network.Integer(-2)
+		case "SET", "PING":
+			return network.SimpleString("OK")
+		default:
+			return network.ErrorValue("ERR unknown command")
+		}
+	})
+
+	tests := []struct {
+		name     string
+		request  string
+		response string
+	}{
+		{
+			name:     "Bulk string reply",
+			request:  "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n",
+			response: "$17\r\nvalue\r\nwith lines\r\n",
+		},
+		{
+			name:     "RESP2 null reply",
+			request:  "*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n",
+			response: "$-1\r\n",
+		},
+		{
+			name:     "Integer reply",
+			request:  "*2\r\n$3\r\nTTL\r\n$3\r\nkey\r\n",
+			response: ":-2\r\n",
+		},
+		{
+			name:     "Simple string reply to inline command",
+			request:  "SET key value\r\n",
+			response: "+OK\r\n",
+		},
+		{
+			name:     "Error reply",
+			request:  "*1\r\n$4\r\nINCR\r\n",
+			response: "-ERR unknown command\r\n",
+		},
+		{
+			name:     "Ping is left to the handler",
+			request:  "*1\r\n$4\r\nPING\r\n",
+			response: "+OK\r\n",
+		},
+		{
+			name:     "Connection command",
+			request:  "*1\r\n$7\r\nCOMMAND\r\n",
+			response: "*0\r\n",
+		},
+		{
+			name:     "Pipelined commands",
+			request:  "*1\r\n$4\r\nPING\r\n*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$1\r\nv\r\n",
+			response: "+OK\r\n+OK\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", server.Address())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte(tt.request))
+			require.NoError(t, err)
+
+			response := make([]byte, len(tt.response))
+			_, err = io.ReadFull(conn, response)
+			require.NoError(t, err)
+			assert.Equal(t, tt.response, string(response))
+		})
+	}
+}
+
+func TestTCPServer_HandleRESP3(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(), network.WithServerProtocol(network.RESPProtocol))
+	require.NoError(t, err)
+
+	go server.HandleRESP(ctx, func(_ context.Context, _ *session.Session, args []string) network.RESPValue {
+		return network.Null()
+	})
+
+	conn, err := net.Dial("tcp", server.Address())
+	require.NoError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	_, err = conn.Write([]byte("*2\r\n$5\r\nHELLO\r\n$1\r\n3\r\n"))
+	require.NoError(t, err)
+
+	header, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "%3\r\n", header, "RESP3 clients get a map")
+	for range 6 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "$") {
+			_, err = reader.ReadString('\n')
+			require.NoError(t, err)
+		}
+	}
+
+	_, err = conn.Write([]byte("*2\r\n$3\r\nGET\r\n$7\r\nmissing\r\n"))
+	require.NoError(t, err)
+
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "_\r\n", line)
+
+	_, err = conn.Write([]byte("*2\r\n$5\r\nHELLO\r\n$1\r\n4\r\n"))
+	require.NoError(t, err)
+
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "-NOPROTO unsupported protocol version\r\n", line)
+}
+
+func TestTCPServer_HandleRESPProtocolError(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(), network.WithServerProtocol(network.RESPProtocol))
+	require.NoError(t, err)
+
+	go server.HandleRESP(ctx, func(_ context.Context, _ *session.Session, args []string) network.RESPValue {
+		return network.SimpleString("OK")
+	})
+
+	for _, request := range []string{
+		"*1\r\n:42\r\n",
+		// The total length of the bulk strings overflows int.
+		"*2\r\n$3\r\nGET\r\n$9223372036854775807\r\n",
+	} {
+		conn, err := net.Dial("tcp", server.Address())
+		require.NoError(t, err)
+
+		_, err = conn.Write([]byte(request))
+		require.NoError(t, err)
+
+		response, err := io.ReadAll(conn)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(response), "-ERR Protocol error"), string(response))
+		require.NoError(t, conn.Close())
+	}
+}
+
+func TestTCPServer_HandleRESPMaxClients(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(),
+		network.WithServerProtocol(network.RESPProtocol), network.WithServerMaxConnectionsNumber(1))
+	require.NoError(t, err)
+
+	go server.HandleRESP(ctx, func(_ context.Context, _ *session.Session, args []string) network.RESPValue {
+		return network.SimpleString("OK")
+	})
+
+	first, err := net.Dial("tcp", server.Address())
+	require.NoError(t, err)
+	defer first.Close()
+
+	// The first connection holds the slot once its command is answered.
+	_, err = first.Write([]byte("PING\r\n"))
+	require.NoError(t, err)
+	_, err = first.Read(make([]byte, 16))
+	require.NoError(t, err)
+
+	second, err := net.Dial("tcp", server.Address())
+	require.NoError(t, err)
+	defer second.Close()
+
+	response, err := io.ReadAll(second)
+	require.NoError(t, err)
+	assert.Equal(t, "-ERR "+network.ErrMaxClients.Error()+"\r\n", string(response))
+}
@@ -1,27 +1,24 @@
 package network
 
 import (
-	"errors"
+	"bytes"
+	"crypto/tls"
 	"fmt"
-	"io"
 	"net"
 	"time"
 )
 
 type TCPClient struct {
 	conn        net.Conn
+	reader      *FrameReader
+	tlsConfig   *tls.Config
 	idleTimeout time.Duration
 	bufferSize  int
 }
 
+// NewTCPClient connects to the server address, unix:///path/to.sock is the address of a unix socket.
 func NewTCPClient(address string, options ...TCPClientOption) (*TCPClient, error) {
-	connection, err := net.Dial("tcp", address)
-	if err != nil {
-		return nil, fmt.Errorf("fail to dial: %w", err)
-	}
-
 	client := &TCPClient{
-		conn:       connection,
 		bufferSize: defaultBufferSize,
 	}
 
@@ -29,6 +26,25 @@ func NewTCPClient(address string, options ...TCPClientOption) (*TCPClient, error
 		option(client)
 	}
 
+	dialer := &net.Dialer{Timeout: client.idleTimeout}
+	network, address := splitAddress(address)
+	var (
+		connection net.Conn
+		err        error
+	)
+	if client.tlsConfig != nil {
+		connection, err = tls.DialWithDialer(dialer, network, address, client.tlsConfig)
+	} else {
+		connection, err = dialer.Dial(network, address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to dial: %w", err)
+	}
+
+	client.conn = connection
+	// The responses are not limited: the server answers a small request with a large value.
+	client.reader = NewFrameReader(connection, 0)
+
 	if client.idleTimeout != 0 {
 		if err := connection.SetDeadline(time.Now().Add(client.idleTimeout)); err != nil {
 			return nil, fmt.Errorf("fail to set deadline for connection: %w", err)
@@ -38,20 +54,29 @@ func NewTCPClient(address string, options ...TCPClientOption) (*TCPClient, error
 	return client, nil
 }
 
+// Send sends the request and returns the response, ErrFrameTooLarge if the request
+// is longer than the buffer size.
 func (c *TCPClient) Send(request []byte) ([]byte, error) {
-	if _, err := c.conn.Write(request); err != nil {
+	if len(request) > c.bufferSize {
+		return nil, ErrFrameTooLarge
+	}
+	if err := WriteFrame(c.conn, RequestFrame, request); err != nil {
 		return nil, err
 	}
 
-	response := make([]byte, c.bufferSize)
-	count, err := c.conn.Read(response)
-	if err != nil && err != io.EOF {
+	frameType, response, err := c.reader.Read()
+	if err != nil {
 		return nil, err
-	} else if count == c.bufferSize {
-		return nil, errors.New("small buffer size")
 	}
 
-	return response[:count], nil
+	switch frameType {
+	case ResponseFrame:
+		return bytes.Clone(response), nil
+	case ErrorFrame:
+		return nil, fmt.Errorf("server error: %s", response)
+	default:
+		return nil, ErrInvalidFrame
+	}
 }
 
 func (c *TCPClient) BufferSize() int {
@@ -1,6 +1,9 @@
 package network
 
-import "time"
+import (
+	"crypto/tls"
+	"time"
+)
 
 const defaultBufferSize = 4 << 10
 
@@ -12,8 +15,20 @@ func WithClientIdleTimeout(timeout time.Duration) TCPClientOption {
 	}
 }
 
+// WithClientBufferSize sets the max size of the requests, the responses are not limited.
 func WithClientBufferSize(size uint) TCPClientOption {
 	return func(client *TCPClient) {
 		client.bufferSize = int(size)
 	}
 }
+
+// WithClientTLSConfig makes the client connect over TLS with the config.
+func WithClientTLSConfig(config *tls.Config) TCPClientOption {
+	return func(client *TCPClient) {
+		client.tlsConfig = config
+	}
+}
+
+func (c *TCPClient) TLSConfig() *tls.Config {
+	return c.tlsConfig
+}
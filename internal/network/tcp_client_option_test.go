@@ -1,6 +1,7 @@
 package network_test
 
 import (
+	"crypto/tls"
 	"testing"
 	"time"
 
@@ -31,3 +32,15 @@ func TestWithClientBufferSize(t *testing.T) {
 
 	assert.Equal(t, bufferSize, uint(client.BufferSize()))
 }
+
+func TestWithClientTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	config := &tls.Config{ServerName: "localhost"}
+	option := network.WithClientTLSConfig(config)
+
+	var client network.TCPClient
+	option(&client)
+
+	assert.Same(t, config, client.TLSConfig())
+}
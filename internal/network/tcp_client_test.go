@@ -1,7 +1,6 @@
 package network_test
 
 import (
-	"errors"
 	"net"
 	"syscall"
 	"testing"
@@ -29,10 +28,13 @@ func TestTCPClient(t *testing.T) {
 				return
 			}
 
-			_, err = connection.Read(make([]byte, 2048))
-			require.NoError(t, err)
+			// The client that refused to send the request closes the connection.
+			if _, _, err = network.NewFrameReader(connection, 2048).Read(); err != nil {
+				_ = connection.Close()
+				continue
+			}
 
-			_, err = connection.Write([]byte(serverResponse))
+			err = network.WriteFrame(connection, network.ResponseFrame, []byte(serverResponse))
 			require.NoError(t, err)
 		}
 	}()
@@ -63,7 +65,16 @@ func TestTCPClient(t *testing.T) {
 				require.NoError(t, err)
 				return client
 			},
-			wantErr: errors.New("small buffer size"),
+			wantErr: network.ErrFrameTooLarge,
+		},
+		"client with small max message size reads large response": {
+			request: "hi",
+			client: func() *network.TCPClient {
+				client, err := network.NewTCPClient(serverAddress, network.WithClientBufferSize(5))
+				require.NoError(t, err)
+				return client
+			},
+			wantResponse: serverResponse,
 		},
 		"client with idle timeout": {
 			request: "hello server",
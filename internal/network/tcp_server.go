@@ -1,27 +1,69 @@
 package network
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alukart32/go-fast-key/internal/pkg/concurrency"
+	"github.com/alukart32/go-fast-key/internal/session"
 	"go.uber.org/zap"
 )
 
-type TCPHandler = func(context.Context, []byte) []byte
+// TCPHandler handles the request of the client session.
+type TCPHandler = func(context.Context, *session.Session, []byte) []byte
+
+// Protocol defines the protocol the clients of the server speak.
+type Protocol string
+
+const (
+	// FastKeyProtocol is the length-prefixed frames protocol.
+	FastKeyProtocol Protocol = "fastkey"
+	// RESPProtocol is the Redis serialization protocol.
+	RESPProtocol Protocol = "resp"
+)
+
+var (
+	// ErrServerClosed is sent to the idle clients when the server shuts down.
+	ErrServerClosed = errors.New("server is shutting down")
+	// ErrMaxClients is sent to the clients that connect when the server has max connections.
+	ErrMaxClients = errors.New("max number of clients reached")
+)
+
+const (
+	defaultShutdownTimeout = 5 * time.Second
+	// rejectTimeout limits the time of writing the error to the rejected client.
+	rejectTimeout = time.Second
+)
 
 type TCPServer struct {
 	listener  net.Listener
 	semaphore *concurrency.Semaphore
+	protocol  Protocol
+	sessions  *session.Manager
+	tlsConfig *tls.Config
+
+	connsMtx sync.Mutex
+	conns    map[net.Conn]struct{}
+	closing  atomic.Bool
+	rejected atomic.Uint64
+	traffic  traffic
 
-	idleTimeout    time.Duration
-	bufferSize     int
-	maxConnections int
+	socketPermissions os.FileMode
+	idleTimeout       time.Duration
+	shutdownTimeout   time.Duration
+	bufferSize        int
+	maxConnections    int
 
 	logger *zap.Logger
 }
@@ -31,13 +73,8 @@ func NewTCPServer(address string, logger *zap.Logger, options ...TCPServerOption
 		return nil, errors.New("logger is nil")
 	}
 
-	listener, err := net.Listen("tcp", address)
-	if err != nil {
-		return nil, fmt.Errorf("fail to listen: %w", err)
-	}
-
 	server := &TCPServer{
-		listener: listener,
+		protocol: FastKeyProtocol,
 		logger:   logger,
 	}
 
@@ -45,11 +82,41 @@ func NewTCPServer(address string, logger *zap.Logger, options ...TCPServerOption
 		option(server)
 	}
 
-	server.semaphore = concurrency.NewSemaphore(server.maxConnections)
+	if server.protocol != FastKeyProtocol && server.protocol != RESPProtocol {
+		return nil, fmt.Errorf("unsupported protocol: %v", server.protocol)
+	}
+
+	var (
+		listener net.Listener
+		err      error
+	)
+	if network, path := splitAddress(address); network == "unix" {
+		listener, err = listenUnix(path, server.socketPermissions)
+	} else {
+		listener, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to listen: %w", err)
+	}
+
+	// The traffic is counted below TLS, so it matches the bytes on the wire.
+	server.listener = &trafficListener{Listener: listener, traffic: &server.traffic}
+	if server.tlsConfig != nil {
+		server.listener = tls.NewListener(server.listener, server.tlsConfig)
+	}
+	if server.sessions == nil {
+		server.sessions = session.NewManager()
+	}
 	if server.bufferSize == 0 {
 		server.bufferSize = 4 << 10
 	}
-	server.semaphore = concurrency.NewSemaphore(server.maxConnections)
+	if server.shutdownTimeout == 0 {
+		server.shutdownTimeout = defaultShutdownTimeout
+	}
+	server.conns = make(map[net.Conn]struct{})
+	if server.maxConnections > 0 {
+		server.semaphore = concurrency.NewSemaphore(server.maxConnections)
+	}
 
 	return server, nil
 }
@@ -59,7 +126,32 @@ func (s *TCPServer) HandleQueries(ctx context.Context, handler TCPHandler) {
 		return
 	}
 
-	var wg sync.WaitGroup
+	s.serve(ctx, func(conn net.Conn) {
+		sess := s.sessions.Open(remoteAddress(conn))
+		defer s.sessions.Close(sess)
+		s.handleConn(ctx, conn, sess, handler)
+	})
+}
+
+// HandleRESP serves the clients speaking the RESP protocol.
+func (s *TCPServer) HandleRESP(ctx context.Context, handler RESPHandler) {
+	if s == nil || handler == nil {
+		return
+	}
+
+	s.serve(ctx, func(conn net.Conn) {
+		sess := s.sessions.Open(remoteAddress(conn))
+		defer s.sessions.Close(sess)
+		s.handleRESPConn(ctx, conn, sess, handler)
+	})
+}
+
+// serve accepts the connections until ctx is done, then shuts the server down gracefully.
+func (s *TCPServer) serve(ctx context.Context, handleConn func(net.Conn)) {
+	var (
+		wg    sync.WaitGroup
+		conns sync.WaitGroup
+	)
 	wg.Add(1)
 
 	go func() {
@@ -76,11 +168,22 @@ func (s *TCPServer) HandleQueries(ctx context.Context, handler TCPHandler) {
 				continue
 			}
 
-			s.semaphore.Acquire()
+			if !s.semaphore.TryAcquire() {
+				s.reject(conn, &conns)
+				continue
+			}
+			if !s.trackConn(conn, &conns) {
+				s.semaphore.Release()
+				_ = conn.Close()
+				continue
+			}
+
 			s.logger.Debug("accept new connection", zap.String("address", conn.LocalAddr().String()))
 			go func() {
+				defer conns.Done()
 				defer s.semaphore.Release()
-				s.handleConn(ctx, conn, handler)
+				defer s.closeConn(conn)
+				handleConn(conn)
 			}()
 		}
 	}()
@@ -88,9 +191,103 @@ func (s *TCPServer) HandleQueries(ctx context.Context, handler TCPHandler) {
 	<-ctx.Done()
 
 	s.listener.Close()
+	s.drain(&conns)
 	wg.Wait() // wait goroutine to shut down before all connections are closed.
 }
 
+// reject writes ErrMaxClients to the connection in the protocol of the server and closes it,
+// the shutdown waits for the rejected connections as for the served ones.
+func (s *TCPServer) reject(conn net.Conn, conns *sync.WaitGroup) {
+	s.rejected.Add(1)
+	s.logger.Warn("reject connection", zap.String("address", remoteAddress(conn)), zap.Error(ErrMaxClients))
+
+	s.connsMtx.Lock()
+	closing := s.closing.Load()
+	if !closing {
+		conns.Add(1)
+	}
+	s.connsMtx.Unlock()
+	if closing {
+		_ = conn.Close()
+		return
+	}
+
+	go func() {
+		defer conns.Done()
+		defer conn.Close()
+
+		_ = conn.SetDeadline(time.Now().Add(rejectTimeout))
+		if s.protocol == RESPProtocol {
+			_, _ = conn.Write(appendRESP(nil, ErrorValue("ERR "+ErrMaxClients.Error()), 2))
+			return
+		}
+		_ = WriteFrame(conn, ErrorFrame, []byte(ErrMaxClients.Error()))
+	}()
+}
+
+// trackConn registers the accepted connection, the connections accepted during the shutdown are refused.
+func (s *TCPServer) trackConn(conn net.Conn, conns *sync.WaitGroup) bool {
+	s.connsMtx.Lock()
+	defer s.connsMtx.Unlock()
+
+	if s.closing.Load() {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	conns.Add(1)
+	return true
+}
+
+// drain waits for the connections to finish their in-flight requests.
+//
+// The idle connections are woken up from reading and closed at once, the connections
+// that are still open when the shutdown timeout expires are closed forcibly
+// and their handlers are not waited for.
+func (s *TCPServer) drain(conns *sync.WaitGroup) {
+	s.connsMtx.Lock()
+	s.closing.Store(true)
+	for conn := range s.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	s.connsMtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		conns.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-time.After(s.shutdownTimeout):
+	}
+
+	s.connsMtx.Lock()
+	s.logger.Warn("close connections after the shutdown timeout", zap.Int("count", len(s.conns)))
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.connsMtx.Unlock()
+}
+
+func (s *TCPServer) isClosing() bool {
+	return s.closing.Load()
+}
+
+// Close stops accepting the connections of the server that is not serving yet.
+func (s *TCPServer) Close() error {
+	return s.listener.Close()
+}
+
+// Address returns the address the server listens on, unix sockets are returned as unix:///path/to.sock.
+func (s *TCPServer) Address() string {
+	if addr := s.listener.Addr(); addr.Network() == "unix" {
+		return unixScheme + addr.String()
+	}
+	return s.listener.Addr().String()
+}
+
 func (s *TCPServer) BufferSize() int {
 	return s.bufferSize
 }
@@ -103,20 +300,169 @@ func (s *TCPServer) IdleTimeout() time.Duration {
 	return s.idleTimeout
 }
 
-func (s *TCPServer) handleConn(ctx context.Context, conn net.Conn, handler TCPHandler) {
-	defer func() {
-		if v := recover(); v != nil {
-			s.logger.Error("captured panic", zap.Any("panic", v))
+func (s *TCPServer) Protocol() Protocol {
+	return s.protocol
+}
+
+func (s *TCPServer) SocketPermissions() os.FileMode {
+	return s.socketPermissions
+}
+
+// Stats defines the state of the server.
+type Stats struct {
+	Address             string
+	Protocol            Protocol
+	Connections         int
+	RejectedConnections uint64
+	BytesRead           uint64
+	BytesWritten        uint64
+}
+
+// Stats returns the current state of the server.
+func (s *TCPServer) Stats() Stats {
+	return Stats{
+		Address:             s.Address(),
+		Protocol:            s.protocol,
+		Connections:         s.Connections(),
+		RejectedConnections: s.RejectedConnections(),
+		BytesRead:           s.BytesRead(),
+		BytesWritten:        s.BytesWritten(),
+	}
+}
+
+// Connections returns the number of the open connections.
+func (s *TCPServer) Connections() int {
+	s.connsMtx.Lock()
+	defer s.connsMtx.Unlock()
+	return len(s.conns)
+}
+
+// BytesRead returns the number of the bytes read from the clients.
+func (s *TCPServer) BytesRead() uint64 {
+	return s.traffic.read.Load()
+}
+
+// BytesWritten returns the number of the bytes written to the clients.
+func (s *TCPServer) BytesWritten() uint64 {
+	return s.traffic.written.Load()
+}
+
+// RejectedConnections returns the number of the connections rejected because of the max connections.
+func (s *TCPServer) RejectedConnections() uint64 {
+	return s.rejected.Load()
+}
+
+func (s *TCPServer) ShutdownTimeout() time.Duration {
+	return s.shutdownTimeout
+}
+
+func (s *TCPServer) TLSConfig() *tls.Config {
+	return s.tlsConfig
+}
+
+// Sessions returns the sessions of the connected clients.
+func (s *TCPServer) Sessions() *session.Manager {
+	return s.sessions
+}
+
+func (s *TCPServer) closeConn(conn net.Conn) {
+	if v := recover(); v != nil {
+		s.logger.Error("captured panic", zap.Any("panic", v))
+	}
+
+	if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		s.logger.Warn("fail to close connection", zap.Error(err))
+	}
+
+	s.connsMtx.Lock()
+	delete(s.conns, conn)
+	s.connsMtx.Unlock()
+	s.logger.Debug("connection is closed", zap.String("address", conn.LocalAddr().String()))
+}
+
+// handleConn answers the requests of the connection in order.
+//
+// The responses are buffered while the pipelined requests are already read,
+// and written together before the server waits for more requests.
+func (s *TCPServer) handleConn(ctx context.Context, conn net.Conn, sess *session.Session, handler TCPHandler) {
+	reader := NewFrameReader(conn, s.bufferSize)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		if s.idleTimeout != 0 {
+			if err := conn.SetReadDeadline(time.Now().Add(s.idleTimeout)); err != nil {
+				s.logger.Warn("fail to set read deadline", zap.Error(err))
+				break
+			}
 		}
 
-		if err := conn.Close(); err != nil {
-			s.logger.Warn("fail to close connection", zap.Error(err))
+		// The deadline is set before the check, so the shutdown can not be missed by the blocked read.
+		if s.isClosing() && !reader.Buffered() {
+			_ = WriteFrame(writer, ErrorFrame, []byte(ErrServerClosed.Error()))
+			_ = writer.Flush()
+			break
 		}
-		s.logger.Debug("connection is closed", zap.String("address", conn.LocalAddr().String()))
-	}()
 
-	// reuse buffer for requests
-	request := make([]byte, s.bufferSize)
+		frameType, request, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if s.isClosing() {
+				_ = WriteFrame(writer, ErrorFrame, []byte(ErrServerClosed.Error()))
+				_ = writer.Flush()
+				break
+			}
+
+			s.logger.Warn(
+				"fail to read frame",
+				zap.String("address", conn.RemoteAddr().String()),
+				zap.Error(err),
+			)
+			if errors.Is(err, ErrFrameTooLarge) || errors.Is(err, ErrInvalidFrame) {
+				_ = WriteFrame(writer, ErrorFrame, []byte(err.Error()))
+			}
+			_ = writer.Flush()
+			break
+		}
+		if frameType != RequestFrame {
+			s.logger.Warn("unexpected frame", zap.Uint8("type", uint8(frameType)))
+			_ = WriteFrame(writer, ErrorFrame, []byte(ErrInvalidFrame.Error()))
+			_ = writer.Flush()
+			break
+		}
+
+		if s.idleTimeout != 0 {
+			if err := conn.SetWriteDeadline(time.Now().Add(s.idleTimeout)); err != nil {
+				s.logger.Warn("fail to set read deadline", zap.Error(err))
+				break
+			}
+		}
+
+		s.logger.Debug("read connection", zap.Int("size", len(request)))
+
+		response := handler(ctx, sess, request)
+		err = WriteFrame(writer, ResponseFrame, response)
+		if err == nil && !reader.Buffered() {
+			err = writer.Flush()
+		}
+		if err != nil {
+			s.logger.Warn(
+				"fail to write data",
+				zap.String("address", conn.RemoteAddr().String()),
+				zap.Error(err),
+			)
+			break
+		}
+	}
+}
+
+// handleRESPConn answers the RESP commands of the connection in order,
+// the replies to the pipelined commands are written together.
+func (s *TCPServer) handleRESPConn(ctx context.Context, conn net.Conn, sess *session.Session, handler RESPHandler) {
+	reader := newRESPReader(conn, s.bufferSize)
+	version := 2
+	var replies []byte
 
 	for {
 		if s.idleTimeout != 0 {
@@ -126,16 +472,31 @@ func (s *TCPServer) handleConn(ctx context.Context, conn net.Conn, handler TCPHa
 			}
 		}
 
-		count, err := conn.Read(request)
-		if err != nil && err != io.EOF {
+		// The idle RESP clients are not sent anything, they see the connection closed.
+		if s.isClosing() && !reader.buffered() {
+			_, _ = conn.Write(replies)
+			break
+		}
+
+		args, err := reader.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if s.isClosing() {
+				_, _ = conn.Write(replies)
+				break
+			}
+
 			s.logger.Warn(
-				"fail to read data",
+				"fail to read resp command",
 				zap.String("address", conn.RemoteAddr().String()),
 				zap.Error(err),
 			)
-			break
-		} else if count == s.bufferSize {
-			s.logger.Warn("small buffer size", zap.Int("buffer_size", s.bufferSize))
+			if errors.Is(err, ErrFrameTooLarge) || errors.Is(err, ErrInvalidRESP) {
+				replies = appendRESP(replies, ErrorValue("ERR Protocol error: "+err.Error()), version)
+			}
+			_, _ = conn.Write(replies)
 			break
 		}
 
@@ -146,10 +507,29 @@ func (s *TCPServer) handleConn(ctx context.Context, conn net.Conn, handler TCPHa
 			}
 		}
 
-		s.logger.Debug("read connection", zap.String("data", string(request[:count])))
+		quit := false
+		if len(args) != 0 {
+			// The connection commands reveal no data, so they are answered before authentication,
+			// the other commands, PING included, are left to the handler.
+			var response RESPValue
+			switch strings.ToUpper(args[0]) {
+			case "HELLO":
+				response = hello(args[1:], &version)
+			case "COMMAND":
+				response = Array()
+			case "QUIT":
+				response, quit = SimpleString("OK"), true
+			default:
+				response = handler(ctx, sess, args)
+			}
+			replies = appendRESP(replies, response, version)
+		}
+
+		if (reader.buffered() && !quit) || len(replies) == 0 {
+			continue
+		}
 
-		response := handler(ctx, request[:count])
-		if _, err := conn.Write(response); err != nil {
+		if _, err := conn.Write(replies); err != nil {
 			s.logger.Warn(
 				"fail to write data",
 				zap.String("address", conn.RemoteAddr().String()),
@@ -157,5 +537,26 @@ func (s *TCPServer) handleConn(ctx context.Context, conn net.Conn, handler TCPHa
 			)
 			break
 		}
+		replies = replies[:0]
+		if quit {
+			break
+		}
 	}
 }
+
+// hello switches the connection protocol version and returns the server properties.
+func hello(args []string, version *int) RESPValue {
+	if len(args) > 0 {
+		requested, err := strconv.Atoi(args[0])
+		if err != nil || requested < 2 || requested > 3 {
+			return ErrorValue("NOPROTO unsupported protocol version")
+		}
+		*version = requested
+	}
+
+	return Map(
+		BulkString("server"), BulkString("fastkey"),
+		BulkString("proto"), Integer(int64(*version)),
+		BulkString("mode"), BulkString("standalone"),
+	)
+}
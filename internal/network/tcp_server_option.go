@@ -1,6 +1,12 @@
 package network
 
-import "time"
+import (
+	"crypto/tls"
+	"os"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/session"
+)
 
 type TCPServerOption func(*TCPServer)
 
@@ -16,8 +22,43 @@ func WithServerBufferSize(size uint) TCPServerOption {
 	}
 }
 
+// WithServerMaxConnectionsNumber sets the limit of the open connections, zero means no limit.
 func WithServerMaxConnectionsNumber(count uint) TCPServerOption {
 	return func(server *TCPServer) {
 		server.maxConnections = int(count)
 	}
 }
+
+func WithServerProtocol(protocol Protocol) TCPServerOption {
+	return func(server *TCPServer) {
+		server.protocol = protocol
+	}
+}
+
+// WithServerSessions sets the manager of the client sessions, so the servers may share it.
+func WithServerSessions(sessions *session.Manager) TCPServerOption {
+	return func(server *TCPServer) {
+		server.sessions = sessions
+	}
+}
+
+// WithServerTLSConfig makes the server accept the connections over TLS with the config.
+func WithServerTLSConfig(config *tls.Config) TCPServerOption {
+	return func(server *TCPServer) {
+		server.tlsConfig = config
+	}
+}
+
+// WithServerSocketPermissions sets the permissions of the unix socket file.
+func WithServerSocketPermissions(perm os.FileMode) TCPServerOption {
+	return func(server *TCPServer) {
+		server.socketPermissions = perm
+	}
+}
+
+// WithServerShutdownTimeout sets the grace period for the in-flight requests when the server shuts down.
+func WithServerShutdownTimeout(timeout time.Duration) TCPServerOption {
+	return func(server *TCPServer) {
+		server.shutdownTimeout = timeout
+	}
+}
@@ -1,10 +1,13 @@
 package network_test
 
 import (
+	"crypto/tls"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,3 +46,60 @@ func TestWithServerMaxConnectionsNumber(t *testing.T) {
 
 	assert.Equal(t, maxConnections, uint(server.MaxConnections()))
 }
+
+func TestWithServerProtocol(t *testing.T) {
+	t.Parallel()
+
+	option := network.WithServerProtocol(network.RESPProtocol)
+
+	var server network.TCPServer
+	option(&server)
+
+	assert.Equal(t, network.RESPProtocol, server.Protocol())
+}
+
+func TestWithServerSessions(t *testing.T) {
+	t.Parallel()
+
+	sessions := session.NewManager()
+	option := network.WithServerSessions(sessions)
+
+	var server network.TCPServer
+	option(&server)
+
+	assert.Same(t, sessions, server.Sessions())
+}
+
+func TestWithServerTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	option := network.WithServerTLSConfig(config)
+
+	var server network.TCPServer
+	option(&server)
+
+	assert.Same(t, config, server.TLSConfig())
+}
+
+func TestWithServerSocketPermissions(t *testing.T) {
+	t.Parallel()
+
+	option := network.WithServerSocketPermissions(0o660)
+
+	var server network.TCPServer
+	option(&server)
+
+	assert.Equal(t, os.FileMode(0o660), server.SocketPermissions())
+}
+
+func TestWithServerShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	option := network.WithServerShutdownTimeout(time.Second)
+
+	var server network.TCPServer
+	option(&server)
+
+	assert.Equal(t, time.Second, server.ShutdownTimeout())
+}
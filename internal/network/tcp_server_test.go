@@ -1,13 +1,17 @@
 package network_test
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"net"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -24,7 +28,7 @@ func TestTCPServer(t *testing.T) {
 	require.NoError(t, err)
 
 	go func() {
-		server.HandleQueries(ctx, func(ctx context.Context, data []byte) []byte {
+		server.HandleQueries(ctx, func(ctx context.Context, _ *session.Session, data []byte) []byte {
 			return []byte("hello-" + string(data))
 		})
 	}()
@@ -39,17 +43,17 @@ func TestTCPServer(t *testing.T) {
 		connection, clientErr := net.Dial("tcp", serverAddress)
 		require.NoError(t, clientErr)
 
-		_, clientErr = connection.Write([]byte("client-1"))
+		clientErr = network.WriteFrame(connection, network.RequestFrame, []byte("client-1"))
 		require.NoError(t, clientErr)
 
-		buffer := make([]byte, 1024)
-		size, clientErr := connection.Read(buffer)
+		frameType, response, clientErr := network.NewFrameReader(connection, 1024).Read()
 		require.NoError(t, clientErr)
 
 		clientErr = connection.Close()
 		require.NoError(t, clientErr)
 
-		assert.Equal(t, "hello-client-1", string(buffer[:size]))
+		assert.Equal(t, network.ResponseFrame, frameType)
+		assert.Equal(t, "hello-client-1", string(response))
 	}()
 
 	go func() {
@@ -58,19 +62,328 @@ func TestTCPServer(t *testing.T) {
 		connection, clientErr := net.Dial("tcp", serverAddress)
 		require.NoError(t, clientErr)
 
-		_, clientErr = connection.Write([]byte("client-2"))
+		clientErr = network.WriteFrame(connection, network.RequestFrame, []byte("client-2"))
 		require.NoError(t, clientErr)
 
-		buffer := make([]byte, 1024)
-		size, clientErr := connection.Read(buffer)
+		frameType, response, clientErr := network.NewFrameReader(connection, 1024).Read()
 		require.NoError(t, clientErr)
 
 		clientErr = connection.Close()
 		require.NoError(t, clientErr)
 
-		assert.Equal(t, "hello-client-2", string(buffer[:size]))
+		assert.Equal(t, network.ResponseFrame, frameType)
+		assert.Equal(t, "hello-client-2", string(response))
 	}()
 
 	wg.Wait()
 	cancel()
 }
+
+func TestTCPServer_Framing(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(), network.WithServerBufferSize(64<<10))
+	require.NoError(t, err)
+
+	go server.HandleQueries(ctx, func(ctx context.Context, _ *session.Session, data []byte) []byte {
+		return append([]byte("echo:"), data...)
+	})
+
+	connection, err := net.Dial("tcp", server.Address())
+	require.NoError(t, err)
+	defer connection.Close()
+
+	reader := network.NewFrameReader(connection, 0)
+	large := bytes.Repeat([]byte("value with spaces\nand lines "), 1000)
+
+	// Both frames are sent by one write and the second one is larger than a single read.
+	var requests bytes.Buffer
+	require.NoError(t, network.WriteFrame(&requests, network.RequestFrame, []byte("SET key a b\nc")))
+	require.NoError(t, network.WriteFrame(&requests, network.RequestFrame, large))
+	_, err = connection.Write(requests.Bytes())
+	require.NoError(t, err)
+
+	_, response, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "echo:SET key a b\nc", string(response))
+
+	_, response, err = reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte("echo:"), large...), response)
+
+	// The frame is split into many writes.
+	var fragmented bytes.Buffer
+	require.NoError(t, network.WriteFrame(&fragmented, network.RequestFrame, []byte("GET key")))
+	for _, b := range fragmented.Bytes() {
+		_, err = connection.Write([]byte{b})
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+
+	_, response, err = reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "echo:GET key", string(response))
+}
+
+func TestTCPServer_FrameTooLarge(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(), network.WithServerBufferSize(8))
+	require.NoError(t, err)
+
+	go server.HandleQueries(ctx, func(ctx context.Context, _ *session.Session, data []byte) []byte {
+		return data
+	})
+
+	connection, err := net.Dial("tcp", server.Address())
+	require.NoError(t, err)
+	defer connection.Close()
+
+	require.NoError(t, network.WriteFrame(connection, network.RequestFrame, []byte("too large request")))
+
+	reader := network.NewFrameReader(connection, 0)
+	frameType, response, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, network.ErrorFrame, frameType)
+	assert.Equal(t, network.ErrFrameTooLarge.Error(), string(response))
+
+	_, _, err = reader.Read()
+	assert.ErrorIs(t, err, io.EOF, "the connection is closed")
+}
+
+func TestTCPServer_Sessions(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop())
+	require.NoError(t, err)
+
+	go server.HandleQueries(ctx, func(_ context.Context, sess *session.Session, data []byte) []byte {
+		return []byte(fmt.Sprintf("%d %s", sess.ID(), sess.RemoteAddr()))
+	})
+
+	first, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+	second, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+	defer second.Close()
+
+	send := func(client *network.TCPClient) (uint64, string) {
+		response, err := client.Send([]byte("CLIENT"))
+		require.NoError(t, err)
+
+		var (
+			id      uint64
+			address string
+		)
+		_, err = fmt.Sscan(string(response), &id, &address)
+		require.NoError(t, err)
+		return id, address
+	}
+
+	firstID, firstAddress := send(first)
+	secondID, secondAddress := send(second)
+
+	// The session lives as long as the connection.
+	again, _ := send(first)
+	assert.Equal(t, firstID, again)
+	assert.NotEqual(t, firstID, secondID)
+	assert.NotEqual(t, firstAddress, secondAddress)
+	assert.Equal(t, []uint64{firstID, secondID}, sessionIDs(server.Sessions()))
+
+	first.Close()
+	assert.Eventually(t, func() bool {
+		return server.Sessions().Len() == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func sessionIDs(sessions *session.Manager) []uint64 {
+	var ids []uint64
+	for _, s := range sessions.List() {
+		ids = append(ids, s.ID())
+	}
+	return ids
+}
+
+func TestTCPServer_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop())
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.HandleQueries(ctx, func(_ context.Context, _ *session.Session, data []byte) []byte {
+			if string(data) == "SLOW" {
+				close(started)
+				<-release
+			}
+			return data
+		})
+	}()
+
+	idle, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+	defer idle.Close()
+	busy, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+	defer busy.Close()
+
+	_, err = idle.Send([]byte("PING"))
+	require.NoError(t, err)
+
+	response := make(chan []byte)
+	go func() {
+		data, err := busy.Send([]byte("SLOW"))
+		assert.NoError(t, err)
+		response <- data
+	}()
+	<-started
+
+	cancel()
+
+	// The idle client is notified, the in-flight request keeps the server running.
+	_, err = idle.Send([]byte("PING"))
+	assert.ErrorContains(t, err, network.ErrServerClosed.Error())
+	select {
+	case <-done:
+		t.Fatal("server is down before the in-flight request is finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, "SLOW", string(<-response))
+	<-done
+
+	_, err = network.NewTCPClient(server.Address())
+	assert.Error(t, err)
+}
+
+func TestTCPServer_ShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(),
+		network.WithServerShutdownTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.HandleQueries(ctx, func(_ context.Context, _ *session.Session, data []byte) []byte {
+			close(started)
+			<-release
+			return data
+		})
+	}()
+
+	client, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+	defer client.Close()
+
+	sent := make(chan error)
+	go func() {
+		_, err := client.Send([]byte("STUCK"))
+		sent <- err
+	}()
+	<-started
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("server is not down after the shutdown timeout")
+	}
+	assert.Error(t, <-sent, "the connection is closed forcibly")
+}
+
+func TestTCPServer_MaxConnections(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(), network.WithServerMaxConnectionsNumber(1))
+	require.NoError(t, err)
+
+	go server.HandleQueries(ctx, func(_ context.Context, _ *session.Session, data []byte) []byte {
+		return data
+	})
+
+	first, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+	_, err = first.Send([]byte("PING"))
+	require.NoError(t, err)
+
+	second, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+	defer second.Close()
+
+	_, err = second.Send([]byte("PING"))
+	assert.ErrorContains(t, err, network.ErrMaxClients.Error())
+	assert.Equal(t, uint64(1), server.RejectedConnections())
+
+	// The connection is accepted again once the first client leaves.
+	first.Close()
+	assert.Eventually(t, func() bool {
+		client, err := network.NewTCPClient(server.Address())
+		if err != nil {
+			return false
+		}
+		defer client.Close()
+
+		_, err = client.Send([]byte("PING"))
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTCPServer_Traffic(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop())
+	require.NoError(t, err)
+
+	go server.HandleQueries(ctx, func(_ context.Context, _ *session.Session, data []byte) []byte {
+		return []byte("PONG!")
+	})
+
+	client, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+
+	_, err = client.Send([]byte("PING"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.Connections())
+
+	// The frames have the 5 bytes header.
+	assert.Equal(t, uint64(5+4), server.BytesRead())
+	assert.Equal(t, uint64(5+5), server.BytesWritten())
+	assert.Equal(t, network.Stats{
+		Address:      server.Address(),
+		Protocol:     network.FastKeyProtocol,
+		Connections:  1,
+		BytesRead:    5 + 4,
+		BytesWritten: 5 + 5,
+	}, server.Stats())
+
+	client.Close()
+	assert.Eventually(t, func() bool {
+		return server.Connections() == 0
+	}, time.Second, 10*time.Millisecond)
+}
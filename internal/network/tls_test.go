@@ -0,0 +1,264 @@
+package network_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testCerts struct {
+	caFile, otherCAFile   string
+	serverCert, serverKey string
+	clientCert, clientKey string
+}
+
+// generateTestCerts writes a CA, the server and client certificates signed by it and one more CA to dir.
+func generateTestCerts(t *testing.T) testCerts {
+	t.Helper()
+
+	dir := t.TempDir()
+	write := func(name, blockType string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0o600))
+		return path
+	}
+
+	serial := int64(0)
+	issue := func(template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		serial++
+		template.SerialNumber = big.NewInt(serial)
+		template.NotBefore = time.Now().Add(-time.Hour)
+		template.NotAfter = time.Now().Add(time.Hour)
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+		require.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return cert, key
+	}
+	writePair := func(name string, cert *x509.Certificate, key *ecdsa.PrivateKey) (string, string) {
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return write(name+".crt", "CERTIFICATE", cert.Raw), write(name+".key", "EC PRIVATE KEY", keyDER)
+	}
+	newCA := func(name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+		return issue(&x509.Certificate{
+			Subject:               pkix.Name{CommonName: name},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}, nil, nil)
+	}
+
+	ca, caKey := newCA("fastkey test ca")
+	otherCA, _ := newCA("other test ca")
+
+	var certs testCerts
+	certs.caFile = write("ca.crt", "CERTIFICATE", ca.Raw)
+	certs.otherCAFile = write("other-ca.crt", "CERTIFICATE", otherCA.Raw)
+
+	server, serverKey := issue(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	certs.serverCert, certs.serverKey = writePair("server", server, serverKey)
+
+	client, clientKey := issue(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	certs.clientCert, certs.clientKey = writePair("client", client, clientKey)
+
+	return certs
+}
+
+func startTLSServer(t *testing.T, certs testCerts, requireClientCert bool) string {
+	t.Helper()
+
+	config, err := network.NewServerTLSConfig(certs.serverCert, certs.serverKey, certs.caFile, requireClientCert)
+	require.NoError(t, err)
+
+	server, err := network.NewTCPServer("localhost:0", zap.NewNop(), network.WithServerTLSConfig(config))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go server.HandleQueries(ctx, func(_ context.Context, _ *session.Session, data []byte) []byte {
+		return append([]byte("echo "), data...)
+	})
+	return server.Address()
+}
+
+func TestTLS(t *testing.T) {
+	t.Parallel()
+
+	certs := generateTestCerts(t)
+	address := startTLSServer(t, certs, false)
+
+	t.Run("trusted server", func(t *testing.T) {
+		config, err := network.NewClientTLSConfig(certs.caFile, "", "", "localhost")
+		require.NoError(t, err)
+
+		client, err := network.NewTCPClient(address, network.WithClientTLSConfig(config))
+		require.NoError(t, err)
+		defer client.Close()
+
+		response, err := client.Send([]byte("GET key"))
+		require.NoError(t, err)
+		assert.Equal(t, "echo GET key", string(response))
+	})
+
+	t.Run("untrusted server", func(t *testing.T) {
+		config, err := network.NewClientTLSConfig(certs.otherCAFile, "", "", "localhost")
+		require.NoError(t, err)
+
+		_, err = network.NewTCPClient(address, network.WithClientTLSConfig(config))
+		var unknownAuthority x509.UnknownAuthorityError
+		assert.ErrorAs(t, err, &unknownAuthority)
+	})
+
+	t.Run("plain client", func(t *testing.T) {
+		client, err := network.NewTCPClient(address, network.WithClientIdleTimeout(time.Second))
+		require.NoError(t, err)
+		defer client.Close()
+
+		_, err = client.Send([]byte("GET key"))
+		assert.Error(t, err)
+	})
+}
+
+func TestTLS_ClientCert(t *testing.T) {
+	t.Parallel()
+
+	certs := generateTestCerts(t)
+	address := startTLSServer(t, certs, true)
+
+	t.Run("client with certificate", func(t *testing.T) {
+		config, err := network.NewClientTLSConfig(certs.caFile, certs.clientCert, certs.clientKey, "localhost")
+		require.NoError(t, err)
+
+		client, err := network.NewTCPClient(address, network.WithClientTLSConfig(config))
+		require.NoError(t, err)
+		defer client.Close()
+
+		response, err := client.Send([]byte("GET key"))
+		require.NoError(t, err)
+		assert.Equal(t, "echo GET key", string(response))
+	})
+
+	t.Run("client without certificate", func(t *testing.T) {
+		config, err := network.NewClientTLSConfig(certs.caFile, "", "", "localhost")
+		require.NoError(t, err)
+
+		// With TLS 1.3 the server checks the client certificate after the client finished the handshake.
+		client, err := network.NewTCPClient(address, network.WithClientTLSConfig(config))
+		if err == nil {
+			defer client.Close()
+			_, err = client.Send([]byte("GET key"))
+		}
+		assert.Error(t, err)
+	})
+}
+
+func TestNewServerTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	certs := generateTestCerts(t)
+
+	tests := map[string]struct {
+		certFile, keyFile, caFile string
+		requireClientCert         bool
+
+		wantErr bool
+	}{
+		"server certificate": {
+			certFile: certs.serverCert,
+			keyFile:  certs.serverKey,
+		},
+		"client certificates": {
+			certFile:          certs.serverCert,
+			keyFile:           certs.serverKey,
+			caFile:            certs.caFile,
+			requireClientCert: true,
+		},
+		"without key": {
+			certFile: certs.serverCert,
+			wantErr:  true,
+		},
+		"mismatched key": {
+			certFile: certs.serverCert,
+			keyFile:  certs.clientKey,
+			wantErr:  true,
+		},
+		"required client certificates without ca": {
+			certFile:          certs.serverCert,
+			keyFile:           certs.serverKey,
+			requireClientCert: true,
+			wantErr:           true,
+		},
+		"ca without certificates": {
+			certFile: certs.serverCert,
+			keyFile:  certs.serverKey,
+			caFile:   certs.serverKey,
+			wantErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config, err := network.NewServerTLSConfig(test.certFile, test.keyFile, test.caFile, test.requireClientCert)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, config)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, config)
+			}
+		})
+	}
+}
+
+func TestNewClientTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	certs := generateTestCerts(t)
+
+	_, err := network.NewClientTLSConfig("", certs.clientCert, "", "")
+	assert.Error(t, err)
+
+	_, err = network.NewClientTLSConfig(filepath.Join(t.TempDir(), "missing.crt"), "", "", "")
+	assert.Error(t, err)
+
+	config, err := network.NewClientTLSConfig("", "", "", "fastkey.local")
+	require.NoError(t, err)
+	assert.Equal(t, "fastkey.local", config.ServerName)
+	assert.Nil(t, config.RootCAs)
+}
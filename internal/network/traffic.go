@@ -0,0 +1,43 @@
+package network
+
+import (
+	"net"
+	"sync/atomic"
+)
+
+// traffic counts the bytes the server reads from and writes to the clients.
+type traffic struct {
+	read    atomic.Uint64
+	written atomic.Uint64
+}
+
+// trafficListener counts the traffic of the accepted connections.
+type trafficListener struct {
+	net.Listener
+	traffic *traffic
+}
+
+func (l *trafficListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &trafficConn{Conn: conn, traffic: l.traffic}, nil
+}
+
+type trafficConn struct {
+	net.Conn
+	traffic *traffic
+}
+
+func (c *trafficConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.traffic.read.Add(uint64(n))
+	return n, err
+}
+
+func (c *trafficConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.traffic.written.Add(uint64(n))
+	return n, err
+}
@@ -0,0 +1,107 @@
+package network
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const unixScheme = "unix://"
+
+// staleSocketTimeout is how long the server waits for a connection to the existing socket file
+// before it treats the file as a stale one.
+const staleSocketTimeout = 100 * time.Millisecond
+
+// splitAddress returns the network and the address to listen or dial:
+// unix:///path/to.sock is the unix socket /path/to.sock, other addresses are TCP ones.
+func splitAddress(address string) (string, string) {
+	if path, ok := strings.CutPrefix(address, unixScheme); ok {
+		return "unix", path
+	}
+	return "tcp", address
+}
+
+// listenUnix listens on the unix socket and sets the permissions of its file if perm is not zero.
+//
+// The socket file left by a server that was not stopped gracefully is removed,
+// the file of the socket that still accepts connections is not.
+func listenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("socket path is empty")
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("set socket permissions: %w", err)
+		}
+	}
+	return listener, nil
+}
+
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%v is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, staleSocketTimeout); err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %v is in use", path)
+	}
+	return os.Remove(path)
+}
+
+// remoteAddress returns the address of the client.
+//
+// The clients of a unix socket have no address, they are named after the socket and their peer,
+// so the failed authentication attempts of one peer never lock out the others.
+func remoteAddress(conn net.Conn) string {
+	if conn.RemoteAddr().Network() == "unix" {
+		return unixScheme + conn.LocalAddr().String() + "#" + unixPeer(unwrapConn(conn))
+	}
+	return conn.RemoteAddr().String()
+}
+
+// unixConnections numbers the unix connections whose peer credentials are unknown.
+var unixConnections atomic.Uint64
+
+// connectionPeer names the peer after the connection, it is used if the peer credentials are unknown.
+func connectionPeer() string {
+	return "conn=" + strconv.FormatUint(unixConnections.Add(1), 10)
+}
+
+// unwrapConn returns the connection accepted by the listener under the TLS and traffic wrappers.
+func unwrapConn(conn net.Conn) net.Conn {
+	for {
+		switch c := conn.(type) {
+		case *tls.Conn:
+			conn = c.NetConn()
+		case *trafficConn:
+			conn = c.Conn
+		default:
+			return conn
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package network
+
+import (
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// unixPeer names the peer of the unix connection after its user id taken from SO_PEERCRED,
+// so all the connections of a local user share the authentication limit.
+func unixPeer(conn net.Conn) string {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return connectionPeer()
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return connectionPeer()
+	}
+
+	var (
+		cred    *syscall.Ucred
+		credErr error
+	)
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil || credErr != nil {
+		return connectionPeer()
+	}
+	return "uid=" + strconv.FormatUint(uint64(cred.Uid), 10)
+}
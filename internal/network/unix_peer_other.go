@@ -0,0 +1,11 @@
+//go:build !linux
+
+package network
+
+import "net"
+
+// unixPeer names the peer of the unix connection after the connection,
+// the peer credentials are read on Linux only.
+func unixPeer(net.Conn) string {
+	return connectionPeer()
+}
@@ -0,0 +1,116 @@
+package network_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/network"
+	"github.com/alukart32/go-fast-key/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTCPServer_Unix(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "fastkey.sock")
+
+	// The socket file of a crashed server.
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	server, err := network.NewTCPServer("unix://"+path, zap.NewNop(), network.WithServerSocketPermissions(0o600))
+	require.NoError(t, err)
+	assert.Equal(t, "unix://"+path, server.Address())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, err = network.NewTCPServer("unix://"+path, zap.NewNop())
+	assert.ErrorContains(t, err, "is in use")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.HandleQueries(ctx, func(_ context.Context, sess *session.Session, data []byte) []byte {
+			return []byte(sess.RemoteAddr() + " " + string(data))
+		})
+	}()
+
+	client, err := network.NewTCPClient(server.Address())
+	require.NoError(t, err)
+	defer client.Close()
+
+	response, err := client.Send([]byte("GET key"))
+	require.NoError(t, err)
+
+	// The clients are named after their user on Linux and after their connection elsewhere.
+	peer := "#conn="
+	if runtime.GOOS == "linux" {
+		peer = "#uid=" + strconv.Itoa(os.Getuid()) + " "
+	}
+	assert.True(t, strings.HasPrefix(string(response), "unix://"+path+peer), string(response))
+	assert.True(t, strings.HasSuffix(string(response), " GET key"), string(response))
+
+	cancel()
+	<-done
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "socket file is removed on close")
+}
+
+func TestTCPServer_UnixNotSocket(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "fastkey.sock")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+	server, err := network.NewTCPServer("unix://"+path, zap.NewNop())
+	assert.Nil(t, server)
+	assert.ErrorContains(t, err, "is not a socket")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestTCPServer_UnixAndTCP(t *testing.T) {
+	t.Parallel()
+
+	sessions := session.NewManager()
+	unixServer, err := network.NewTCPServer("unix://"+filepath.Join(t.TempDir(), "fastkey.sock"), zap.NewNop(),
+		network.WithServerSessions(sessions))
+	require.NoError(t, err)
+	tcpServer, err := network.NewTCPServer("localhost:0", zap.NewNop(), network.WithServerSessions(sessions))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := func(_ context.Context, sess *session.Session, _ []byte) []byte {
+		return []byte(sess.RemoteAddr())
+	}
+	go unixServer.HandleQueries(ctx, handler)
+	go tcpServer.HandleQueries(ctx, handler)
+
+	for _, address := range []string{unixServer.Address(), tcpServer.Address()} {
+		client, err := network.NewTCPClient(address)
+		require.NoError(t, err)
+		defer client.Close()
+
+		response, err := client.Send([]byte("PING"))
+		require.NoError(t, err)
+		assert.Equal(t, strings.HasPrefix(address, "unix://"), strings.HasPrefix(string(response), "unix://"))
+	}
+	assert.Equal(t, 2, sessions.Len())
+}
@@ -0,0 +1,36 @@
+package concurrency
+
+type result[T any] struct {
+	done  chan struct{}
+	value T
+}
+
+// Future defines a value that becomes available later.
+type Future[T any] struct {
+	r *result[T]
+}
+
+// Get waits for the value and returns it.
+func (f Future[T]) Get() T {
+	<-f.r.done
+	return f.r.value
+}
+
+// Promise defines the producer side of a Future.
+type Promise[T any] struct {
+	r *result[T]
+}
+
+func NewPromise[T any]() Promise[T] {
+	return Promise[T]{r: &result[T]{done: make(chan struct{})}}
+}
+
+// Set resolves the promise, it must be called once.
+func (p Promise[T]) Set(value T) {
+	p.r.value = value
+	close(p.r.done)
+}
+
+func (p Promise[T]) Future() Future[T] {
+	return Future[T]{r: p.r}
+}
@@ -0,0 +1,26 @@
+package concurrency_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/pkg/concurrency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromise(t *testing.T) {
+	t.Parallel()
+
+	promise := concurrency.NewPromise[error]()
+	future := promise.Future()
+
+	wantErr := errors.New("error")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		promise.Set(wantErr)
+	}()
+
+	assert.Equal(t, wantErr, future.Get())
+	assert.Equal(t, wantErr, future.Get(), "resolved future must return the same value")
+}
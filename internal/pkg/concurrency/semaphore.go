@@ -1,11 +1,10 @@
 package concurrency
 
-import "sync"
+import "context"
 
+// Semaphore limits the number of the concurrent holders, the nil semaphore does not limit them.
 type Semaphore struct {
-	count int
-	max   int
-	cond  *sync.Cond
+	tokens chan struct{}
 }
 
 func NewSemaphore(limit int) *Semaphore {
@@ -13,35 +12,56 @@ func NewSemaphore(limit int) *Semaphore {
 		limit = 1
 	}
 	return &Semaphore{
-		max:  limit,
-		cond: sync.NewCond(&sync.Mutex{}),
+		tokens: make(chan struct{}, limit),
 	}
 }
 
+// Acquire waits until the semaphore is acquired.
 func (s *Semaphore) Acquire() {
 	if s == nil {
 		return
 	}
 
-	s.cond.L.Lock()
-	defer s.cond.L.Unlock()
+	s.tokens <- struct{}{}
+}
 
-	for s.count >= s.max {
-		s.cond.Wait()
+// TryAcquire acquires the semaphore if it is not at its limit and reports whether it did.
+func (s *Semaphore) TryAcquire() bool {
+	if s == nil {
+		return true
 	}
 
-	s.count++
+	select {
+	case s.tokens <- struct{}{}:
+		return true
+	default:
+		return false
+	}
 }
 
-func (s *Semaphore) Release() {
+// AcquireContext waits until the semaphore is acquired or ctx is done.
+func (s *Semaphore) AcquireContext(ctx context.Context) error {
 	if s == nil {
-		return
+		return nil
 	}
 
-	s.cond.L.Lock()
-	defer s.cond.L.Unlock()
+	select {
+	case s.tokens <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	s.count--
+// Release releases the acquired semaphore, it panics if the semaphore is not acquired.
+func (s *Semaphore) Release() {
+	if s == nil {
+		return
+	}
 
-	s.cond.Signal()
+	select {
+	case <-s.tokens:
+	default:
+		panic("concurrency: release of the semaphore that is not acquired")
+	}
 }
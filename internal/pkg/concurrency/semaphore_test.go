@@ -0,0 +1,61 @@
+package concurrency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/pkg/concurrency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSemaphore(t *testing.T) {
+	t.Parallel()
+
+	semaphore := concurrency.NewSemaphore(2)
+	assert.True(t, semaphore.TryAcquire())
+	semaphore.Acquire()
+	assert.False(t, semaphore.TryAcquire(), "semaphore is at its limit")
+
+	acquired := make(chan struct{})
+	go func() {
+		semaphore.Acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("semaphore is acquired over its limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	semaphore.Release()
+	<-acquired
+}
+
+func TestSemaphore_AcquireContext(t *testing.T) {
+	t.Parallel()
+
+	semaphore := concurrency.NewSemaphore(1)
+	assert.NoError(t, semaphore.AcquireContext(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, semaphore.AcquireContext(ctx), context.DeadlineExceeded)
+
+	semaphore.Release()
+	assert.NoError(t, semaphore.AcquireContext(context.Background()))
+
+	semaphore.Release()
+	assert.Panics(t, semaphore.Release, "semaphore is released more times than acquired")
+}
+
+func TestSemaphore_Nil(t *testing.T) {
+	t.Parallel()
+
+	var semaphore *concurrency.Semaphore
+	semaphore.Acquire()
+	assert.True(t, semaphore.TryAcquire())
+	assert.NoError(t, semaphore.AcquireContext(context.Background()))
+	semaphore.Release()
+}
@@ -0,0 +1,62 @@
+package session
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// Manager tracks the sessions of the connected clients.
+type Manager struct {
+	mtx      sync.Mutex
+	sessions map[uint64]*Session
+}
+
+// NewManager creates a new Manager.
+func NewManager() *Manager {
+	return &Manager{
+		sessions: make(map[uint64]*Session),
+	}
+}
+
+// Open creates the session of the client connected from the remote address.
+func (m *Manager) Open(remoteAddr string) *Session {
+	s := New(remoteAddr)
+
+	m.mtx.Lock()
+	m.sessions[s.ID()] = s
+	m.mtx.Unlock()
+
+	return s
+}
+
+// Close closes the session and forgets it.
+func (m *Manager) Close(s *Session) {
+	m.mtx.Lock()
+	delete(m.sessions, s.ID())
+	m.mtx.Unlock()
+
+	s.Close()
+}
+
+// List returns the open sessions ordered by ID.
+func (m *Manager) List() []*Session {
+	m.mtx.Lock()
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, s)
+	}
+	m.mtx.Unlock()
+
+	slices.SortFunc(sessions, func(a, b *Session) int {
+		return cmp.Compare(a.ID(), b.ID())
+	})
+	return sessions
+}
+
+// Len returns the number of the open sessions.
+func (m *Manager) Len() int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return len(m.sessions)
+}
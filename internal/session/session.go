@@ -0,0 +1,181 @@
+package session
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alukart32/go-fast-key/internal/database/compute"
+)
+
+// lastID is the ID of the last created session.
+var lastID atomic.Uint64
+
+// Session holds the state of a client connection.
+//
+// The requests of a connection are handled one by one, so the transaction
+// is not used concurrently. The other state may be read by other connections.
+type Session struct {
+	id         uint64
+	remoteAddr string
+	createdAt  time.Time
+
+	mtx  sync.RWMutex
+	user string
+	db   int
+
+	tx Transaction
+}
+
+// New creates a new Session of the client with the remote address,
+// the session IDs are unique within the process.
+func New(remoteAddr string) *Session {
+	return &Session{
+		id:         lastID.Add(1),
+		remoteAddr: remoteAddr,
+		createdAt:  time.Now(),
+	}
+}
+
+// ID returns the unique ID of the session.
+func (s *Session) ID() uint64 {
+	return s.id
+}
+
+// RemoteAddr returns the address of the client.
+func (s *Session) RemoteAddr() string {
+	return s.remoteAddr
+}
+
+// CreatedAt returns the time the client connected at.
+func (s *Session) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// Authenticate marks the session as authenticated by the user.
+func (s *Session) Authenticate(user string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.user = user
+}
+
+// User returns the name of the authenticated user, it is empty before authentication.
+func (s *Session) User() string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.user
+}
+
+// Authenticated reports whether the session is authenticated.
+func (s *Session) Authenticated() bool {
+	return s.User() != ""
+}
+
+// SelectDatabase sets the index of the database the session works with.
+func (s *Session) SelectDatabase(index int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.db = index
+}
+
+// Database returns the index of the selected database, 0 by default.
+func (s *Session) Database() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.db
+}
+
+// Transaction returns the transaction state of the session.
+func (s *Session) Transaction() *Transaction {
+	return &s.tx
+}
+
+// Close releases the session resources, it is called once the connection is closed.
+func (s *Session) Close() {
+	if s.tx.registry != nil {
+		s.tx.registry.Unwatch(&s.tx)
+	}
+}
+
+// Registry tracks the keys watched by the transactions.
+type Registry interface {
+	Unwatch(t *Transaction)
+}
+
+// Transaction holds the queries queued after MULTI and the keys watched by the session.
+type Transaction struct {
+	active  bool
+	failed  bool
+	queries []compute.Query
+
+	registry Registry
+	watched  []string
+	// dirty is set by other sessions that modify a watched key.
+	dirty atomic.Bool
+}
+
+// Begin starts queueing the queries.
+func (t *Transaction) Begin() {
+	t.active = true
+}
+
+// Active reports whether the queries are queued.
+func (t *Transaction) Active() bool {
+	return t.active
+}
+
+// Queue adds the query to the transaction.
+func (t *Transaction) Queue(q compute.Query) {
+	t.queries = append(t.queries, q)
+}
+
+// Queries returns the queued queries.
+func (t *Transaction) Queries() []compute.Query {
+	return t.queries
+}
+
+// Fail marks the queued transaction as failed, so it is discarded by EXEC.
+// It does nothing if no transaction is started.
+func (t *Transaction) Fail() {
+	if t.active {
+		t.failed = true
+	}
+}
+
+// Failed reports whether a query failed to be queued.
+func (t *Transaction) Failed() bool {
+	return t.failed
+}
+
+// Watch adds the keys watched in the registry, the registry forgets them once the session is closed.
+func (t *Transaction) Watch(r Registry, keys ...string) {
+	t.registry = r
+	t.watched = append(t.watched, keys...)
+}
+
+// Watched returns the watched keys.
+func (t *Transaction) Watched() []string {
+	return t.watched
+}
+
+// Invalidate marks the transaction as aborted by a change of a watched key,
+// it is safe to call concurrently.
+func (t *Transaction) Invalidate() {
+	t.dirty.Store(true)
+}
+
+// Invalidated reports whether a watched key is changed.
+func (t *Transaction) Invalidated() bool {
+	return t.dirty.Load()
+}
+
+// Reset ends the transaction and forgets the queued queries and the watched keys,
+// the keys must be unwatched in the registry before.
+func (t *Transaction) Reset() {
+	t.active = false
+	t.failed = false
+	t.queries = nil
+	t.registry = nil
+	t.watched = nil
+	t.dirty.Store(false)
+}
@@ -0,0 +1,61 @@
+package session_test
+
+import (
+	"testing"
+
+	"github.com/alukart32/go-fast-key/internal/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+
+	manager := session.NewManager()
+	first := manager.Open("127.0.0.1:1001")
+	second := manager.Open("127.0.0.1:1002")
+
+	assert.NotEqual(t, first.ID(), second.ID())
+	assert.Equal(t, "127.0.0.1:1001", first.RemoteAddr())
+	assert.Equal(t, []*session.Session{first, second}, manager.List())
+
+	manager.Close(first)
+	assert.Equal(t, []*session.Session{second}, manager.List())
+	assert.Equal(t, 1, manager.Len())
+}
+
+func TestSession(t *testing.T) {
+	t.Parallel()
+
+	s := session.New("127.0.0.1:1001")
+	assert.False(t, s.Authenticated())
+	assert.Zero(t, s.Database())
+	assert.False(t, s.CreatedAt().IsZero())
+
+	s.Authenticate("svc-a")
+	s.SelectDatabase(2)
+	assert.True(t, s.Authenticated())
+	assert.Equal(t, "svc-a", s.User())
+	assert.Equal(t, 2, s.Database())
+}
+
+func TestTransaction(t *testing.T) {
+	t.Parallel()
+
+	tx := session.New("").Transaction()
+
+	tx.Fail()
+	assert.False(t, tx.Failed(), "no transaction is started")
+
+	tx.Begin()
+	tx.Fail()
+	tx.Invalidate()
+	assert.True(t, tx.Active())
+	assert.True(t, tx.Failed())
+	assert.True(t, tx.Invalidated())
+
+	tx.Reset()
+	assert.False(t, tx.Active())
+	assert.False(t, tx.Failed())
+	assert.False(t, tx.Invalidated())
+	assert.Empty(t, tx.Queries())
+}